@@ -0,0 +1,303 @@
+// Package anomaly detects unusual statements against the card own history
+package anomaly
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/export"
+	"github.com/pkg/errors"
+)
+
+// Anomaly kinds
+const (
+	KindAmount      = "amount"
+	KindNewTerminal = "new-terminal"
+	KindHour        = "hour"
+	KindSpike       = "spike"
+)
+
+// Default detection options
+const (
+	DefaultZScore        = 3.5
+	DefaultMinHistory    = 5
+	DefaultHourShare     = 0.02
+	DefaultNewAfter      = 30 * 24 * time.Hour
+	minHourStatements    = 50
+	madNormalConstant    = 1.4826 // scale of MAD to the standard deviation of the normal distribution
+	meanADNormalConstant = 1.2533 // scale of mean absolute deviation to the standard deviation
+	minSpikeStatements   = 3
+)
+
+// Group is a named key of statements groups for amount anomalies, e.g. Terminal or Category
+type Group struct {
+	Name string
+	Key  func(s *p24.Statement) (string, error)
+}
+
+// TerminalGroup groups statements by Terminal
+var TerminalGroup = Group{Name: "Terminal", Key: func(s *p24.Statement) (string, error) { return s.Terminal, nil }}
+
+// Options of detection
+type Options struct {
+	Groups     []Group       // amount groups, TerminalGroup if empty
+	ZScore     float64       // robust z-score of amounts and daily counts anomalies, DefaultZScore if zero
+	MinHistory int           // minimal count of group statements for amount anomalies, DefaultMinHistory if zero
+	HourShare  float64       // hours with smaller share of statements are odd, DefaultHourShare if zero
+	NewAfter   time.Duration // terminals are new after the history of the duration, DefaultNewAfter if zero
+}
+
+// Anomaly is an unusual statement or a day with unusual count of statements.
+// Score is a sum of detectors scores, each detector score is at least 1
+type Anomaly struct {
+	TranDate    time.Time
+	Card        string
+	Terminal    string
+	Description string
+	CardAmount  p24.Funds
+	Count       int // count of statements of the day for spikes
+	Kinds       string
+	Score       float64
+	Reason      string
+}
+
+// Detect returns anomalies of statements sorted by score descending
+func Detect(statements []p24.Statement, opts Options) ([]*Anomaly, error) {
+	opts = opts.withDefaults()
+	sorted := make([]*p24.Statement, len(statements))
+	for i := range statements {
+		sorted[i] = &statements[i]
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].TranDate.Before(sorted[j].TranDate) })
+
+	byStatement := map[*p24.Statement]*Anomaly{}
+	add := func(s *p24.Statement, kind string, score float64, reason string) {
+		a, ok := byStatement[s]
+		if !ok {
+			a = &Anomaly{TranDate: s.TranDate, Card: s.Card, Terminal: s.Terminal, Description: s.Description, CardAmount: s.CardAmount}
+			byStatement[s] = a
+		}
+		a.Score += score
+		a.Kinds = join(a.Kinds, kind, ",")
+		a.Reason = join(a.Reason, reason, "; ")
+	}
+
+	for _, g := range opts.Groups {
+		if err := detectAmounts(sorted, g, opts, add); err != nil {
+			return nil, err
+		}
+	}
+	detectNewTerminals(sorted, opts, add)
+	detectHours(sorted, opts, add)
+
+	res := make([]*Anomaly, 0, len(byStatement))
+	for _, s := range sorted {
+		if a, ok := byStatement[s]; ok {
+			res = append(res, a)
+		}
+	}
+	res = append(res, detectSpikes(sorted, opts)...)
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Score > res[j].Score })
+	return res, nil
+}
+
+// Rows returns export rows of anomalies
+func Rows(anomalies []*Anomaly) export.Rows {
+	items := make([]interface{}, len(anomalies))
+	for i := range anomalies {
+		items[i] = anomalies[i]
+	}
+	return export.Rows{Root: "anomalies", Elem: "anomaly", Items: items}
+}
+
+func (opts Options) withDefaults() Options {
+	if len(opts.Groups) == 0 {
+		opts.Groups = []Group{TerminalGroup}
+	}
+	if opts.ZScore <= 0 {
+		opts.ZScore = DefaultZScore
+	}
+	if opts.MinHistory <= 0 {
+		opts.MinHistory = DefaultMinHistory
+	}
+	if opts.HourShare <= 0 {
+		opts.HourShare = DefaultHourShare
+	}
+	if opts.NewAfter <= 0 {
+		opts.NewAfter = DefaultNewAfter
+	}
+	return opts
+}
+
+type addFunc func(s *p24.Statement, kind string, score float64, reason string)
+
+// detectAmounts flags amounts far from the median of the group and currency by robust z-score
+func detectAmounts(statements []*p24.Statement, g Group, opts Options, add addFunc) error {
+	groups := map[string][]*p24.Statement{}
+	for _, s := range statements {
+		key, err := g.Key(s)
+		if err != nil {
+			return errors.Wrapf(err, "%s group", g.Name)
+		}
+		if key != "" {
+			k := key + "\x00" + s.CardAmount.Currency
+			groups[k] = append(groups[k], s)
+		}
+	}
+
+	for _, list := range groups {
+		if len(list) < opts.MinHistory {
+			continue
+		}
+		values := make([]float64, len(list))
+		for i, s := range list {
+			values[i] = s.CardAmount.Amount.Float64()
+		}
+		median, scale := medianMAD(values)
+		scale *= madNormalConstant
+		if scale == 0 {
+			// most amounts are the same, mean absolute deviation is used instead
+			for _, v := range values {
+				scale += math.Abs(v - median)
+			}
+			scale *= meanADNormalConstant / float64(len(values))
+		}
+		if scale == 0 {
+			continue
+		}
+		for i, s := range list {
+			z := math.Abs(values[i]-median) / scale
+			if z < opts.ZScore {
+				continue
+			}
+			key, _ := g.Key(s)
+			add(s, KindAmount, z/opts.ZScore, fmt.Sprintf("amount %.2f is far from the usual %.2f of %s %q",
+				values[i], median, strings.ToLower(g.Name), key))
+		}
+	}
+	return nil
+}
+
+// detectNewTerminals flags the first statements of terminals after opts.NewAfter of the card history
+func detectNewTerminals(statements []*p24.Statement, opts Options, add addFunc) {
+	first := map[string]time.Time{} // the first statement of a card
+	seen := map[string]bool{}
+	for _, s := range statements {
+		if _, ok := first[s.Card]; !ok {
+			first[s.Card] = s.TranDate
+		}
+		key := s.Card + "\x00" + strings.ToLower(s.Terminal)
+		if s.Terminal == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		if s.TranDate.Sub(first[s.Card]) >= opts.NewAfter {
+			add(s, KindNewTerminal, 1, fmt.Sprintf("first-ever terminal %q", s.Terminal))
+		}
+	}
+}
+
+// detectHours flags statements at hours with a small share of the card statements
+func detectHours(statements []*p24.Statement, opts Options, add addFunc) {
+	kiev := p24.NewKievLocation()
+	counts, totals := map[string]*[24]int{}, map[string]int{}
+	for _, s := range statements {
+		if counts[s.Card] == nil {
+			counts[s.Card] = &[24]int{}
+		}
+		counts[s.Card][s.TranDate.In(kiev).Hour()]++
+		totals[s.Card]++
+	}
+
+	for _, s := range statements {
+		if totals[s.Card] < minHourStatements {
+			continue
+		}
+		h := s.TranDate.In(kiev).Hour()
+		share := float64(counts[s.Card][h]) / float64(totals[s.Card])
+		if share < opts.HourShare {
+			add(s, KindHour, 1+(opts.HourShare-share)/opts.HourShare,
+				fmt.Sprintf("odd hour %02d:00, %.1f%% of statements", h, share*100))
+		}
+	}
+}
+
+// detectSpikes returns days with count of statements far above the usual daily count of the card
+func detectSpikes(statements []*p24.Statement, opts Options) []*Anomaly {
+	kiev := p24.NewKievLocation()
+	type day struct {
+		card  string
+		date  time.Time
+		count int
+	}
+	var days []*day
+	byKey := map[string]*day{}
+	for _, s := range statements {
+		t := s.TranDate.In(kiev)
+		d := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, kiev)
+		key := s.Card + d.Format("2006-01-02")
+		if byKey[key] == nil {
+			byKey[key] = &day{card: s.Card, date: d}
+			days = append(days, byKey[key])
+		}
+		byKey[key].count++
+	}
+
+	counts := map[string][]float64{}
+	for _, d := range days {
+		counts[d.card] = append(counts[d.card], float64(d.count))
+	}
+	var res []*Anomaly
+	for _, d := range days {
+		if len(counts[d.card]) < opts.MinHistory || d.count < minSpikeStatements {
+			continue
+		}
+		median, mad := medianMAD(counts[d.card])
+		if mad == 0 {
+			mad = 1 / madNormalConstant // one statement more than usual is one deviation
+		}
+		z := (float64(d.count) - median) / (madNormalConstant * mad)
+		if z < opts.ZScore {
+			continue
+		}
+		res = append(res, &Anomaly{
+			TranDate: d.date, Card: d.card, Count: d.count, Kinds: KindSpike, Score: z / opts.ZScore,
+			Reason: fmt.Sprintf("%d statements a day, usually %.0f", d.count, median),
+		})
+	}
+	return res
+}
+
+// medianMAD returns median and median absolute deviation of values
+func medianMAD(values []float64) (median, mad float64) {
+	median = medianOf(values)
+	deviations := make([]float64, len(values))
+	for i, v := range values {
+		deviations[i] = math.Abs(v - median)
+	}
+	return median, medianOf(deviations)
+}
+
+func medianOf(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n == 0 {
+		return 0
+	}
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+func join(x, y, sep string) string {
+	if x == "" {
+		return y
+	}
+	return x + sep + y
+}
@@ -0,0 +1,58 @@
+package anomaly
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Detect(t *testing.T) {
+	kiev := p24.NewKievLocation()
+	var statements []p24.Statement
+	for day := 0; day < 60; day++ {
+		statements = append(statements, p24.Statement{
+			Card: "4111", TranDate: time.Date(2022, 1, 1+day, 9+day%10, 0, 0, 0, kiev), Terminal: "Shop",
+			CardAmount: p24.Funds{Currency: "UAH", Amount: -10000 - p24.Amount(day%5)*100},
+		})
+	}
+	statements = append(statements, []p24.Statement{
+		{Card: "4111", TranDate: time.Date(2022, 1, 21, 12, 0, 0, 0, kiev), Terminal: "Shop", CardAmount: p24.Funds{Currency: "UAH", Amount: -90000}},   // amount
+		{Card: "4111", TranDate: time.Date(2022, 2, 10, 12, 0, 0, 0, kiev), Terminal: "Jeweller", CardAmount: p24.Funds{Currency: "UAH", Amount: -500}}, // new terminal
+		{Card: "4111", TranDate: time.Date(2022, 2, 11, 3, 0, 0, 0, kiev), Terminal: "Shop", CardAmount: p24.Funds{Currency: "UAH", Amount: -10000}},    // odd hour
+		// spike, new terminal at odd hour
+		{Card: "4111", TranDate: time.Date(2022, 2, 20, 2, 0, 0, 0, kiev), Terminal: "Cafe", CardAmount: p24.Funds{Currency: "UAH", Amount: -100}},
+		{Card: "4111", TranDate: time.Date(2022, 2, 20, 11, 0, 0, 0, kiev), Terminal: "Cafe", CardAmount: p24.Funds{Currency: "UAH", Amount: -100}},
+		{Card: "4111", TranDate: time.Date(2022, 2, 20, 12, 0, 0, 0, kiev), Terminal: "Cafe", CardAmount: p24.Funds{Currency: "UAH", Amount: -100}},
+		{Card: "4111", TranDate: time.Date(2022, 2, 20, 13, 0, 0, 0, kiev), Terminal: "Cafe", CardAmount: p24.Funds{Currency: "UAH", Amount: -100}},
+	}...)
+
+	anomalies, err := Detect(statements, Options{})
+	require.NoError(t, err)
+
+	kinds := map[string]*Anomaly{}
+	for i, a := range anomalies {
+		if i > 0 {
+			require.GreaterOrEqual(t, anomalies[i-1].Score, a.Score)
+		}
+		require.GreaterOrEqual(t, a.Score, 1.0)
+		kinds[a.Kinds] = a
+	}
+	require.Len(t, anomalies, 5, kinds)
+
+	require.Equal(t, p24.Amount(-90000), kinds[KindAmount].CardAmount.Amount)
+	require.Contains(t, kinds[KindAmount].Reason, `of terminal "Shop"`)
+	require.Greater(t, kinds[KindAmount].Score, 10.0)
+	require.Equal(t, "Jeweller", kinds[KindNewTerminal].Terminal)
+	require.Equal(t, 3, kinds[KindHour].TranDate.Hour())
+	require.Equal(t, "Cafe", kinds[KindNewTerminal+","+KindHour].Terminal)
+	require.Equal(t, 5, kinds[KindSpike].Count)
+	require.Equal(t, "5 statements a day, usually 1", kinds[KindSpike].Reason)
+
+	// categories groups
+	failed := Group{Name: "Category", Key: func(s *p24.Statement) (string, error) { return "", errors.New("failed") }}
+	_, err = Detect(statements, Options{Groups: []Group{failed}})
+	require.Error(t, err)
+}
@@ -0,0 +1,227 @@
+// Package balance reconstructs running balance of statements by Rest and checks its continuity
+package balance
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/export"
+)
+
+// Issue kinds
+const (
+	KindGap      = "gap"      // Rest is not the previous Rest plus CardAmount, statements are missing or wrong
+	KindCurrency = "currency" // CardAmount or Rest currency differs from the previous Rest currency
+)
+
+// Issue is a continuity violation between two sequential statements of a card
+type Issue struct {
+	Kind     string
+	Card     string
+	After    string    // location of the previous statement, e.g. "2022-01-02 10:00:00 #ABC123"
+	Before   string    // location of the statement
+	TranDate time.Time // the statement date
+	Expected p24.Funds // the previous Rest plus CardAmount
+	Rest     p24.Funds
+	Missing  p24.Funds // Rest - Expected, the sum of missing statements amounts
+}
+
+// Day is a daily balance of a card. Average is time weighted balance of the day
+type Day struct {
+	Date     time.Time
+	Card     string
+	Currency string
+	Open     p24.Amount
+	Close    p24.Amount
+	Min      p24.Amount
+	Max      p24.Amount
+	Average  p24.Amount
+	Count    int
+}
+
+// Order returns statements grouped by card in chronological order. Statements of the same time
+// are ordered to chain their rests if possible
+func Order(statements []p24.Statement) map[string][]p24.Statement {
+	res := map[string][]p24.Statement{}
+	for _, s := range statements {
+		res[s.Card] = append(res[s.Card], s)
+	}
+	for _, list := range res {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].TranDate.Before(list[j].TranDate) })
+		for start := 0; start < len(list); {
+			end := start + 1
+			for end < len(list) && list[end].TranDate.Equal(list[start].TranDate) {
+				end++
+			}
+			chain(list[start:end], list[:start])
+			start = end
+		}
+	}
+	return res
+}
+
+// chain orders statements of the same time so that each one continues the previous rest.
+// The first one continues the last rest of prev or is the one no other statement leads to
+func chain(group, prev []p24.Statement) {
+	if len(group) < 2 {
+		return
+	}
+	opening := func(s p24.Statement) p24.Amount { return s.Rest.Amount - s.CardAmount.Amount }
+	for i := range group {
+		first := true
+		if len(prev) > 0 {
+			first = opening(group[i]) == prev[len(prev)-1].Rest.Amount
+		} else {
+			for k := range group {
+				if k != i && group[k].Rest.Amount == opening(group[i]) {
+					first = false
+				}
+			}
+		}
+		if first {
+			group[0], group[i] = group[i], group[0]
+			break
+		}
+	}
+	for i := 1; i < len(group); i++ {
+		for k := i; k < len(group); k++ {
+			if opening(group[k]) == group[i-1].Rest.Amount {
+				group[i], group[k] = group[k], group[i]
+				break
+			}
+		}
+	}
+}
+
+// Check returns continuity issues of statements ordered by card and date
+func Check(statements []p24.Statement) []Issue {
+	var issues []Issue
+	byCard := Order(statements)
+	for _, card := range cards(byCard) {
+		list := byCard[card]
+		for i := 1; i < len(list); i++ {
+			prev, s := list[i-1], list[i]
+			issue := Issue{
+				Card:     card,
+				After:    location(prev),
+				Before:   location(s),
+				TranDate: s.TranDate,
+				Expected: p24.Funds{Currency: prev.Rest.Currency, Amount: prev.Rest.Amount + s.CardAmount.Amount},
+				Rest:     s.Rest,
+			}
+			switch {
+			case s.Rest.Currency != prev.Rest.Currency || s.CardAmount.Currency != prev.Rest.Currency:
+				issue.Kind = KindCurrency
+			case s.Rest.Amount != issue.Expected.Amount:
+				issue.Kind = KindGap
+				issue.Missing = p24.Funds{Currency: s.Rest.Currency, Amount: s.Rest.Amount - issue.Expected.Amount}
+			default:
+				continue
+			}
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+// Daily returns daily balances of cards from the first to the last statement date including days without statements.
+// The opening balance of the first day is the first statement Rest minus its CardAmount
+func Daily(statements []p24.Statement) []*Day {
+	kiev := p24.NewKievLocation()
+	var res []*Day
+	byCard := Order(statements)
+	for _, card := range cards(byCard) {
+		list := byCard[card]
+		if len(list) == 0 {
+			continue
+		}
+		balance := list[0].Rest.Amount - list[0].CardAmount.Amount
+		day := newDay(card, list[0].Rest.Currency, date(list[0].TranDate.In(kiev)), balance)
+		for _, s := range list {
+			t := s.TranDate.In(kiev)
+			for !date(t).Equal(day.Date) {
+				res = append(res, day.finish(balance))
+				day = newDay(card, day.Currency, day.Date.AddDate(0, 0, 1), balance)
+			}
+			day.add(t, s.Rest.Amount, balance)
+			balance = s.Rest.Amount
+		}
+		res = append(res, day.finish(balance))
+	}
+	return res
+}
+
+// IssuesRows returns export rows of issues
+func IssuesRows(issues []Issue) export.Rows {
+	items := make([]interface{}, len(issues))
+	for i := range issues {
+		items[i] = issues[i]
+	}
+	return export.Rows{Root: "issues", Elem: "issue", Items: items}
+}
+
+// DailyRows returns export rows of daily balances
+func DailyRows(days []*Day) export.Rows {
+	items := make([]interface{}, len(days))
+	for i := range days {
+		items[i] = days[i]
+	}
+	return export.Rows{Root: "balances", Elem: "day", Items: items}
+}
+
+// dayAccumulator accumulates time weighted balance of a day
+type dayAccumulator struct {
+	*Day
+	last     time.Time
+	weighted float64 // sum of balance * seconds
+}
+
+func newDay(card, currency string, d time.Time, open p24.Amount) *dayAccumulator {
+	return &dayAccumulator{
+		Day:  &Day{Date: d, Card: card, Currency: currency, Open: open, Min: open, Max: open},
+		last: d,
+	}
+}
+
+// add adds a statement rest at t, balance is the balance before the statement
+func (d *dayAccumulator) add(t time.Time, rest, balance p24.Amount) {
+	d.weighted += float64(balance) * t.Sub(d.last).Seconds()
+	d.last = t
+	d.Count++
+	if rest < d.Min {
+		d.Min = rest
+	}
+	if rest > d.Max {
+		d.Max = rest
+	}
+}
+
+// finish returns the day with closing balance
+func (d *dayAccumulator) finish(balance p24.Amount) *Day {
+	end := d.Date.AddDate(0, 0, 1)
+	d.weighted += float64(balance) * end.Sub(d.last).Seconds()
+	d.Close = balance
+	d.Average = p24.Amount(math.Round(d.weighted / end.Sub(d.Date).Seconds()))
+	return d.Day
+}
+
+func location(s p24.Statement) string {
+	return fmt.Sprintf("%s #%s", s.TranDate.In(p24.NewKievLocation()).Format("2006-01-02 15:04:05"), s.Appcode)
+}
+
+func cards(byCard map[string][]p24.Statement) []string {
+	res := make([]string, 0, len(byCard))
+	for card := range byCard {
+		res = append(res, card)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// date returns midnight of t date in t location
+func date(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
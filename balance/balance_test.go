@@ -0,0 +1,68 @@
+package balance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Check(t *testing.T) {
+	kiev := p24.NewKievLocation()
+	statements := []p24.Statement{
+		// 1000 is missing before
+		{
+			Card: "1", Appcode: "4", TranDate: time.Date(2022, 1, 3, 12, 0, 0, 0, kiev),
+			CardAmount: p24.Funds{Currency: "UAH", Amount: -500}, Rest: p24.Funds{Currency: "UAH", Amount: 8500},
+		},
+		// the same time statements are chained by rest
+		{
+			Card: "1", Appcode: "2", TranDate: time.Date(2022, 1, 1, 12, 0, 0, 0, kiev),
+			CardAmount: p24.Funds{Currency: "UAH", Amount: -300}, Rest: p24.Funds{Currency: "UAH", Amount: 9600},
+		},
+		{
+			Card: "1", Appcode: "1", TranDate: time.Date(2022, 1, 1, 12, 0, 0, 0, kiev),
+			CardAmount: p24.Funds{Currency: "UAH", Amount: -100}, Rest: p24.Funds{Currency: "UAH", Amount: 9900},
+		},
+		{
+			Card: "1", Appcode: "3", TranDate: time.Date(2022, 1, 2, 6, 0, 0, 0, kiev),
+			CardAmount: p24.Funds{Currency: "UAH", Amount: 400}, Rest: p24.Funds{Currency: "UAH", Amount: 10000},
+		},
+		{
+			Card: "2", Appcode: "1", TranDate: time.Date(2022, 1, 1, 10, 0, 0, 0, kiev),
+			CardAmount: p24.Funds{Currency: "UAH", Amount: -100}, Rest: p24.Funds{Currency: "UAH", Amount: 100},
+		},
+		{
+			Card: "2", Appcode: "2", TranDate: time.Date(2022, 1, 1, 11, 0, 0, 0, kiev),
+			CardAmount: p24.Funds{Currency: "UAH", Amount: -100}, Rest: p24.Funds{Currency: "UAH", Amount: 0},
+		},
+		{
+			Card: "2", Appcode: "3", TranDate: time.Date(2022, 1, 1, 12, 0, 0, 0, kiev),
+			CardAmount: p24.Funds{Currency: "UAH", Amount: -1}, Rest: p24.Funds{Currency: "USD", Amount: 0},
+		},
+	}
+
+	issues := Check(statements)
+	require.Len(t, issues, 2)
+	require.Equal(t, KindGap, issues[0].Kind)
+	require.Equal(t, "2022-01-02 06:00:00 #3", issues[0].After)
+	require.Equal(t, "2022-01-03 12:00:00 #4", issues[0].Before)
+	require.Equal(t, p24.Funds{Currency: "UAH", Amount: 9500}, issues[0].Expected)
+	require.Equal(t, p24.Funds{Currency: "UAH", Amount: -1000}, issues[0].Missing)
+	require.Equal(t, KindCurrency, issues[1].Kind)
+	require.Equal(t, "2", issues[1].Card)
+
+	days := Daily(statements[:4])
+	require.Len(t, days, 3)
+	require.Equal(t, Day{
+		Date: time.Date(2022, 1, 1, 0, 0, 0, 0, kiev), Card: "1", Currency: "UAH", Open: 10000, Close: 9600, Min: 9600, Max: 10000, Average: 9800, Count: 2,
+	}, *days[0])
+	// 6 hours of 9600 and 18 hours of 10000
+	require.Equal(t, Day{
+		Date: time.Date(2022, 1, 2, 0, 0, 0, 0, kiev), Card: "1", Currency: "UAH", Open: 9600, Close: 10000, Min: 9600, Max: 10000, Average: 9900, Count: 1,
+	}, *days[1])
+	require.Equal(t, p24.Amount(8500), days[2].Close)
+	require.Equal(t, p24.Amount(8500), days[2].Min)
+	require.Empty(t, Daily(nil))
+}
@@ -0,0 +1,142 @@
+package category
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/dimboknv/p24"
+	"github.com/pkg/errors"
+)
+
+// Classifier is a multinomial naive Bayes classifier of statements categories
+// trained on tokens of Description and Terminal of labelled statements
+type Classifier struct {
+	Docs   map[string]int            `json:"docs"`   // count of labelled statements by category
+	Tokens map[string]map[string]int `json:"tokens"` // token counts by category
+	Totals map[string]int            `json:"totals"` // count of all tokens by category
+	Vocab  map[string]struct{}       `json:"vocab"`
+}
+
+// Suggestion is a suggested category with confidence in [0, 1]
+type Suggestion struct {
+	Category   string
+	Confidence float64
+}
+
+// NewClassifier returns untrained Classifier
+func NewClassifier() *Classifier {
+	return &Classifier{
+		Docs:   map[string]int{},
+		Tokens: map[string]map[string]int{},
+		Totals: map[string]int{},
+		Vocab:  map[string]struct{}{},
+	}
+}
+
+// LoadClassifier reads Classifier model from json file
+func LoadClassifier(filename string) (*Classifier, error) {
+	data, err := os.ReadFile(filepath.Clean(filename))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read model %q", filename)
+	}
+	c := NewClassifier()
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse model %q", filename)
+	}
+	return c, nil
+}
+
+// Save writes Classifier model to json file
+func (c *Classifier) Save(filename string) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return errors.Wrapf(os.WriteFile(filename, data, 0o600), "failed to write model %q", filename)
+}
+
+// Train adds labelled statement to the model. Statements without tokens or category are skipped
+func (c *Classifier) Train(s *p24.Statement, category string) {
+	tokens := Tokenize(s)
+	if category = strings.TrimSpace(category); category == "" || len(tokens) == 0 {
+		return
+	}
+
+	c.Docs[category]++
+	if c.Tokens[category] == nil {
+		c.Tokens[category] = map[string]int{}
+	}
+	for _, t := range tokens {
+		c.Tokens[category][t]++
+		c.Totals[category]++
+		c.Vocab[t] = struct{}{}
+	}
+}
+
+// Trained returns count of labelled statements
+func (c *Classifier) Trained() (n int) {
+	for _, d := range c.Docs {
+		n += d
+	}
+	return n
+}
+
+// Suggest returns the most probable category of s. Confidence is a posterior probability of the category,
+// so it is low if statement tokens are unknown or shared by several categories.
+// Returns empty Suggestion if the model is not trained
+func (c *Classifier) Suggest(s *p24.Statement) Suggestion {
+	total := c.Trained()
+	if total == 0 {
+		return Suggestion{}
+	}
+
+	categories := make([]string, 0, len(c.Docs))
+	for category := range c.Docs {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories) // stable result for equal scores
+
+	// log probabilities with laplace smoothing
+	tokens, vocab := Tokenize(s), float64(len(c.Vocab))
+	scores := make([]float64, len(categories))
+	for i, category := range categories {
+		scores[i] = math.Log(float64(c.Docs[category]) / float64(total))
+		for _, t := range tokens {
+			scores[i] += math.Log((float64(c.Tokens[category][t]) + 1) / (float64(c.Totals[category]) + vocab))
+		}
+	}
+
+	best := 0
+	for i := range scores {
+		if scores[i] > scores[best] {
+			best = i
+		}
+	}
+	var sum float64
+	for i := range scores {
+		sum += math.Exp(scores[i] - scores[best])
+	}
+	return Suggestion{Category: categories[best], Confidence: 1 / sum}
+}
+
+// Tokenize returns lower case words of s Description and Terminal. Terminal tokens are prefixed by "t:",
+// numbers and single letters are skipped as they are mostly dates, amounts and receipt ids
+func Tokenize(s *p24.Statement) []string {
+	words := func(str, prefix string) (res []string) {
+		for _, w := range strings.FieldsFunc(strings.ToLower(str), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			if len([]rune(w)) < 2 || strings.IndexFunc(w, unicode.IsLetter) == -1 {
+				continue
+			}
+			res = append(res, prefix+w)
+		}
+		return res
+	}
+	return append(words(s.Description, ""), words(s.Terminal, "t:")...)
+}
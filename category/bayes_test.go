@@ -0,0 +1,73 @@
+package category
+
+import (
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/dimboknv/p24"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Classifier(t *testing.T) {
+	clf := NewClassifier()
+	require.Equal(t, Suggestion{}, clf.Suggest(&p24.Statement{Description: "uber"}))
+
+	labelled := []struct {
+		description, terminal, category string
+	}{
+		{"Uber trip 123", "UBER BV", "transport"},
+		{"Uber trip 456", "UBER BV", "transport"},
+		{"Bolt ride", "BOLT", "transport"},
+		{"Silpo market", "SILPO 12", "groceries"},
+		{"ATB market", "ATB 7", "groceries"},
+		{"Silpo", "SILPO 3", "groceries"},
+		{"12345", "", "skipped"},
+		{"Salary", "", ""},
+	}
+	for _, l := range labelled {
+		clf.Train(&p24.Statement{Description: l.description, Terminal: l.terminal}, l.category)
+	}
+	require.Equal(t, 6, clf.Trained())
+
+	filename := filepath.Join(t.TempDir(), "model.json")
+	require.NoError(t, clf.Save(filename))
+	clf, err := LoadClassifier(filename)
+	require.NoError(t, err)
+
+	cases := []struct {
+		statement     p24.Statement
+		category      string
+		minConfidence float64
+		maxConfidence float64
+	}{
+		{p24.Statement{Description: "Uber trip 789", Terminal: "UBER BV"}, "transport", 0.9, 1},
+		{p24.Statement{Description: "SILPO", Terminal: "SILPO 44"}, "groceries", 0.9, 1},
+		{p24.Statement{Description: "Unknown shop"}, "groceries", 0.4, 0.6},
+	}
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			s := clf.Suggest(&c.statement)
+			require.Equal(t, c.category, s.Category)
+			require.True(t, s.Confidence >= c.minConfidence && s.Confidence <= c.maxConfidence, s.Confidence)
+		})
+	}
+
+	categorizer := &Categorizer{Classifier: clf, MinConfidence: DefaultMinConfidence}
+	r, err := categorizer.Categorize(&p24.Statement{Description: "Unknown shop"})
+	require.NoError(t, err)
+	require.True(t, r.Review)
+	require.Equal(t, "", r.Category)
+	require.Equal(t, "groceries", r.Suggestion.Category)
+
+	r, err = categorizer.Categorize(&p24.Statement{Description: "Bolt ride", Terminal: "BOLT"})
+	require.NoError(t, err)
+	require.False(t, r.Review)
+	require.Equal(t, "transport", r.Category)
+}
+
+func Test_Tokenize(t *testing.T) {
+	s := p24.Statement{Description: "Оплата: Сільпо #123, a b2", Terminal: "SILPO/12"}
+	require.Equal(t, []string{"оплата", "сільпо", "b2", "t:silpo"}, Tokenize(&s))
+}
@@ -0,0 +1,164 @@
+package category
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/export"
+	"github.com/dimboknv/p24-cli/pipeline"
+	"github.com/pkg/errors"
+)
+
+// DefaultMinConfidence is a minimal confidence of classifier suggestion to be used as a category
+const DefaultMinConfidence = 0.8
+
+// Categorizer categorises statements by manually assigned labels of store, then by rules,
+// statements without matched rules are categorised by classifier suggestions
+type Categorizer struct {
+	Store         *Store      // optional
+	Rules         *Rules      // optional
+	Classifier    *Classifier // optional
+	MinConfidence float64     // suggestions with lower confidence are flagged for review
+
+	results map[p24.Statement]Result // results of categorised statements, see Memoize
+}
+
+// Result is a category of a statement
+type Result struct {
+	Category    string
+	SubCategory string
+	Explain     string
+	Suggestion  Suggestion // classifier suggestion, empty if a rule is matched or there is no classifier
+	Confidence  float64    // 1 for rules, suggestion confidence for classifier and 0 if there is no suggestion
+	Review      bool       // there is only low confidence suggestion
+}
+
+// Memoize makes c reuse results of categorised statements.
+// Store, Rules and Classifier must not be changed after it
+func (c *Categorizer) Memoize() {
+	c.results = map[p24.Statement]Result{}
+}
+
+// Categorize returns category of s
+func (c *Categorizer) Categorize(s *p24.Statement) (Result, error) {
+	if c.results == nil {
+		return c.categorize(s)
+	}
+	if r, ok := c.results[*s]; ok {
+		return r, nil
+	}
+	r, err := c.categorize(s)
+	if err == nil {
+		c.results[*s] = r
+	}
+	return r, err
+}
+
+func (c *Categorizer) categorize(s *p24.Statement) (Result, error) {
+	if c.Store != nil {
+		if l, ok := c.Store.Get(pipeline.Fingerprint(s)); ok {
+			return Result{Category: l.Category, SubCategory: l.SubCategory, Explain: "assigned manually", Confidence: 1}, nil
+		}
+	}
+	if c.Rules != nil {
+		m, err := c.Rules.Match(s)
+		if err != nil {
+			return Result{}, err
+		}
+		if m.Rule != nil {
+			return Result{Category: m.Rule.Category, SubCategory: m.Rule.SubCategory, Explain: m.Explain(), Confidence: 1}, nil
+		}
+	}
+
+	if c.Classifier == nil {
+		return Result{Explain: Match{}.Explain()}, nil
+	}
+	res := Result{Suggestion: c.Classifier.Suggest(s)}
+	switch {
+	case res.Suggestion.Category == "":
+		res.Explain = "no rule matched, classifier is not trained"
+	case res.Suggestion.Confidence < c.MinConfidence:
+		res.Review, res.Confidence = true, res.Suggestion.Confidence
+		res.Explain = fmt.Sprintf("needs review: suggested %s with confidence %.2f", res.Suggestion.Category, res.Suggestion.Confidence)
+	default:
+		res.Category, res.Confidence = res.Suggestion.Category, res.Suggestion.Confidence
+		res.Explain = fmt.Sprintf("suggested %s with confidence %.2f", res.Suggestion.Category, res.Suggestion.Confidence)
+	}
+	return res, nil
+}
+
+// Automatic returns label of s by rules or classifier suggestion, manually assigned labels of store are ignored
+func (c *Categorizer) Automatic(s *p24.Statement) (Label, error) {
+	auto := *c
+	auto.Store, auto.results = nil, nil
+	r, err := auto.Categorize(s)
+	return Label{Category: r.Category, SubCategory: r.SubCategory}, err
+}
+
+// Categories returns sorted unique list of known categories of store, rules and classifier
+func (c *Categorizer) Categories() []string {
+	set := map[string]struct{}{}
+	if c.Store != nil {
+		for _, l := range c.Store.Labels {
+			set[l.Category] = struct{}{}
+		}
+	}
+	if c.Rules != nil {
+		for _, category := range c.Rules.Categories() {
+			set[category] = struct{}{}
+		}
+	}
+	if c.Classifier != nil {
+		for category := range c.Classifier.Docs {
+			set[category] = struct{}{}
+		}
+	}
+
+	res := make([]string, 0, len(set))
+	for category := range set {
+		res = append(res, category)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Fields returns virtual fields of statements categorised by c:
+// - Category and SubCategory, empty if there is no matched rule or confident suggestion
+// - CategoryRule is explanation of the category, see Match.Explain
+// - SuggestedCategory and CategoryConfidence of classifier suggestion
+// - NeedsReview is true if there is only low confidence suggestion
+//
+// Fields return an error if c is nil
+func Fields(c *Categorizer) export.VirtualFields {
+	field := func(f func(r Result) interface{}) export.VirtualField {
+		return func(obj interface{}) (interface{}, error) {
+			if c == nil {
+				return nil, errors.New("categorisation rules or model are not set")
+			}
+			var s p24.Statement
+			switch v := obj.(type) {
+			case *p24.Statement:
+				s = *v
+			case p24.Statement:
+				s = v
+			default:
+				return nil, errors.Errorf("%T is not a statement", obj)
+			}
+			r, err := c.Categorize(&s)
+			if err != nil {
+				return nil, err
+			}
+			return f(r), nil
+		}
+	}
+
+	return export.VirtualFields{
+		"Category":           field(func(r Result) interface{} { return r.Category }),
+		"SubCategory":        field(func(r Result) interface{} { return r.SubCategory }),
+		"CategoryRule":       field(func(r Result) interface{} { return r.Explain }),
+		"SuggestedCategory":  field(func(r Result) interface{} { return r.Suggestion.Category }),
+		"CategoryConfidence": field(func(r Result) interface{} { return r.Confidence }),
+		"NeedsReview":        field(func(r Result) interface{} { return r.Review }),
+	}
+}
@@ -0,0 +1,277 @@
+// Package category implements statements categorisation
+package category
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/export"
+	"github.com/dimboknv/p24-cli/expr"
+	"github.com/dimboknv/p24-cli/merchant"
+	"github.com/dimboknv/p24-cli/pipeline"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Rules matching strategies
+const (
+	// FirstMatch strategy selects the first matched rule in file order
+	FirstMatch = "first"
+	// PriorityMatch strategy selects matched rule with the highest priority,
+	// rules with the same priority are ordered as in file
+	PriorityMatch = "priority"
+)
+
+// Rules is a list of categorisation rules
+type Rules struct {
+	Strategy  string               `yaml:"strategy"`
+	Rules     []*Rule              `yaml:"rules"`
+	Merchants *merchant.Normalizer `yaml:"-"` // merchant names of "merchant" conditions, builtin heuristics if nil
+}
+
+// Range is an inclusive range of amounts, nil bound is unlimited
+type Range struct {
+	Min *float64 `yaml:"min"`
+	Max *float64 `yaml:"max"`
+}
+
+// Rule matches statements and assigns category to them.
+// All specified conditions must match
+type Rule struct {
+	description *regexp.Regexp
+	terminal    *regexp.Regexp
+	merchant    *regexp.Regexp
+	when        *expr.Expr
+	weekdays    map[time.Weekday]struct{}
+
+	Amount      *Range   `yaml:"amount"`      // CardAmount range
+	Name        string   `yaml:"name"`        // rule name, category/subcategory by default
+	Category    string   `yaml:"category"`    // required
+	SubCategory string   `yaml:"subcategory"` // optional
+	Description string   `yaml:"description"` // Description regexp
+	Terminal    string   `yaml:"terminal"`    // Terminal regexp
+	Merchant    string   `yaml:"merchant"`    // canonical merchant name regexp, see merchant.Normalizer
+	Currency    string   `yaml:"currency"`    // Amount or CardAmount currency
+	When        string   `yaml:"when"`        // boolean expression of statement fields, Fingerprint and Merchant, see Rules.Fields
+	Weekdays    []string `yaml:"weekdays"`    // TranDate weekdays, e.g. Saturday
+	Priority    int      `yaml:"priority"`    // used by "priority" strategy
+}
+
+// Match is a matched rule with reasons of matching
+type Match struct {
+	Rule    *Rule
+	Reasons []string
+}
+
+// Explain returns human readable reason of matching
+func (m Match) Explain() string {
+	if m.Rule == nil {
+		return "no rule matched"
+	}
+	return fmt.Sprintf("%s: %s", m.Rule.Name, strings.Join(m.Reasons, " && "))
+}
+
+// LoadRules reads Rules from yaml file
+func LoadRules(filename string) (*Rules, error) {
+	data, err := os.ReadFile(filepath.Clean(filename))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read rules %q", filename)
+	}
+
+	rules := &Rules{}
+	if err := yaml.Unmarshal(data, rules); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse rules %q", filename)
+	}
+	if err := rules.Compile(); err != nil {
+		return nil, errors.Wrapf(err, "invalid rules %q", filename)
+	}
+	return rules, nil
+}
+
+// Compile validates rules and prepares them for matching. It must be called before Match
+func (r *Rules) Compile() error {
+	switch r.Strategy {
+	case "":
+		r.Strategy = FirstMatch
+	case FirstMatch, PriorityMatch:
+	default:
+		return errors.Errorf("unknown strategy %q", r.Strategy)
+	}
+
+	fields := r.Fields()
+	for i, rule := range r.Rules {
+		if err := rule.compile(fields); err != nil {
+			return errors.Wrapf(err, "rule #%d %q", i+1, rule.Name)
+		}
+	}
+
+	if r.Strategy == PriorityMatch {
+		sort.SliceStable(r.Rules, func(i, j int) bool {
+			return r.Rules[i].Priority > r.Rules[j].Priority
+		})
+	}
+	return nil
+}
+
+// Match returns the rule selected for s by rules strategy. Match.Rule is nil if there are no matched rules
+func (r *Rules) Match(s *p24.Statement) (Match, error) {
+	env := r.Fields().Env(s)
+	for _, rule := range r.Rules {
+		reasons, ok, err := rule.match(s, r.Merchants, env)
+		if err != nil {
+			return Match{}, errors.Wrapf(err, "rule %q", rule.Name)
+		}
+		if ok {
+			return Match{Rule: rule, Reasons: reasons}, nil
+		}
+	}
+	return Match{}, nil
+}
+
+// Fields returns virtual fields of "when" expressions: pipeline.Fields and merchant.Fields by Merchants
+func (r *Rules) Fields() export.VirtualFields {
+	fields := pipeline.Fields()
+	for name, f := range merchant.Fields(r.Merchants) {
+		fields[name] = f
+	}
+	return fields
+}
+
+// Categories returns sorted unique list of rules categories
+func (r *Rules) Categories() []string {
+	set := map[string]struct{}{}
+	for _, rule := range r.Rules {
+		set[rule.Category] = struct{}{}
+	}
+	res := make([]string, 0, len(set))
+	for c := range set {
+		res = append(res, c)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func (rule *Rule) compile(fields export.VirtualFields) (err error) {
+	if rule.Category == "" {
+		return errors.New("category is required")
+	}
+	if rule.Name == "" {
+		rule.Name = strings.Trim(rule.Category+"/"+rule.SubCategory, "/")
+	}
+
+	if rule.Description != "" {
+		if rule.description, err = regexp.Compile(rule.Description); err != nil {
+			return errors.Wrap(err, "invalid description regexp")
+		}
+	}
+	if rule.Terminal != "" {
+		if rule.terminal, err = regexp.Compile(rule.Terminal); err != nil {
+			return errors.Wrap(err, "invalid terminal regexp")
+		}
+	}
+	if rule.Merchant != "" {
+		if rule.merchant, err = regexp.Compile(rule.Merchant); err != nil {
+			return errors.Wrap(err, "invalid merchant regexp")
+		}
+	}
+	if rule.When != "" {
+		if rule.when, err = expr.Parse(rule.When); err != nil {
+			return errors.Wrap(err, "invalid when expression")
+		}
+		if err = fields.Check(p24.Statement{}, rule.when); err != nil {
+			return errors.Wrap(err, "invalid when expression")
+		}
+	}
+
+	if len(rule.Weekdays) > 0 {
+		rule.weekdays = map[time.Weekday]struct{}{}
+	}
+	for _, wd := range rule.Weekdays {
+		d, err := parseWeekday(wd)
+		if err != nil {
+			return err
+		}
+		rule.weekdays[d] = struct{}{}
+	}
+	return nil
+}
+
+// match returns list of matched conditions and true if all of rule conditions are matched
+func (rule *Rule) match(s *p24.Statement, merchants *merchant.Normalizer, env expr.Env) ([]string, bool, error) {
+	var reasons []string
+	if rule.description != nil {
+		if !rule.description.MatchString(s.Description) {
+			return nil, false, nil
+		}
+		reasons = append(reasons, fmt.Sprintf("Description ~ %q", rule.Description))
+	}
+	if rule.terminal != nil {
+		if !rule.terminal.MatchString(s.Terminal) {
+			return nil, false, nil
+		}
+		reasons = append(reasons, fmt.Sprintf("Terminal ~ %q", rule.Terminal))
+	}
+	if rule.merchant != nil {
+		if !rule.merchant.MatchString(merchants.Name(s)) {
+			return nil, false, nil
+		}
+		reasons = append(reasons, fmt.Sprintf("Merchant ~ %q", rule.Merchant))
+	}
+	if rule.Currency != "" {
+		if !strings.EqualFold(s.Amount.Currency, rule.Currency) && !strings.EqualFold(s.CardAmount.Currency, rule.Currency) {
+			return nil, false, nil
+		}
+		reasons = append(reasons, fmt.Sprintf("currency == %s", rule.Currency))
+	}
+	if rule.Amount != nil {
+		a := s.CardAmount.Amount.Float64()
+		if (rule.Amount.Min != nil && a < *rule.Amount.Min) || (rule.Amount.Max != nil && a > *rule.Amount.Max) {
+			return nil, false, nil
+		}
+		reasons = append(reasons, fmt.Sprintf("CardAmount in %s", rule.Amount))
+	}
+	if rule.weekdays != nil {
+		if _, ok := rule.weekdays[s.TranDate.Weekday()]; !ok {
+			return nil, false, nil
+		}
+		reasons = append(reasons, fmt.Sprintf("weekday %s", s.TranDate.Weekday()))
+	}
+	if rule.when != nil {
+		ok, err := rule.when.EvalBool(env)
+		if err != nil || !ok {
+			return nil, false, err
+		}
+		reasons = append(reasons, rule.When)
+	}
+
+	if len(reasons) == 0 {
+		reasons = append(reasons, "always")
+	}
+	return reasons, true, nil
+}
+
+// String returns Range string representation, e.g. "[-100, 0]"
+func (r *Range) String() string {
+	bound := func(f *float64, inf string) string {
+		if f == nil {
+			return inf
+		}
+		return fmt.Sprint(*f)
+	}
+	return fmt.Sprintf("[%s, %s]", bound(r.Min, "-inf"), bound(r.Max, "+inf"))
+}
+
+func parseWeekday(str string) (time.Weekday, error) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if name := d.String(); strings.EqualFold(str, name) || strings.EqualFold(str, name[:3]) {
+			return d, nil
+		}
+	}
+	return 0, errors.Errorf("invalid weekday %q", str)
+}
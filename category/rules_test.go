@@ -0,0 +1,172 @@
+package category
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/stretchr/testify/require"
+)
+
+const testRules = `
+rules:
+  - name: taxi
+    category: transport
+    subcategory: taxi
+    description: "(?i)uber|bolt"
+  - category: groceries
+    terminal: "(?i)silpo"
+    amount: {max: 0}
+  - category: leisure
+    weekdays: [sat, Sunday]
+    currency: usd
+  - category: payroll
+    when: 'CardAmount > 1000'
+  - category: shopping
+    merchant: "^Rozetka$"
+  - name: books
+    category: shopping
+    when: 'Merchant == "Yakaboo" && CardAmount < 0'
+`
+
+func Test_Rules_Match(t *testing.T) {
+	monday, saturday := time.Date(2022, 1, 3, 10, 0, 0, 0, time.UTC), time.Date(2022, 1, 8, 10, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		strategy  string
+		statement p24.Statement
+		rule      string
+		explain   string
+	}{
+		{
+			statement: p24.Statement{Description: "UBER trip", TranDate: monday},
+			rule:      "taxi",
+			explain:   `taxi: Description ~ "(?i)uber|bolt"`,
+		},
+		{
+			statement: p24.Statement{Terminal: "SILPO 12", CardAmount: p24.Funds{Currency: "UAH", Amount: -1000}, TranDate: monday},
+			rule:      "groceries",
+			explain:   `groceries: Terminal ~ "(?i)silpo" && CardAmount in [-inf, 0]`,
+		},
+		{
+			statement: p24.Statement{Terminal: "SILPO 12", CardAmount: p24.Funds{Currency: "UAH", Amount: 1000}, TranDate: monday},
+		},
+		{
+			statement: p24.Statement{Amount: p24.Funds{Currency: "USD", Amount: 100}, TranDate: saturday},
+			rule:      "leisure",
+			explain:   "leisure: currency == usd && weekday Saturday",
+		},
+		{
+			statement: p24.Statement{Description: "Bolt", CardAmount: p24.Funds{Currency: "UAH", Amount: 200000}, TranDate: monday},
+			rule:      "taxi",
+		},
+		{
+			strategy:  "priority",
+			statement: p24.Statement{Description: "Bolt", CardAmount: p24.Funds{Currency: "UAH", Amount: 200000}, TranDate: monday},
+			rule:      "payroll",
+			explain:   "payroll: CardAmount > 1000",
+		},
+		{
+			statement: p24.Statement{Description: "ROZETKA.COM.UA 123", TranDate: monday},
+			rule:      "shopping",
+			explain:   `shopping: Merchant ~ "^Rozetka$"`,
+		},
+		{
+			statement: p24.Statement{Description: "YAKABOO.UA 42", CardAmount: p24.Funds{Currency: "UAH", Amount: -30000}, TranDate: monday},
+			rule:      "books",
+			explain:   `books: Merchant == "Yakaboo" && CardAmount < 0`,
+		},
+		{
+			statement: p24.Statement{Description: "unknown", TranDate: monday},
+			explain:   "no rule matched",
+		},
+	}
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			rules := loadTestRules(t, testRules)
+			if c.strategy == PriorityMatch {
+				rules.Strategy = c.strategy
+				rules.Rules[3].Priority = 10
+				require.NoError(t, rules.Compile())
+			}
+
+			m, err := rules.Match(&c.statement)
+			require.NoError(t, err)
+			if c.rule == "" {
+				require.Nil(t, m.Rule)
+			} else {
+				require.NotNil(t, m.Rule)
+				require.Equal(t, c.rule, m.Rule.Name)
+			}
+			if c.explain != "" {
+				require.Equal(t, c.explain, m.Explain())
+			}
+		})
+	}
+}
+
+func Test_LoadRules(t *testing.T) {
+	cases := []struct {
+		rules   string
+		withErr bool
+	}{
+		{rules: testRules},
+		{rules: "rules: [{description: uber}]", withErr: true},
+		{rules: "rules: [{category: a, description: '('}]", withErr: true},
+		{rules: "rules: [{category: a, weekdays: [someday]}]", withErr: true},
+		{rules: "rules: [{category: a, when: 'A &&'}]", withErr: true},
+		{rules: "rules: [{category: a, when: 'Category == \"b\"'}]", withErr: true},
+		{rules: "strategy: last", withErr: true},
+		{rules: "rules: {", withErr: true},
+	}
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "rules.yml")
+			require.NoError(t, os.WriteFile(filename, []byte(c.rules), 0o600))
+			_, err := LoadRules(filename)
+			require.True(t, c.withErr == (err != nil), err)
+		})
+	}
+}
+
+func Test_Fields(t *testing.T) {
+	rules := loadTestRules(t, testRules)
+	s := p24.Statement{Description: "uber"}
+
+	fields := Fields(&Categorizer{Rules: rules})
+	v, err := fields["Category"](&s)
+	require.NoError(t, err)
+	require.Equal(t, "transport", v)
+	v, err = fields["SubCategory"](s)
+	require.NoError(t, err)
+	require.Equal(t, "taxi", v)
+	require.Equal(t, []string{"groceries", "leisure", "payroll", "shopping", "transport"}, rules.Categories())
+
+	_, err = Fields(nil)["Category"](&s)
+	require.Error(t, err)
+
+	// memoized results are reused
+	c := &Categorizer{Rules: rules}
+	c.Memoize()
+	r, err := c.Categorize(&s)
+	require.NoError(t, err)
+	c.Rules = nil
+	memoized, err := c.Categorize(&s)
+	require.NoError(t, err)
+	require.Equal(t, r, memoized)
+	require.Equal(t, "transport", memoized.Category)
+}
+
+func loadTestRules(t *testing.T, str string) *Rules {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "rules.yml")
+	require.NoError(t, os.WriteFile(filename, []byte(str), 0o600))
+	rules, err := LoadRules(filename)
+	require.NoError(t, err)
+	return rules
+}
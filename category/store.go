@@ -0,0 +1,88 @@
+package category
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// Label is a manually assigned category of a statement
+type Label struct {
+	Category    string `json:"category"`
+	SubCategory string `json:"subcategory,omitempty"`
+}
+
+// Store is a local json file of manually assigned categories by statement fingerprint
+type Store struct {
+	filename string
+	Labels   map[string]Label `json:"labels"`
+}
+
+// DefaultStorePath returns default store file path, "$XDG_CONFIG_HOME/p24/categories.json" on linux
+func DefaultStorePath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "p24", "categories.json")
+}
+
+// OpenStore reads Store from filename. Returns empty Store if the file does not exist
+func OpenStore(filename string) (*Store, error) {
+	s := &Store{filename: filename, Labels: map[string]Label{}}
+	data, err := os.ReadFile(filepath.Clean(filename))
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read store %q", filename)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse store %q", filename)
+	}
+	if s.Labels == nil {
+		s.Labels = map[string]Label{}
+	}
+	return s, nil
+}
+
+// Get returns label of a statement by fingerprint
+func (s *Store) Get(fingerprint string) (Label, bool) {
+	l, ok := s.Labels[fingerprint]
+	return l, ok
+}
+
+// Set sets label of a statement by fingerprint, label with empty category removes it.
+// Returns true if the store is changed
+func (s *Store) Set(fingerprint string, l Label) bool {
+	old, ok := s.Labels[fingerprint]
+	if l.Category == "" {
+		delete(s.Labels, fingerprint)
+		return ok
+	}
+	s.Labels[fingerprint] = l
+	return !ok || old != l
+}
+
+// Assign sets label l of a statement unless it equals label of rules or classifier, then the manually assigned
+// label is removed, so the statement follows later rules changes. Returns true if the store is changed
+func (s *Store) Assign(fingerprint string, l, automatic Label) bool {
+	if l == automatic {
+		l = Label{}
+	}
+	return s.Set(fingerprint, l)
+}
+
+// Save writes Store to its file, parent directory is created if needed
+func (s *Store) Save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.filename), 0o700); err != nil {
+		return errors.Wrapf(err, "failed to create store directory")
+	}
+	return errors.Wrapf(os.WriteFile(s.filename, data, 0o600), "failed to write store %q", s.filename)
+}
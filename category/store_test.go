@@ -0,0 +1,49 @@
+package category
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/pipeline"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Store(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "p24", "categories.json")
+	store, err := OpenStore(filename)
+	require.NoError(t, err)
+	require.Empty(t, store.Labels)
+
+	s := p24.Statement{Appcode: "1", Description: "Uber trip"}
+	require.True(t, store.Set(pipeline.Fingerprint(&s), Label{Category: "transport", SubCategory: "taxi"}))
+	require.False(t, store.Set(pipeline.Fingerprint(&s), Label{Category: "transport", SubCategory: "taxi"}))
+	require.True(t, store.Set("unknown", Label{Category: "other"}))
+	require.True(t, store.Set("unknown", Label{}))
+	require.False(t, store.Set("unknown", Label{}))
+	require.NoError(t, store.Save())
+
+	store, err = OpenStore(filename)
+	require.NoError(t, err)
+	require.Len(t, store.Labels, 1)
+
+	rules := loadTestRules(t, testRules)
+	r, err := (&Categorizer{Store: store, Rules: rules}).Categorize(&s)
+	require.NoError(t, err)
+	require.Equal(t, Result{Category: "transport", SubCategory: "taxi", Explain: "assigned manually", Confidence: 1}, r)
+
+	r, err = (&Categorizer{Store: store, Rules: rules}).Categorize(&p24.Statement{Description: "Bolt"})
+	require.NoError(t, err)
+	require.Equal(t, "taxi", r.SubCategory)
+	require.Equal(t, []string{"groceries", "leisure", "payroll", "shopping", "transport"}, (&Categorizer{Store: store, Rules: rules}).Categories())
+
+	// labels equal to rules categories are not stored and remove manually assigned ones
+	bolt := p24.Statement{Appcode: "2", Description: "Bolt"}
+	auto, err := (&Categorizer{Store: store, Rules: rules}).Automatic(&bolt)
+	require.NoError(t, err)
+	require.Equal(t, Label{Category: "transport", SubCategory: "taxi"}, auto)
+	require.False(t, store.Assign(pipeline.Fingerprint(&bolt), Label{Category: "transport", SubCategory: "taxi"}, auto))
+	require.True(t, store.Assign(pipeline.Fingerprint(&bolt), Label{Category: "leisure"}, auto))
+	require.True(t, store.Assign(pipeline.Fingerprint(&bolt), Label{Category: "transport", SubCategory: "taxi"}, auto))
+	require.Len(t, store.Labels, 1)
+}
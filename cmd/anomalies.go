@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/anomaly"
+	"github.com/dimboknv/p24-cli/config"
+	"github.com/dimboknv/p24-cli/export"
+	"github.com/dimboknv/p24-cli/pipeline"
+	log "github.com/go-pkgz/lgr"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultAnomaliesFormat   = "Score{precision=2}|Kinds|TranDate|Terminal|Description|CardAmount|Count|Reason|,"
+	defaultAnomaliesEncoding = "table"
+)
+
+// AnomaliesCmd set of flags for unusual statements detection
+// nolint:govet // need to save command arguments order
+type AnomaliesCmd struct {
+	StatementsSourceOpts
+	CategoryOpts
+	MerchantOpts
+	ZScore     float64 `long:"z-score" default:"3.5" description:"Robust z-score of unusual amounts and daily counts of statements"`
+	MinHistory int     `long:"min-history" default:"5" description:"Minimal count of terminal or category statements to check amounts"`
+	ExportOpts
+}
+
+// Execute detects unusual statements, entry point for "anomalies" command
+func (cmd *AnomaliesCmd) Execute(_ []string) error {
+	log.Printf("[INFO] \"anomalies\" command is started")
+
+	if err := cmd.setup(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		defer cancel()
+		cmd.waitSigterm(ctx)
+	}()
+
+	statements, err := cmd.loadStatements(ctx, cmd.fields())
+	if err != nil {
+		return errors.Wrap(err, "failed to get statements list")
+	}
+
+	opts := anomaly.Options{Groups: []anomaly.Group{anomaly.TerminalGroup}, ZScore: cmd.ZScore, MinHistory: cmd.MinHistory}
+	if cmd.categorizer != nil {
+		opts.Groups = append(opts.Groups, anomaly.Group{Name: "Category", Key: func(s *p24.Statement) (string, error) {
+			r, err := cmd.categorizer.Categorize(s)
+			return r.Category, err
+		}})
+	}
+	anomalies, err := anomaly.Detect(statements.Statements, opts)
+	if err != nil {
+		return errors.Wrap(err, "failed to detect anomalies")
+	}
+	cmd.report("detected %d anomalies in %d statements", len(anomalies), len(statements.Statements))
+
+	if err = cmd.export(anomaly.Rows(anomalies)); err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] \"anomalies\" command succeeded terminated")
+	return nil
+}
+
+func (cmd *AnomaliesCmd) setup() error {
+	if cmd.ZScore <= 0 || cmd.MinHistory <= 0 {
+		return errors.New("z-score and min history should be positive")
+	}
+	if err := cmd.setupMerchants(&cmd.CommonOpts); err != nil {
+		return errors.Wrapf(err, "invalid merchant options")
+	}
+	if err := cmd.setupCategorizer(&cmd.CommonOpts, cmd.merchants); err != nil {
+		return errors.Wrapf(err, "invalid categorisation options")
+	}
+
+	defaults := config.Preset{Format: defaultAnomaliesFormat, Encoding: defaultAnomaliesEncoding}
+	if err := cmd.setupExport(&cmd.CommonOpts, defaults, anomaly.Anomaly{}, nil); err != nil {
+		return err
+	}
+
+	return cmd.setupSource(cmd.fields())
+}
+
+// fields returns virtual fields of statements available for filters
+func (cmd *AnomaliesCmd) fields() export.VirtualFields {
+	fields := pipeline.Fields()
+	for name, f := range cmd.categoryFields() {
+		fields[name] = f
+	}
+	for name, f := range cmd.merchantFields() {
+		fields[name] = f
+	}
+	return fields
+}
@@ -124,8 +124,8 @@ func (cmd *BalanceCmd) makeMarshaller() (marhsaller, error) {
 }
 
 func (cmd *BalanceCmd) setup() (err error) {
-	if err := p24.CheckCardNumber(cmd.Card); err != nil {
-		return errors.Wrapf(err, "invalid card number")
+	if err := cmd.checkCredentials(); err != nil {
+		return err
 	}
 
 	if _, err := cmd.makeMarshaller(); err != nil {
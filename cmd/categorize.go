@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/config"
+	"github.com/dimboknv/p24-cli/export"
+	"github.com/dimboknv/p24-cli/pipeline"
+	log "github.com/go-pkgz/lgr"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultCategorizeFormat   = "Fingerprint|TranDate|CardAmount|Description|Terminal|Category|SubCategory|NeedsReview|,"
+	defaultCategorizeEncoding = "csv"
+)
+
+// CategorizeCmd set of flags for statements categorisation by rules
+// nolint:govet // need to save command arguments order
+type CategorizeCmd struct {
+	StatementsSourceOpts
+	CategoryOpts
+	MerchantOpts
+	SortOpts
+	ExportOpts
+	Explain bool `long:"explain" description:"Add CategoryRule column with the rule or suggestion fired for each statement"`
+}
+
+// Execute categorises statements list, entry point for "categorize" command
+func (cmd *CategorizeCmd) Execute(_ []string) error {
+	log.Printf("[INFO] \"categorize\" command is started rules=%s model=%s explain=%t", cmd.RulesFile, cmd.ModelFile, cmd.Explain)
+
+	if err := cmd.setup(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		defer cancel()
+		cmd.waitSigterm(ctx)
+	}()
+
+	statements, err := cmd.loadStatements(ctx, cmd.fields())
+	if err != nil {
+		return errors.Wrap(err, "failed to get statements list")
+	}
+
+	uncategorised, review := 0, 0
+	for i := range statements.Statements {
+		r, err := cmd.categorizer.Categorize(&statements.Statements[i])
+		if err != nil {
+			return errors.Wrap(err, "failed to categorise statements")
+		}
+		if r.Review {
+			review++
+		}
+		if r.Category == "" {
+			uncategorised++
+		}
+	}
+	if uncategorised > 0 {
+		cmd.report("%d of %d statements are uncategorised, %d of them need review", uncategorised, len(statements.Statements), review)
+	}
+
+	if err = cmd.sort(statements.Statements, cmd.fields()); err != nil {
+		return err
+	}
+
+	if err = cmd.export(export.StatementsRows(statements)); err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] \"categorize\" command succeeded terminated")
+	return nil
+}
+
+func (cmd *CategorizeCmd) setup() error {
+	if err := cmd.setupMerchants(&cmd.CommonOpts); err != nil {
+		return errors.Wrapf(err, "invalid merchant options")
+	}
+	if err := cmd.setupCategorizer(&cmd.CommonOpts, cmd.merchants); err != nil {
+		return errors.Wrapf(err, "invalid categorisation options")
+	}
+	if cmd.categorizer == nil {
+		return errors.New("categorisation rules or model are required, use --rules, --model or config file")
+	}
+	// statements are categorised by filters, the uncategorised count, sort and export
+	cmd.categorizer.Memoize()
+
+	defaults := config.Preset{Format: defaultCategorizeFormat, Encoding: defaultCategorizeEncoding}
+	if err := cmd.setupExport(&cmd.CommonOpts, defaults, p24.Statement{}, cmd.fields()); err != nil {
+		return err
+	}
+	cmd.exportOpts = append(cmd.exportOpts, cmd.categoryExportOpts()...)
+
+	// explanation is the last column of any export format
+	if cmd.Explain && !contains(cmd.exportFormat.Fields, "CategoryRule") {
+		cmd.exportFormat.Fields = append(cmd.exportFormat.Fields, "CategoryRule")
+	}
+
+	if err := cmd.setupSort(cmd.fields()); err != nil {
+		return err
+	}
+
+	return cmd.setupSource(cmd.fields())
+}
+
+// fields returns virtual fields available for export format, sort keys and filters
+func (cmd *CategorizeCmd) fields() export.VirtualFields {
+	fields := pipeline.Fields()
+	for name, f := range cmd.categoryFields() {
+		fields[name] = f
+	}
+	for name, f := range cmd.merchantFields() {
+		fields[name] = f
+	}
+	return fields
+}
+
+func contains(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
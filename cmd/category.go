@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"github.com/dimboknv/p24-cli/category"
+	"github.com/dimboknv/p24-cli/config"
+	"github.com/dimboknv/p24-cli/export"
+	"github.com/dimboknv/p24-cli/merchant"
+	log "github.com/go-pkgz/lgr"
+	"github.com/jessevdk/go-flags"
+)
+
+// CategoryOpts set of flags for statements categorisation
+// nolint:govet // need to save command arguments order
+type CategoryOpts struct {
+	RulesFile     flags.Filename `long:"rules" description:"Categorisation rules yaml file, config file \"rules\" by default"`
+	ModelFile     flags.Filename `long:"model" description:"Category classifier model file, config file \"model\" by default"`
+	StoreFile     flags.Filename `long:"store" description:"Manually assigned categories json file, config file \"store\" by default"`
+	MinConfidence float64        `long:"min-confidence" default:"0.8" description:"Minimal confidence of classifier suggestion, less confident statements are flagged for review"` // nolint
+
+	categorizer *category.Categorizer
+}
+
+// setupCategorizer loads manually assigned categories store, categorisation rules
+// and classifier model from flags or config file if any of them is set.
+// merchants are merchant names of rules "merchant" conditions, builtin heuristics if nil
+func (opts *CategoryOpts) setupCategorizer(common *CommonOpts, merchants *merchant.Normalizer) error {
+	cfg, err := common.loadConfig()
+	if err != nil {
+		return err
+	}
+
+	c := &category.Categorizer{MinConfidence: opts.MinConfidence}
+	store, err := opts.openStore(cfg)
+	if err != nil {
+		return err
+	}
+	if len(store.Labels) > 0 {
+		c.Store = store
+	}
+	if filename := firstNonEmpty(string(opts.RulesFile), cfg.Rules); filename != "" {
+		log.Printf("[DEBUG] loading categorisation rules from %q", filename)
+		if c.Rules, err = category.LoadRules(filename); err != nil {
+			return err
+		}
+		c.Rules.Merchants = merchants
+	}
+	if filename := firstNonEmpty(string(opts.ModelFile), cfg.Model); filename != "" {
+		log.Printf("[DEBUG] loading classifier model from %q", filename)
+		if c.Classifier, err = category.LoadClassifier(filename); err != nil {
+			return err
+		}
+	}
+
+	if c.Store != nil || c.Rules != nil || c.Classifier != nil {
+		opts.categorizer = c
+	}
+	return nil
+}
+
+// categoryFields returns category virtual fields, see category.Fields
+func (opts *CategoryOpts) categoryFields() export.VirtualFields {
+	return category.Fields(opts.categorizer)
+}
+
+// categoryExportOpts returns xlsx Category column drop-down list option of known categories
+func (opts *CategoryOpts) categoryExportOpts() []export.Option {
+	if opts.categorizer == nil {
+		return nil
+	}
+	return []export.Option{export.WithDropdown("Category", opts.categorizer.Categories())}
+}
+
+func (opts *CategoryOpts) openStore(cfg config.Config) (*category.Store, error) {
+	filename := firstNonEmpty(string(opts.StoreFile), cfg.Store, category.DefaultStorePath())
+	log.Printf("[DEBUG] opening categories store %q", filename)
+	return category.OpenStore(filename)
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"os"
 	"os/signal"
@@ -9,10 +10,12 @@ import (
 	"time"
 
 	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/config"
 	p24http "github.com/dimboknv/p24-cli/http"
 	"github.com/dimboknv/p24-cli/pb"
 	log "github.com/go-pkgz/lgr"
 	"github.com/hashicorp/go-retryablehttp"
+	"github.com/pkg/errors"
 )
 
 // CommonOptionsCommander extends flags.Commander with SetCommon
@@ -31,8 +34,10 @@ type BuildInfo struct {
 
 // CommonOpts sets externally from main, shared across all commands
 type CommonOpts struct {
-	BuildInfo BuildInfo
-	Debug     bool
+	BuildInfo  BuildInfo
+	Locale     string
+	ConfigFile string
+	Debug      bool
 }
 
 // SetCommon satisfies CommonOptionsCommander interface and sets common option fields
@@ -40,6 +45,20 @@ type CommonOpts struct {
 func (opts *CommonOpts) SetCommon(commonOpts CommonOpts) {
 	opts.BuildInfo = commonOpts.BuildInfo
 	opts.Debug = commonOpts.Debug
+	opts.Locale = commonOpts.Locale
+	opts.ConfigFile = commonOpts.ConfigFile
+}
+
+// report prints a message for user to stderr, e.g. count of dropped duplicates
+func (opts *CommonOpts) report(format string, args ...interface{}) {
+	log.Printf("[INFO] "+format, args...)
+	if !opts.Debug { // debug logs are printed to stderr already
+		_, _ = fmt.Fprintf(os.Stderr, format+"\n", args...)
+	}
+}
+
+func (opts *CommonOpts) loadConfig() (config.Config, error) {
+	return config.Load(opts.ConfigFile)
 }
 
 func (opts *CommonOpts) waitSigterm(ctx context.Context) {
@@ -57,13 +76,27 @@ func (opts *CommonOpts) waitSigterm(ctx context.Context) {
 // CommonP24Opts struct with common options and funcs for p24 api commands
 // nolint:govet // need to save command arguments order
 type CommonP24Opts struct {
-	ID          string        `long:"id" required:"true" description:"Merchant id"`
-	Password    string        `long:"pass" required:"true" description:"Merchant password"`
-	Card        string        `long:"card" required:"true" description:"Merchant card number"`
+	ID          string        `long:"id" description:"Merchant id"`
+	Password    string        `long:"pass" description:"Merchant password"`
+	Card        string        `long:"card" description:"Merchant card number"`
 	HTTPTimeout time.Duration `long:"timeout" default:"90s" description:"http request timeout"`
 	CommonOpts
 }
 
+// checkCredentials returns an error if merchant credentials are not set or card number is invalid
+func (opts *CommonP24Opts) checkCredentials() error {
+	switch {
+	case opts.ID == "":
+		return errors.New("merchant id is required")
+	case opts.Password == "":
+		return errors.New("merchant password is required")
+	}
+	if err := p24.CheckCardNumber(opts.Card); err != nil {
+		return errors.Wrapf(err, "invalid card number")
+	}
+	return nil
+}
+
 func (opts *CommonP24Opts) makeP24Client() *p24.Client {
 	retryHTTP := retryablehttp.NewClient()
 	retryHTTP.HTTPClient.Timeout = opts.HTTPTimeout
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/dimboknv/p24-cli/balance"
+	"github.com/dimboknv/p24-cli/config"
+	"github.com/dimboknv/p24-cli/export"
+	"github.com/dimboknv/p24-cli/pipeline"
+	log "github.com/go-pkgz/lgr"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultIssuesFormat       = "Kind|Card|After|Before|Expected{precision=2}|Rest{precision=2}|Missing{precision=2}|,"
+	defaultDailyFormat        = "Date{2006-01-02}|Card|Currency|Open{precision=2}|Close{precision=2}|Min{precision=2}|Max{precision=2}|Average{precision=2}|Count|," // nolint
+	defaultContinuityEncoding = "table"
+)
+
+// ContinuityCmd set of flags for running balance continuity check
+// nolint:govet // need to save command arguments order
+type ContinuityCmd struct {
+	StatementsSourceOpts
+	Daily bool `long:"daily" description:"Export daily end-of-day, min, max and average balances instead of issues"`
+	ExportOpts
+}
+
+// Execute checks that each statement Rest is the previous Rest plus CardAmount, entry point for "continuity" command
+func (cmd *ContinuityCmd) Execute(_ []string) error {
+	log.Printf("[INFO] \"continuity\" command is started")
+
+	if err := cmd.setup(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		defer cancel()
+		cmd.waitSigterm(ctx)
+	}()
+
+	statements, err := cmd.loadStatements(ctx, pipeline.Fields())
+	if err != nil {
+		return errors.Wrap(err, "failed to get statements list")
+	}
+
+	issues := balance.Check(statements.Statements)
+	for _, issue := range issues {
+		cmd.report("%s of card %s between %s and %s: rest %s, expected %s",
+			issue.Kind, issue.Card, issue.After, issue.Before, issue.Rest, issue.Expected)
+	}
+	cmd.report("%d continuity issues in %d statements", len(issues), len(statements.Statements))
+
+	rows := balance.IssuesRows(issues)
+	if cmd.Daily {
+		rows = balance.DailyRows(balance.Daily(statements.Statements))
+	}
+	if err = cmd.export(rows); err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] \"continuity\" command succeeded terminated")
+	return nil
+}
+
+func (cmd *ContinuityCmd) setup() error {
+	defaults := config.Preset{Format: defaultIssuesFormat, Encoding: defaultContinuityEncoding}
+	var strct interface{} = balance.Issue{}
+	if cmd.Daily {
+		defaults.Format, strct = defaultDailyFormat, balance.Day{}
+	}
+	if err := cmd.setupExport(&cmd.CommonOpts, defaults, strct, nil); err != nil {
+		return err
+	}
+	if cmd.Daily {
+		chart := export.Chart{Title: "Balance", X: "Date", Y: []string{"Close", "Min", "Average"}}
+		cmd.exportOpts = append(cmd.exportOpts, export.WithChart(chart))
+	}
+
+	return cmd.setupSource(pipeline.Fields())
+}
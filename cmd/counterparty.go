@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"github.com/dimboknv/p24-cli/counterparty"
+	"github.com/dimboknv/p24-cli/export"
+	log "github.com/go-pkgz/lgr"
+	"github.com/jessevdk/go-flags"
+)
+
+// CounterpartyOpts set of flags for parsing descriptions into counterparty fields
+type CounterpartyOpts struct {
+	PatternsFile  flags.Filename `long:"patterns" description:"Description parsing patterns yaml file, config file \"patterns\" by default"`
+	DirectoryFile flags.Filename `long:"directory" description:"Counterparties directory csv file with name, edrpou and iban columns, config file \"directory\" by default"` // nolint
+
+	parser *counterparty.Parser
+}
+
+// setupParser makes description parser by builtin patterns and patterns file,
+// and loads counterparties directory from flags or config file if it is set
+func (opts *CounterpartyOpts) setupParser(common *CommonOpts) error {
+	cfg, err := common.loadConfig()
+	if err != nil {
+		return err
+	}
+
+	var patterns *counterparty.Patterns
+	if filename := firstNonEmpty(string(opts.PatternsFile), cfg.Patterns); filename != "" {
+		log.Printf("[DEBUG] loading description patterns from %q", filename)
+		if patterns, err = counterparty.LoadPatterns(filename); err != nil {
+			return err
+		}
+	}
+	if opts.parser, err = counterparty.NewParser(patterns); err != nil {
+		return err
+	}
+
+	if filename := firstNonEmpty(string(opts.DirectoryFile), cfg.Directory); filename != "" {
+		log.Printf("[DEBUG] loading counterparties directory from %q", filename)
+		if opts.parser.Directory, err = counterparty.LoadDirectory(filename); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// counterpartyFields returns Counterparty, IBAN, EDRPOU and Purpose virtual fields, see counterparty.Fields
+func (opts *CounterpartyOpts) counterpartyFields() export.VirtualFields {
+	return counterparty.Fields(opts.parser)
+}
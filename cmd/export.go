@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path"
+	"reflect"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/config"
+	"github.com/dimboknv/p24-cli/export"
+	"github.com/dimboknv/p24-cli/pipeline"
+	log "github.com/go-pkgz/lgr"
+	"github.com/jessevdk/go-flags"
+	"github.com/pkg/errors"
+)
+
+// ExportOpts set of flags for exporting statements list to a file/stdout
+// nolint:govet // need to save command arguments order
+type ExportOpts struct {
+	ExportFormatStr string         `short:"f" long:"format" description:"Export format: Field1|Name=expression|...|FieldN|delim (default: Card|Appcode|TranDate|Amount|CardAmount|Rest|Terminal|Description|,)"` // nolint
+	ExportEncoding  string         `short:"e" long:"encoding" choice:"xml" choice:"xlsx" choice:"csv" choice:"table" choice:"json" choice:"pdf" description:"Export encoding (default: xml)"`                    // nolint
+	Preset          string         `short:"p" long:"preset" description:"Export preset name from config file or builtin: ynab, firefly, actual"`
+	OutputFilename  flags.Filename `short:"o" long:"out" description:"Export statements list to a file with specified extname encoding. If empty export to stdout with '-e' encoding"` // nolint
+
+	exportFormat export.Format
+	exportOpts   []export.Option
+}
+
+// SortOpts set of flags for sorting statements list
+type SortOpts struct {
+	SortStr string `long:"sort" description:"Comma separated sort keys, '-' prefix for descending order, e.g. \"TranDate,-CardAmount\". Chronological by default"` // nolint
+
+	sortKeys []pipeline.SortKey
+}
+
+func (opts *SortOpts) setupSort(fields export.VirtualFields) (err error) {
+	opts.sortKeys, err = pipeline.ParseSortKeys(opts.SortStr, fields)
+	return errors.Wrapf(err, "invalid sort keys")
+}
+
+func (opts *SortOpts) sort(statements []p24.Statement, fields export.VirtualFields) error {
+	return errors.Wrap(pipeline.Sort(statements, opts.sortKeys, fields), "failed to sort statements")
+}
+
+// setupExport applies preset and parses export format of strct rows.
+// defaults are format and encoding used if neither flags nor preset set them
+func (opts *ExportOpts) setupExport(common *CommonOpts, defaults config.Preset, strct interface{}, fields export.VirtualFields) (err error) {
+	if err := opts.applyPreset(common, defaults); err != nil {
+		return errors.Wrapf(err, "invalid preset")
+	}
+
+	opts.exportFormat, err = export.MakeFormat(opts.ExportFormatStr, export.DefaultFormatParser(strct, fields))
+	if err != nil {
+		return errors.Wrapf(err, "invalid export format")
+	}
+
+	opts.exportFormat.Locale, err = export.LocaleByName(common.Locale)
+	if err != nil {
+		return errors.Wrapf(err, "invalid locale")
+	}
+
+	if _, err := opts.makeExporter(export.Rows{}); err != nil {
+		return errors.Wrapf(err, "invalid encoding")
+	}
+	return nil
+}
+
+// applyPreset fills export options that are not set explicitly with preset values or defaults
+func (opts *ExportOpts) applyPreset(common *CommonOpts, defaults config.Preset) error {
+	var preset config.Preset
+	if opts.Preset != "" {
+		cfg, err := common.loadConfig()
+		if err != nil {
+			return err
+		}
+		if preset, err = cfg.Preset(opts.Preset); err != nil {
+			return err
+		}
+		log.Printf("[DEBUG] use %q preset %+v", opts.Preset, preset)
+	}
+
+	opts.ExportFormatStr = firstNonEmpty(opts.ExportFormatStr, preset.Format, defaults.Format)
+	opts.ExportEncoding = firstNonEmpty(opts.ExportEncoding, preset.Encoding, defaults.Encoding)
+	common.Locale = firstNonEmpty(common.Locale, preset.Locale, defaults.Locale)
+	opts.exportOpts = []export.Option{export.WithSheet(preset.Sheet)}
+	if preset.NoHeader {
+		opts.exportOpts = append(opts.exportOpts, export.WithoutHeader())
+	}
+	return nil
+}
+
+// export exports rows to output file or stdout
+func (opts *ExportOpts) export(rows export.Rows) error {
+	var w io.Writer = os.Stdout
+	if opts.OutputFilename != "" {
+		f, err := os.OpenFile(string(opts.OutputFilename), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+		if err != nil {
+			return errors.Wrapf(err, "failed to open file %q", opts.OutputFilename)
+		}
+		log.Printf("[DEBUG] generation %q file", f.Name())
+		defer func() {
+			if err := f.Close(); err != nil {
+				log.Printf("[WARN] failed to close file %s: %s", f.Name(), err)
+			}
+		}()
+		w = f
+	}
+
+	// can skip error. it handled in setupExport
+	exporter, _ := opts.makeExporter(rows)
+	log.Printf("[DEBUG] use %q marhsaller", reflect.TypeOf(exporter).String())
+	log.Printf("[DEBUG] exporting %d rows to %q", len(rows.Items), w.(*os.File).Name())
+	return errors.Wrap(exporter.Export(w, opts.exportFormat), "failed to export")
+}
+
+func (opts *ExportOpts) makeExporter(rows export.Rows) (export.Exporter, error) {
+	encoding := opts.ExportEncoding
+	if ext := path.Ext(string(opts.OutputFilename)); ext != "" {
+		encoding = ext[1:]
+	}
+
+	switch encoding {
+	case "xml":
+		return export.NewXMLRows(rows), nil
+	case "xlsx":
+		return export.NewXLSXRows(rows, opts.exportOpts...), nil
+	case "csv":
+		return export.NewCSVRows(rows, opts.exportOpts...), nil
+	case "table", "txt":
+		return export.NewTableRows(rows, opts.exportOpts...), nil
+	case "json":
+		return export.NewJSONRows(rows), nil
+	case "pdf":
+		return export.NewPDFRows(rows, opts.exportOpts...), nil
+	default:
+		return nil, errors.Errorf("%q is unsupported", encoding)
+	}
+}
+
+func firstNonEmpty(ss ...string) string {
+	for _, s := range ss {
+		if s != "" {
+			return s
+		}
+	}
+	return ""
+}
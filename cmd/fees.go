@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/dimboknv/p24-cli/config"
+	"github.com/dimboknv/p24-cli/fx"
+	"github.com/dimboknv/p24-cli/pipeline"
+	log "github.com/go-pkgz/lgr"
+	"github.com/jessevdk/go-flags"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultFeesFormat   = "Level|Month|TranDate|Description|Count|Amount{precision=2}|CardAmount{precision=2}|EffectiveRate{precision=4}|ReferenceRate{precision=4}|RateDate|ReferenceAmount{precision=2}|Fee{precision=2}|Markup{precision=2}|," // nolint
+	defaultFeesEncoding = "table"
+)
+
+// FeesCmd set of flags for foreign currency transactions and conversion fees report
+// nolint:govet // need to save command arguments order
+type FeesCmd struct {
+	StatementsSourceOpts
+	RatesFile flags.Filename `long:"rates" description:"Reference exchange rates csv/json table with date, currency and rate columns, config file \"rates\" by default"` // nolint
+	Totals    bool           `long:"totals" description:"Export totals per month and currency only"`
+	ExportOpts
+
+	rates *fx.Table
+}
+
+// Execute reports effective and reference rates of foreign currency statements, entry point for "fees" command
+func (cmd *FeesCmd) Execute(_ []string) error {
+	log.Printf("[INFO] \"fees\" command is started")
+
+	if err := cmd.setup(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		defer cancel()
+		cmd.waitSigterm(ctx)
+	}()
+
+	statements, err := cmd.loadStatements(ctx, pipeline.Fields())
+	if err != nil {
+		return errors.Wrap(err, "failed to get statements list")
+	}
+
+	rows, unrated := fx.Fees(statements.Statements, cmd.rates)
+	for _, s := range unrated {
+		cmd.report("WARNING: %s statement %q is skipped, there is no reference rate of %s to %s",
+			s.TranDate.Format(fx.DateLayout), s.Description, s.Amount.Currency, s.CardAmount.Currency)
+	}
+	if cmd.Totals {
+		totals := rows[:0]
+		for _, r := range rows {
+			if r.Level == fx.LevelTotal {
+				totals = append(totals, r)
+			}
+		}
+		rows = totals
+	}
+
+	if err = cmd.export(fx.FeeRows(rows)); err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] \"fees\" command succeeded terminated")
+	return nil
+}
+
+func (cmd *FeesCmd) setup() error {
+	cfg, err := cmd.loadConfig()
+	if err != nil {
+		return err
+	}
+	if cmd.rates, err = loadRates(string(cmd.RatesFile), cfg); err != nil {
+		return errors.Wrapf(err, "invalid rates")
+	}
+	if cmd.rates == nil {
+		return errors.New("reference exchange rates table is required, use --rates or config file")
+	}
+
+	defaults := config.Preset{Format: defaultFeesFormat, Encoding: defaultFeesEncoding}
+	if err := cmd.setupExport(&cmd.CommonOpts, defaults, fx.FeeRow{}, fx.FeeFields()); err != nil {
+		return err
+	}
+
+	return cmd.setupSource(pipeline.Fields())
+}
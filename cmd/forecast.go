@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/config"
+	"github.com/dimboknv/p24-cli/export"
+	"github.com/dimboknv/p24-cli/forecast"
+	"github.com/dimboknv/p24-cli/pipeline"
+	"github.com/dimboknv/p24-cli/recurring"
+	log "github.com/go-pkgz/lgr"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultForecastFormat   = "Date{2006-01-02}|Currency|Change{precision=2}|Balance{precision=2}|Below|Events|,"
+	defaultForecastEncoding = "table"
+)
+
+// ForecastCmd set of flags for balance forecast by recurring statements
+// nolint:govet // need to save command arguments order
+type ForecastCmd struct {
+	StatementsSourceOpts
+	RecurringOpts
+	MerchantOpts
+	Days         int    `long:"days" default:"90" description:"Count of forecast days"`
+	ThresholdStr string `long:"threshold" default:"0" description:"Days with the balance below threshold are flagged"`
+	BalanceStr   string `long:"balance" description:"Current balance, e.g. \"1500.50\" or \"1500.50 UAH\". Available balance by --country or Rest of the latest statement until --at by default"` // nolint
+	Country      string `long:"country" description:"Merchant card country to get available balance from p24 api"`
+	ExportOpts
+
+	threshold p24.Amount
+	balance   *p24.Funds
+}
+
+// Execute projects daily balance, entry point for "forecast" command
+func (cmd *ForecastCmd) Execute(_ []string) error {
+	log.Printf("[INFO] \"forecast\" command is started days=%d", cmd.Days)
+
+	if err := cmd.setup(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		defer cancel()
+		cmd.waitSigterm(ctx)
+	}()
+
+	statements, err := cmd.loadStatements(ctx, pipeline.Fields())
+	if err != nil {
+		return errors.Wrap(err, "failed to get statements list")
+	}
+	if len(statements.Statements) == 0 {
+		return errors.New("there are no statements to forecast by")
+	}
+
+	// the latest statement until --at is the balance date and its Rest is the default balance
+	latest, ok := latestStatement(statements.Statements, cmd.recurringOpts.At)
+	if !ok {
+		return errors.Errorf("there are no statements until %s to take the balance", cmd.recurringOpts.At.Format("02.01.2006"))
+	}
+	opts := forecast.Options{Start: latest.TranDate, Days: cmd.Days, Threshold: cmd.threshold}
+	if !cmd.recurringOpts.At.IsZero() {
+		opts.Start = cmd.recurringOpts.At
+	}
+	if opts.Balance, err = cmd.currentBalance(ctx, latest); err != nil {
+		return errors.Wrap(err, "failed to get balance")
+	}
+	cmd.recurringOpts.At = opts.Start
+
+	days := forecast.Forecast(recurring.Detect(statements.Statements, cmd.recurringOpts), opts)
+	for _, d := range days {
+		if d.Below {
+			cmd.report("balance %.2f %s is below threshold at %s", d.Balance.Float64(), d.Currency, d.Date.Format("2006-01-02"))
+			break
+		}
+	}
+
+	if err = cmd.export(forecast.Rows(days)); err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] \"forecast\" command succeeded terminated")
+	return nil
+}
+
+// currentBalance returns --balance, available card balance from p24 api or the latest statement Rest
+func (cmd *ForecastCmd) currentBalance(ctx context.Context, latest p24.Statement) (p24.Funds, error) {
+	switch {
+	case cmd.balance != nil:
+		b := *cmd.balance
+		if b.Currency == "" {
+			b.Currency = latest.Rest.Currency
+		}
+		return b, nil
+	case cmd.Country != "" && cmd.fetch():
+		balance := &BalanceCmd{CommonP24Opts: cmd.CommonP24Opts, Country: cmd.Country}
+		cardBalance, err := balance.getCardBalanceWithProgressBar(ctx)
+		if err != nil {
+			return p24.Funds{}, err
+		}
+		return p24.Funds{Currency: cardBalance.Card.Currency, Amount: cardBalance.Available}, nil
+	default:
+		return latest.Rest, nil
+	}
+}
+
+// latestStatement returns the latest statement not after at, the latest one if at is zero
+func latestStatement(statements []p24.Statement, at time.Time) (p24.Statement, bool) {
+	var (
+		latest p24.Statement
+		ok     bool
+	)
+	for _, s := range statements {
+		if (at.IsZero() || !s.TranDate.After(at)) && (!ok || s.TranDate.After(latest.TranDate)) {
+			latest, ok = s, true
+		}
+	}
+	return latest, ok
+}
+
+func (cmd *ForecastCmd) setup() error {
+	if cmd.Days <= 0 {
+		return errors.Errorf("invalid count of days %d", cmd.Days)
+	}
+	if err := cmd.threshold.UnmarshalText([]byte(cmd.ThresholdStr)); err != nil {
+		return errors.Wrapf(err, "invalid threshold")
+	}
+	if cmd.BalanceStr != "" {
+		var b p24.Funds
+		err := b.UnmarshalText([]byte(cmd.BalanceStr))
+		if !strings.Contains(cmd.BalanceStr, " ") {
+			err = b.Amount.UnmarshalText([]byte(cmd.BalanceStr))
+		}
+		if err != nil {
+			return errors.Wrapf(err, "invalid balance")
+		}
+		cmd.balance = &b
+	}
+	if err := cmd.setupMerchants(&cmd.CommonOpts); err != nil {
+		return errors.Wrapf(err, "invalid merchant options")
+	}
+	if err := cmd.setupRecurring(cmd.merchants); err != nil {
+		return errors.Wrapf(err, "invalid recurring options")
+	}
+
+	defaults := config.Preset{Format: defaultForecastFormat, Encoding: defaultForecastEncoding}
+	if err := cmd.setupExport(&cmd.CommonOpts, defaults, forecast.Day{}, nil); err != nil {
+		return err
+	}
+	cmd.exportOpts = append(cmd.exportOpts, export.WithChart(forecast.Chart()))
+
+	return cmd.setupSource(pipeline.Fields())
+}
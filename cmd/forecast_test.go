@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_LatestStatement(t *testing.T) {
+	kiev := p24.NewKievLocation()
+	statements := []p24.Statement{
+		{Appcode: "1", TranDate: time.Date(2022, 1, 5, 10, 0, 0, 0, kiev), Rest: p24.Funds{Currency: "UAH", Amount: 100000}},
+		{Appcode: "3", TranDate: time.Date(2022, 3, 5, 10, 0, 0, 0, kiev), Rest: p24.Funds{Currency: "UAH", Amount: 300000}},
+		{Appcode: "2", TranDate: time.Date(2022, 2, 5, 10, 0, 0, 0, kiev), Rest: p24.Funds{Currency: "UAH", Amount: 200000}},
+	}
+	cases := []struct {
+		at      time.Time
+		appcode string
+	}{
+		{appcode: "3"},
+		{at: time.Date(2022, 4, 1, 0, 0, 0, 0, kiev), appcode: "3"},
+		{at: time.Date(2022, 3, 1, 0, 0, 0, 0, kiev), appcode: "2"},
+		{at: time.Date(2022, 2, 5, 10, 0, 0, 0, kiev), appcode: "2"},
+		{at: time.Date(2022, 1, 1, 0, 0, 0, 0, kiev)},
+	}
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			latest, ok := latestStatement(statements, c.at)
+			require.Equal(t, c.appcode != "", ok)
+			require.Equal(t, c.appcode, latest.Appcode)
+		})
+	}
+}
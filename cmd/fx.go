@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/config"
+	"github.com/dimboknv/p24-cli/export"
+	"github.com/dimboknv/p24-cli/fx"
+	"github.com/dimboknv/p24-cli/pipeline"
+	log "github.com/go-pkgz/lgr"
+	"github.com/jessevdk/go-flags"
+	"github.com/pkg/errors"
+)
+
+// ConvertOpts set of flags for statements conversion to the base currency
+type ConvertOpts struct {
+	RatesFile flags.Filename `long:"rates" description:"Exchange rates csv/json table with date, currency and rate columns, config file \"rates\" by default"` // nolint
+	Base      string         `long:"base" description:"Base currency of BaseAmount field and converted totals, config file \"base\" or UAH by default"`
+
+	converter *fx.Converter
+}
+
+// setupConverter loads exchange rates table from flags or config file if it is set
+func (opts *ConvertOpts) setupConverter(common *CommonOpts) error {
+	cfg, err := common.loadConfig()
+	if err != nil {
+		return err
+	}
+
+	table, err := loadRates(string(opts.RatesFile), cfg)
+	if err != nil {
+		return err
+	}
+	if table == nil {
+		if opts.Base != "" {
+			return errors.New("exchange rates table is required for --base, use --rates or config file")
+		}
+		return nil
+	}
+	opts.converter = &fx.Converter{Table: table, Base: strings.ToUpper(firstNonEmpty(opts.Base, cfg.Base, "UAH"))}
+	return nil
+}
+
+// convertFields returns BaseAmount, BaseRate and RateDate virtual fields, see fx.Fields
+func (opts *ConvertOpts) convertFields() export.VirtualFields {
+	return fx.Fields(opts.converter)
+}
+
+// amount returns func of statements amounts used by totals and reports: converted amount or CardAmount
+func (opts *ConvertOpts) amount() func(s *p24.Statement) (p24.Funds, error) {
+	if opts.converter == nil {
+		return nil
+	}
+	return opts.converter.Amount
+}
+
+// totals returns xml total elements per CardAmount currency and in the base currency if rates are set
+func (opts *ConvertOpts) totals(statements []p24.Statement) ([]export.Elem, error) {
+	totals, err := pipeline.Totals(statements, nil)
+	if err != nil {
+		return nil, err
+	}
+	var converted []pipeline.Total
+	if opts.converter != nil {
+		if converted, err = pipeline.Totals(statements, opts.converter.Amount); err != nil {
+			return nil, errors.Wrap(err, "failed to convert totals")
+		}
+	}
+
+	elems := make([]export.Elem, 0, len(totals)+len(converted))
+	for i, list := range [][]pipeline.Total{totals, converted} {
+		for _, t := range list {
+			elem := export.Elem{Name: "total", Attrs: []export.Attr{
+				{Name: "currency", Value: t.Currency},
+				{Name: "credit", Value: t.Credit},
+				{Name: "debet", Value: t.Debet},
+			}}
+			if i == 1 {
+				elem.Attrs = append(elem.Attrs, export.Attr{Name: "converted", Value: "true"})
+			}
+			elems = append(elems, elem)
+		}
+	}
+	return elems, nil
+}
+
+// loadRates loads exchange rates table from filename or config file "rates". Returns nil if neither is set
+func loadRates(filename string, cfg config.Config) (*fx.Table, error) {
+	if filename = firstNonEmpty(filename, cfg.Rates); filename == "" {
+		return nil, nil
+	}
+	log.Printf("[DEBUG] loading exchange rates from %q", filename)
+	return fx.LoadTable(filename)
+}
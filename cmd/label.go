@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/dimboknv/p24-cli/category"
+	"github.com/dimboknv/p24-cli/importer"
+	"github.com/dimboknv/p24-cli/pipeline"
+	log "github.com/go-pkgz/lgr"
+	"github.com/jessevdk/go-flags"
+	"github.com/pkg/errors"
+)
+
+// LabelCmd set of flags for importing manually assigned categories to the local store
+// nolint:govet // need to save command arguments order
+type LabelCmd struct {
+	Inputs []flags.Filename `long:"in" required:"true" description:"Categorised xlsx/csv/xml export with Category column. Can be repeated"`
+	All    bool             `long:"all" description:"Store categories of all statements, including categories of rules and classifier suggestions"` // nolint
+	CategoryOpts
+	MerchantOpts
+	CommonOpts
+}
+
+// Execute imports categories of statements to the store, entry point for "label" command.
+// Statements are identified by Fingerprint column or by fingerprint of exported statement fields.
+// Categories equal to categories of rules and classifier suggestions aren't stored unless --all is set,
+// so such statements follow later rules changes
+func (cmd *LabelCmd) Execute(_ []string) error {
+	log.Printf("[INFO] \"label\" command is started in=%v", cmd.Inputs)
+
+	cfg, err := cmd.loadConfig()
+	if err != nil {
+		return err
+	}
+	store, err := cmd.openStore(cfg)
+	if err != nil {
+		return err
+	}
+	if err = cmd.setupMerchants(&cmd.CommonOpts); err != nil {
+		return errors.Wrapf(err, "invalid merchant options")
+	}
+	if err = cmd.setupCategorizer(&cmd.CommonOpts, cmd.merchants); err != nil {
+		return errors.Wrapf(err, "invalid categorisation options")
+	}
+
+	changed, automatic := 0, 0
+	for _, in := range cmd.Inputs {
+		records, err := importer.ReadRecords(string(in))
+		if err != nil {
+			return err
+		}
+		for i, values := range records {
+			if _, ok := values["category"]; !ok {
+				return errors.Errorf("%q has no Category column", in)
+			}
+			s, err := importer.MakeStatement(values)
+			if err != nil {
+				return errors.Wrapf(err, "%q record #%d", in, i+1)
+			}
+			fingerprint := values["fingerprint"]
+			if fingerprint == "" {
+				fingerprint = pipeline.Fingerprint(&s)
+			}
+
+			l := category.Label{Category: strings.TrimSpace(values["category"]), SubCategory: strings.TrimSpace(values["subcategory"])}
+			var auto category.Label
+			if cmd.categorizer != nil && !cmd.All {
+				if auto, err = cmd.categorizer.Automatic(&s); err != nil {
+					return errors.Wrapf(err, "%q record #%d", in, i+1)
+				}
+			}
+			if l.Category != "" && l == auto {
+				automatic++
+			}
+			if store.Assign(fingerprint, l, auto) {
+				changed++
+			}
+		}
+	}
+
+	if err := store.Save(); err != nil {
+		return err
+	}
+	cmd.report("%d categories are changed, %d categories of rules and suggestions are skipped, %d statements are categorised manually",
+		changed, automatic, len(store.Labels))
+	log.Printf("[INFO] \"label\" command succeeded terminated")
+	return nil
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"github.com/dimboknv/p24-cli/export"
+	"github.com/dimboknv/p24-cli/merchant"
+	log "github.com/go-pkgz/lgr"
+	"github.com/jessevdk/go-flags"
+)
+
+// MerchantOpts set of flags for merchant names normalisation
+type MerchantOpts struct {
+	MerchantsFile flags.Filename `long:"merchants" description:"Merchant aliases yaml file, config file \"merchants\" by default"`
+
+	merchants *merchant.Normalizer
+}
+
+// setupMerchants makes merchant normalizer by builtin heuristics and aliases file from flags or config file if it is set
+func (opts *MerchantOpts) setupMerchants(common *CommonOpts) error {
+	cfg, err := common.loadConfig()
+	if err != nil {
+		return err
+	}
+
+	var aliases *merchant.Aliases
+	if filename := firstNonEmpty(string(opts.MerchantsFile), cfg.Merchants); filename != "" {
+		log.Printf("[DEBUG] loading merchant aliases from %q", filename)
+		if aliases, err = merchant.LoadAliases(filename); err != nil {
+			return err
+		}
+	}
+	opts.merchants, err = merchant.NewNormalizer(aliases)
+	return err
+}
+
+// merchantFields returns Merchant virtual field, see merchant.Fields
+func (opts *MerchantOpts) merchantFields() export.VirtualFields {
+	return merchant.Fields(opts.merchants)
+}
@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/config"
+	"github.com/dimboknv/p24-cli/fx"
+	p24http "github.com/dimboknv/p24-cli/http"
+	"github.com/dimboknv/p24-cli/pb"
+	log "github.com/go-pkgz/lgr"
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/jessevdk/go-flags"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultRatesFormat   = "Date{2006-01-02}|Currency|Base|Rate|Buy|Sale|,"
+	defaultRatesEncoding = "table"
+)
+
+// RatesCmd set of flags for fetching NBU and PrivatBank cash exchange rates to a local archive
+// nolint:govet // need to save command arguments order
+type RatesCmd struct {
+	StartDateStr string         `long:"sd" description:"Start date of rates date range with \"dd.mm.yyyy\" layout, today by default"`
+	EndDateStr   string         `long:"ed" description:"End date of rates date range with \"dd.mm.yyyy\" layout, start date by default"`
+	Currencies   string         `long:"currency" description:"Comma separated currencies to export, all by default"`
+	ArchiveFile  flags.Filename `long:"archive" description:"Rates archive csv/json file, config file \"rates\" or \"$XDG_CONFIG_HOME/p24/rates.json\" by default"` // nolint
+	Offline      bool           `long:"offline" description:"Export archived rates without fetching missing dates"`
+	HTTPTimeout  time.Duration  `long:"timeout" default:"90s" description:"http request timeout"`
+	ExportOpts
+	CommonOpts
+
+	startDate  time.Time
+	endDate    time.Time
+	currencies map[string]bool
+	archive    string
+}
+
+// Execute fetches missing rates of date range to the archive and exports them, entry point for "rates" command
+func (cmd *RatesCmd) Execute(_ []string) error {
+	log.Printf("[INFO] \"rates\" command is started sd=%s ed=%s", cmd.StartDateStr, cmd.EndDateStr)
+
+	if err := cmd.setup(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		defer cancel()
+		cmd.waitSigterm(ctx)
+	}()
+
+	table, err := fx.OpenArchive(cmd.archive)
+	if err != nil {
+		return errors.Wrap(err, "failed to open rates archive")
+	}
+
+	if !cmd.Offline {
+		fetched, err := cmd.fetchWithProgressBar(ctx, table)
+		if err != nil {
+			return errors.Wrap(err, "failed to fetch rates")
+		}
+		if fetched > 0 {
+			if err := table.Save(cmd.archive); err != nil {
+				return err
+			}
+			cmd.report("fetched rates of %d days to %s", fetched, cmd.archive)
+		}
+	}
+
+	if err := cmd.export(fx.Rows(cmd.filter(table.Rates()))); err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] \"rates\" command succeeded terminated")
+	return nil
+}
+
+func (cmd *RatesCmd) setup() (err error) {
+	today := time.Now().In(p24.NewKievLocation())
+	cmd.startDate = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	if cmd.StartDateStr != "" {
+		if cmd.startDate, err = time.Parse(inputTimeLayout, cmd.StartDateStr); err != nil {
+			return errors.Wrapf(err, "invalid start date")
+		}
+	}
+	cmd.endDate = cmd.startDate
+	if cmd.EndDateStr != "" {
+		if cmd.endDate, err = time.Parse(inputTimeLayout, cmd.EndDateStr); err != nil {
+			return errors.Wrapf(err, "invalid end date")
+		}
+	}
+	if cmd.endDate.Before(cmd.startDate) {
+		return errors.New("end date is before start date")
+	}
+
+	cmd.currencies = map[string]bool{}
+	for _, c := range strings.Split(cmd.Currencies, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			cmd.currencies[strings.ToUpper(c)] = true
+		}
+	}
+
+	cfg, err := cmd.loadConfig()
+	if err != nil {
+		return err
+	}
+	if cmd.archive = firstNonEmpty(string(cmd.ArchiveFile), cfg.Rates, fx.DefaultArchivePath()); cmd.archive == "" {
+		return errors.New("rates archive file is required, use --archive or config file")
+	}
+
+	defaults := config.Preset{Format: defaultRatesFormat, Encoding: defaultRatesEncoding}
+	return cmd.setupExport(&cmd.CommonOpts, defaults, fx.Rate{}, nil)
+}
+
+// fetchWithProgressBar fetches rates of date range days missing in table and returns the number of fetched days.
+// Past days without rates, e.g. weekends and holidays, are added as empty dates, so they aren't fetched again.
+// Today rates may be not published yet, so today is fetched until it has rates
+func (cmd *RatesCmd) fetchWithProgressBar(ctx context.Context, table *fx.Table) (int, error) {
+	var dates []time.Time
+	for d := cmd.startDate; !d.After(cmd.endDate); d = d.AddDate(0, 0, 1) {
+		if !table.HasDate(d) {
+			dates = append(dates, d)
+		}
+	}
+	if len(dates) == 0 {
+		return 0, nil
+	}
+
+	retryHTTP := retryablehttp.NewClient()
+	retryHTTP.HTTPClient.Timeout = cmd.HTTPTimeout
+	client := &fx.PrivatClient{HTTP: p24http.NewClient(
+		p24http.WithRateLimiter(p24.NewRateLimiter()),
+		p24http.WithRetryableHTTP(retryHTTP),
+		p24http.WithLogger(log.Default()),
+	)}
+
+	var w io.Writer = os.Stderr
+	if cmd.Debug {
+		w = nil
+	}
+	prg := pb.NewProgress(w)
+	title := fmt.Sprintf("rates: %s - %s", cmd.startDate.Format(inputTimeLayout), cmd.endDate.Format(inputTimeLayout))
+	bar := pb.NewSpinBar(title)
+	prg.AddBar(bar)
+
+	now := time.Now().In(p24.NewKievLocation())
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	fetched := 0
+	for _, d := range dates {
+		log.Printf("[DEBUG] getting rates at %s", d.Format(inputTimeLayout))
+		rates, err := client.Fetch(ctx, d)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				bar.Cancel()
+			} else {
+				bar.StopWithErrMsg(errors.Cause(err).Error())
+			}
+			prg.Wait()
+			// keep rates fetched before the failure
+			if fetched > 0 {
+				if saveErr := table.Save(cmd.archive); saveErr != nil {
+					log.Printf("[WARN] failed to save rates archive: %v", saveErr)
+				}
+			}
+			return fetched, errors.Wrapf(err, "rates at %s", d.Format(inputTimeLayout))
+		}
+		table.Add(rates...)
+		switch {
+		case len(rates) > 0:
+			fetched++
+		case d.Before(today):
+			log.Printf("[DEBUG] there are no rates at %s", d.Format(inputTimeLayout))
+			table.AddEmptyDate(d)
+			fetched++
+		}
+	}
+	bar.Stop()
+	prg.Wait()
+	return fetched, nil
+}
+
+// filter returns rates of the date range and currencies
+func (cmd *RatesCmd) filter(rates []fx.Rate) []fx.Rate {
+	res := rates[:0]
+	for _, r := range rates {
+		if r.Date.Before(cmd.startDate) || r.Date.After(cmd.endDate) {
+			continue
+		}
+		if len(cmd.currencies) > 0 && !cmd.currencies[r.Currency] {
+			continue
+		}
+		res = append(res, r)
+	}
+	return res
+}
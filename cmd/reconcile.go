@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"context"
+	"math"
+	"regexp"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/config"
+	"github.com/dimboknv/p24-cli/pipeline"
+	"github.com/dimboknv/p24-cli/reconcile"
+	log "github.com/go-pkgz/lgr"
+	"github.com/jessevdk/go-flags"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultReconcileFormat   = "Status|MatchedBy|ID|Reference|Date{2006-01-02}|Expected{precision=2}|TranDate{2006-01-02 15:04:05}|Paid{precision=2}|Difference{precision=2}|Count|Card|Description|," // nolint
+	defaultReconcileEncoding = "table"
+)
+
+// ReconcileCmd set of flags for reconciliation of incoming statements against expected payments
+// nolint:govet // need to save command arguments order
+type ReconcileCmd struct {
+	StatementsSourceOpts
+	Expected  flags.Filename `long:"expected" required:"true" description:"Expected payments csv file with id, date, amount and optional currency, reference, description columns"` // nolint
+	Tolerance float64        `long:"tolerance" description:"Allowed absolute difference of paid and expected amounts"`
+	Window    int            `long:"window" default:"30" description:"Allowed difference of expected and statement dates in days"`
+	Reference string         `long:"reference" description:"Regexp extracting an expected payment reference from Description by the first group, e.g. '(?i)invoice\\s*#?(\\d+)'"` // nolint
+	ExportOpts
+
+	expected      []reconcile.Expected
+	reconcileOpts reconcile.Options
+}
+
+// Execute matches incoming statements to expected payments, entry point for "reconcile" command
+func (cmd *ReconcileCmd) Execute(_ []string) error {
+	log.Printf("[INFO] \"reconcile\" command is started")
+
+	if err := cmd.setup(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		defer cancel()
+		cmd.waitSigterm(ctx)
+	}()
+
+	statements, err := cmd.loadStatements(ctx, pipeline.Fields())
+	if err != nil {
+		return errors.Wrap(err, "failed to get statements list")
+	}
+
+	items := reconcile.Reconcile(cmd.expected, statements.Statements, cmd.reconcileOpts)
+	counts := map[string]int{}
+	for _, item := range items {
+		counts[item.Status]++
+	}
+	cmd.report("%d matched, %d partially paid, %d unpaid expected payments, %d unexpected statements",
+		counts[reconcile.StatusMatched], counts[reconcile.StatusPartial], counts[reconcile.StatusUnpaid], counts[reconcile.StatusUnexpected])
+
+	if err = cmd.export(reconcile.Rows(items)); err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] \"reconcile\" command succeeded terminated")
+	return nil
+}
+
+func (cmd *ReconcileCmd) setup() (err error) {
+	if cmd.Tolerance < 0 {
+		return errors.Errorf("tolerance %v is negative", cmd.Tolerance)
+	}
+	if cmd.Window < 0 {
+		return errors.Errorf("window %d is negative", cmd.Window)
+	}
+	cmd.reconcileOpts = reconcile.Options{
+		Tolerance: p24.Amount(math.Round(cmd.Tolerance * float64(p24.DecimalPrecision))),
+		Window:    cmd.Window,
+	}
+	if cmd.Reference != "" {
+		if cmd.reconcileOpts.Reference, err = regexp.Compile(cmd.Reference); err != nil {
+			return errors.Wrapf(err, "invalid reference regexp")
+		}
+	}
+	if cmd.expected, err = reconcile.LoadExpected(string(cmd.Expected)); err != nil {
+		return err
+	}
+
+	defaults := config.Preset{Format: defaultReconcileFormat, Encoding: defaultReconcileEncoding}
+	if err := cmd.setupExport(&cmd.CommonOpts, defaults, reconcile.Item{}, reconcile.Fields()); err != nil {
+		return err
+	}
+
+	return cmd.setupSource(pipeline.Fields())
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/config"
+	"github.com/dimboknv/p24-cli/merchant"
+	"github.com/dimboknv/p24-cli/pipeline"
+	"github.com/dimboknv/p24-cli/recurring"
+	log "github.com/go-pkgz/lgr"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultRecurringFormat   = "Name|Terminal|Period|Count|First{2006-01-02}|Last{2006-01-02}|Next{2006-01-02}|Average{precision=2}|Latest{precision=2}|PriceChanges|Changes|Missing|," // nolint
+	defaultRecurringEncoding = "table"
+)
+
+// RecurringOpts set of flags for recurring payments detection
+type RecurringOpts struct {
+	AmountTolerance float64 `long:"amount-tolerance" default:"0.25" description:"Relative difference of amounts of a series"`
+	MinCount        int     `long:"min-count" default:"3" description:"Minimal count of weekly and monthly series statements"`
+	AtStr           string  `long:"at" description:"Date of missing charges check with \"dd.mm.yyyy\" layout, the latest statement date by default"`
+	ByMerchant      bool    `long:"by-merchant" description:"Cluster statements by Merchant instead of Terminal and Description"`
+
+	recurringOpts recurring.Options
+}
+
+// setupRecurring makes recurring detection options, merchants are used by --by-merchant
+func (opts *RecurringOpts) setupRecurring(merchants *merchant.Normalizer) (err error) {
+	opts.recurringOpts = recurring.Options{AmountTolerance: opts.AmountTolerance, MinCount: opts.MinCount}
+	if opts.ByMerchant {
+		opts.recurringOpts.Merchant = merchants.Name
+	}
+	if opts.AtStr != "" {
+		if opts.recurringOpts.At, err = time.ParseInLocation(inputTimeLayout, opts.AtStr, p24.NewKievLocation()); err != nil {
+			return errors.Wrapf(err, "invalid --at date")
+		}
+	}
+	return opts.recurringOpts.Check()
+}
+
+// RecurringCmd set of flags for recurring payments and subscriptions report
+// nolint:govet // need to save command arguments order
+type RecurringCmd struct {
+	StatementsSourceOpts
+	RecurringOpts
+	MerchantOpts
+	ExportOpts
+}
+
+// Execute detects recurring statements series, entry point for "recurring" command
+func (cmd *RecurringCmd) Execute(_ []string) error {
+	log.Printf("[INFO] \"recurring\" command is started")
+
+	if err := cmd.setup(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		defer cancel()
+		cmd.waitSigterm(ctx)
+	}()
+
+	statements, err := cmd.loadStatements(ctx, pipeline.Fields())
+	if err != nil {
+		return errors.Wrap(err, "failed to get statements list")
+	}
+
+	series := recurring.Detect(statements.Statements, cmd.recurringOpts)
+	missing := 0
+	for _, s := range series {
+		if s.Missing {
+			missing++
+		}
+	}
+	cmd.report("detected %d recurring series, %d with missing charges", len(series), missing)
+
+	if err = cmd.export(recurring.Rows(series)); err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] \"recurring\" command succeeded terminated")
+	return nil
+}
+
+func (cmd *RecurringCmd) setup() error {
+	if err := cmd.setupMerchants(&cmd.CommonOpts); err != nil {
+		return errors.Wrapf(err, "invalid merchant options")
+	}
+	if err := cmd.setupRecurring(cmd.merchants); err != nil {
+		return errors.Wrapf(err, "invalid recurring options")
+	}
+
+	defaults := config.Preset{Format: defaultRecurringFormat, Encoding: defaultRecurringEncoding}
+	if err := cmd.setupExport(&cmd.CommonOpts, defaults, recurring.Series{}, nil); err != nil {
+		return err
+	}
+
+	return cmd.setupSource(pipeline.Fields())
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"github.com/dimboknv/p24-cli/export"
+	"github.com/dimboknv/p24-cli/refund"
+	"github.com/pkg/errors"
+)
+
+// RefundOpts set of flags for pairing refunds with purchases
+type RefundOpts struct {
+	RefundWindow int `long:"refund-window" default:"90" description:"Maximal count of days between a purchase and its refund"`
+
+	pairs *refund.Pairs
+}
+
+// setupRefunds makes empty refunds pairs, they are detected by source analyzer after loading statements
+func (opts *RefundOpts) setupRefunds(source *StatementsSourceOpts) error {
+	if opts.RefundWindow < 0 {
+		return errors.Errorf("refund window %d is negative", opts.RefundWindow)
+	}
+	opts.pairs = refund.NewPairs(refund.Options{Window: opts.RefundWindow})
+	source.analyzers = append(source.analyzers, opts.pairs.Detect)
+	return nil
+}
+
+// refundFields returns PairID and IsRefund virtual fields, see refund.Fields
+func (opts *RefundOpts) refundFields() export.VirtualFields {
+	return refund.Fields(opts.pairs)
+}
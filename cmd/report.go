@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/config"
+	"github.com/dimboknv/p24-cli/export"
+	"github.com/dimboknv/p24-cli/pipeline"
+	"github.com/dimboknv/p24-cli/refund"
+	"github.com/dimboknv/p24-cli/report"
+	log "github.com/go-pkgz/lgr"
+	"github.com/pkg/errors"
+)
+
+const defaultReportEncoding = "table"
+
+// ReportCmd set of flags for statements aggregation report
+// nolint:govet // need to save command arguments order
+type ReportCmd struct {
+	StatementsSourceOpts
+	CategoryOpts
+	MerchantOpts
+	ConvertOpts
+	RefundOpts
+	TransferOpts
+	CounterpartyOpts
+	By           string `long:"by" default:"month" description:"Comma separated group keys: year, month, week, day, weekday, category, terminal, currency, merchant or Name=expression"` // nolint
+	NetOfRefunds bool   `long:"net-of-refunds" description:"Drop refunds paired with purchases and reduce purchases amounts by them"`
+	ExportOpts
+
+	keys []report.Key
+}
+
+// Execute aggregates statements list, entry point for "report" command
+func (cmd *ReportCmd) Execute(_ []string) error {
+	log.Printf("[INFO] \"report\" command is started by=%s", cmd.By)
+
+	if err := cmd.setup(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		defer cancel()
+		cmd.waitSigterm(ctx)
+	}()
+
+	statements, err := cmd.loadStatements(ctx, cmd.fields())
+	if err != nil {
+		return errors.Wrap(err, "failed to get statements list")
+	}
+
+	rows, err := cmd.aggregate(statements.Statements)
+	if err != nil {
+		return err
+	}
+
+	if err = cmd.export(report.Rows(rows)); err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] \"report\" command succeeded terminated")
+	return nil
+}
+
+func (cmd *ReportCmd) setup() (err error) {
+	if err := cmd.setupMerchants(&cmd.CommonOpts); err != nil {
+		return errors.Wrapf(err, "invalid merchant options")
+	}
+	if err := cmd.setupCategorizer(&cmd.CommonOpts, cmd.merchants); err != nil {
+		return errors.Wrapf(err, "invalid categorisation options")
+	}
+	if err := cmd.setupConverter(&cmd.CommonOpts); err != nil {
+		return errors.Wrapf(err, "invalid conversion options")
+	}
+	if err := cmd.setupRefunds(&cmd.StatementsSourceOpts); err != nil {
+		return errors.Wrapf(err, "invalid refund options")
+	}
+	if err := cmd.setupTransfers(&cmd.StatementsSourceOpts); err != nil {
+		return errors.Wrapf(err, "invalid transfer options")
+	}
+	if err := cmd.setupParser(&cmd.CommonOpts); err != nil {
+		return errors.Wrapf(err, "invalid description parsing options")
+	}
+
+	cmd.keys, err = report.ParseKeys(cmd.By, cmd.fields())
+	if err != nil {
+		return err
+	}
+
+	defaults := config.Preset{Format: report.DefaultFormat(cmd.keys), Encoding: defaultReportEncoding}
+	if err := cmd.setupExport(&cmd.CommonOpts, defaults, report.Row{}, report.Fields(cmd.keys)); err != nil {
+		return err
+	}
+
+	return cmd.setupSource(cmd.fields())
+}
+
+// fields returns virtual fields of statements available for group keys and filters
+func (cmd *ReportCmd) fields() export.VirtualFields {
+	fields := pipeline.Fields()
+	for name, f := range cmd.categoryFields() {
+		fields[name] = f
+	}
+	for name, f := range cmd.merchantFields() {
+		fields[name] = f
+	}
+	for name, f := range cmd.convertFields() {
+		fields[name] = f
+	}
+	for name, f := range cmd.refundFields() {
+		fields[name] = f
+	}
+	for name, f := range cmd.transferFields() {
+		fields[name] = f
+	}
+	for name, f := range cmd.counterpartyFields() {
+		fields[name] = f
+	}
+	return fields
+}
+
+// aggregate returns report rows of statements. Net of refunds, group keys are evaluated by original statements
+// and only aggregated amounts are reduced by refunds
+func (cmd *ReportCmd) aggregate(statements []p24.Statement) ([]*report.Row, error) {
+	amount := cmd.amount()
+	if cmd.NetOfRefunds {
+		list := refund.Net(statements, cmd.pairs)
+		log.Printf("[DEBUG] %d of %d statements are left net of refunds", len(list), len(statements))
+		convert := amount
+		amount = func(s *p24.Statement) (p24.Funds, error) {
+			netted := cmd.pairs.Netted(s)
+			if convert == nil {
+				return netted.CardAmount, nil
+			}
+			return convert(&netted)
+		}
+		statements = list
+	}
+
+	rows, err := report.Aggregate(statements, cmd.keys, cmd.fields(), amount)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to aggregate statements")
+	}
+	return rows, nil
+}
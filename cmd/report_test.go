@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/category"
+	"github.com/dimboknv/p24-cli/pipeline"
+	"github.com/dimboknv/p24-cli/refund"
+	"github.com/dimboknv/p24-cli/report"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ReportCmd_NetOfRefunds(t *testing.T) {
+	kiev := p24.NewKievLocation()
+	statements := []p24.Statement{
+		{
+			Appcode: "1", TranDate: time.Date(2022, 1, 1, 10, 0, 0, 0, kiev), Terminal: "GIFT SHOP", Description: "Gift shop",
+			Amount: p24.Funds{Currency: "UAH", Amount: -100000}, CardAmount: p24.Funds{Currency: "UAH", Amount: -100000},
+		},
+		{
+			Appcode: "2", TranDate: time.Date(2022, 1, 3, 10, 0, 0, 0, kiev), Terminal: "GIFT SHOP", Description: "Refund",
+			Amount: p24.Funds{Currency: "UAH", Amount: 30000}, CardAmount: p24.Funds{Currency: "UAH", Amount: 30000},
+		},
+		{
+			Appcode: "3", TranDate: time.Date(2022, 1, 5, 10, 0, 0, 0, kiev), Terminal: "CAFE", Description: "Cafe",
+			Amount: p24.Funds{Currency: "UAH", Amount: -50000}, CardAmount: p24.Funds{Currency: "UAH", Amount: -50000},
+		},
+	}
+	store, err := category.OpenStore(filepath.Join(t.TempDir(), "categories.json"))
+	require.NoError(t, err)
+	store.Set(pipeline.Fingerprint(&statements[0]), category.Label{Category: "gifts"})
+
+	cmd := &ReportCmd{NetOfRefunds: true}
+	cmd.categorizer = &category.Categorizer{Store: store}
+	cmd.pairs = refund.NewPairs(refund.Options{})
+	cmd.pairs.Detect(statements)
+	cmd.keys, err = report.ParseKeys(`category,Paired=PairID != ""`, cmd.fields())
+	require.NoError(t, err)
+
+	rows, err := cmd.aggregate(statements)
+	require.NoError(t, err)
+	type group struct {
+		category, paired string
+		outflow          p24.Amount
+	}
+	var groups []group
+	for _, r := range rows {
+		if r.Level == report.LevelGroup {
+			groups = append(groups, group{category: r.Key(0), paired: r.Key(1), outflow: r.Outflow})
+		}
+	}
+	// the labelled purchase keeps its category and pair, its amount is reduced by the refund
+	require.Equal(t, []group{{paired: "false", outflow: 50000}, {category: "gifts", paired: "true", outflow: 70000}}, groups)
+	require.Equal(t, p24.Amount(-100000), statements[0].CardAmount.Amount)
+}
@@ -0,0 +1,249 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/url"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/export"
+	"github.com/dimboknv/p24-cli/expr"
+	"github.com/dimboknv/p24-cli/importer"
+	"github.com/dimboknv/p24-cli/pb"
+	"github.com/dimboknv/p24-cli/pipeline"
+	log "github.com/go-pkgz/lgr"
+	"github.com/jessevdk/go-flags"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+)
+
+// StatementsSourceOpts set of flags for loading statements list from p24 api and/or previously exported files
+// nolint:govet // need to save command arguments order
+type StatementsSourceOpts struct {
+	CommonP24Opts
+	StartDateStr string           `long:"sd" description:"Start date of statements date range with \"dd.mm.yyyy\" layout"`
+	EndDateStr   string           `long:"ed" description:"End date of statements date range with \"dd.mm.yyyy\" layout"`
+	Inputs       []flags.Filename `long:"in" description:"Load statements from previously exported xml/csv file. Can be repeated. p24 api is used too if --id is set"` // nolint
+	DedupBy      string           `long:"dedup-by" description:"Comma separated fields identifying a statement for deduplication. Fingerprint by default"`             // nolint
+	NoDedup      bool             `long:"no-dedup" description:"Keep duplicated statements"`
+	Where        string           `short:"w" long:"where" description:"Filter expression, e.g. 'CardAmount.Amount < 0 && Description ~ \"(?i)uber\"'"`
+	NoVerify     bool             `long:"no-verify" description:"Skip integrity verification of fetched statements against response totals"`
+	Strict       bool             `long:"strict" description:"Fail if fetched statements have integrity problems instead of warnings"`
+
+	startDate time.Time
+	endDate   time.Time
+	dedupKeys []*expr.Expr
+	where     *expr.Expr
+	analyzers []func(statements []p24.Statement) // run on deduplicated statements before filtering, e.g. refunds pairing
+}
+
+// fetch returns true if statements should be loaded from p24 api
+func (opts *StatementsSourceOpts) fetch() bool {
+	return len(opts.Inputs) == 0 || opts.ID != ""
+}
+
+func (opts *StatementsSourceOpts) setupSource(fields export.VirtualFields) (err error) {
+	if opts.fetch() {
+		if err := opts.checkCredentials(); err != nil {
+			return err
+		}
+
+		opts.startDate, err = time.Parse(inputTimeLayout, opts.StartDateStr)
+		if err != nil {
+			return errors.Wrapf(err, "invalid start date")
+		}
+
+		opts.endDate, err = time.Parse(inputTimeLayout, opts.EndDateStr)
+		if err != nil {
+			return errors.Wrapf(err, "invalid end date")
+		}
+	}
+
+	opts.dedupKeys, err = pipeline.ParseDedupKeys(opts.DedupBy, fields)
+	if err != nil {
+		return errors.Wrapf(err, "invalid dedup keys")
+	}
+
+	opts.where, err = pipeline.ParseWhere(opts.Where, fields)
+	if err != nil {
+		return errors.Wrapf(err, "invalid where expression")
+	}
+	return nil
+}
+
+// loadStatements loads statements from p24 api and input files, merges, deduplicates, analyzes and filters them
+func (opts *StatementsSourceOpts) loadStatements(ctx context.Context, fields export.VirtualFields) (p24.Statements, error) {
+	var sources []p24.Statements
+	if opts.fetch() {
+		statements, err := opts.getStatementsWithProgressBar(ctx)
+		if err != nil {
+			return p24.Statements{}, err
+		}
+		sources = append(sources, statements)
+	}
+
+	for _, in := range opts.Inputs {
+		log.Printf("[DEBUG] reading statements from %q", in)
+		statements, err := importer.ReadFile(string(in))
+		if err != nil {
+			return p24.Statements{}, err
+		}
+		sources = append(sources, statements)
+	}
+	res := mergeStatements(sources...)
+
+	// imported csv files have no totals, duplicates shouldn't be counted twice
+	// and totals of several sources can be in different currencies
+	recount := len(opts.Inputs) > 0 || len(sources) > 1
+	if !opts.NoDedup {
+		statements, dropped, err := pipeline.Dedup(res.Statements, opts.dedupKeys, fields)
+		if err != nil {
+			return p24.Statements{}, errors.Wrap(err, "failed to deduplicate statements")
+		}
+		if dropped > 0 {
+			opts.report("dropped %d duplicated statements", dropped)
+			recount = true
+		}
+		res.Statements = statements
+	}
+
+	for _, analyze := range opts.analyzers {
+		analyze(res.Statements)
+	}
+
+	if opts.where != nil {
+		statements, err := pipeline.Filter(res.Statements, opts.where, fields)
+		if err != nil {
+			return p24.Statements{}, errors.Wrap(err, "failed to filter statements")
+		}
+		log.Printf("[DEBUG] %d of %d statements matched %q", len(statements), len(res.Statements), opts.where)
+		res.Statements, recount = statements, true
+	}
+
+	// totals should reflect loaded statements only
+	if recount {
+		pipeline.Recount(&res)
+	}
+	return res, nil
+}
+
+func (opts *StatementsSourceOpts) getStatementsWithProgressBar(ctx context.Context) (p24.Statements, error) {
+	eg, egCtx := errgroup.WithContext(ctx)
+	statementsOpts := SplitStatementsDateRange(opts.startDate, opts.endDate, opts.Card)
+	client, prg, chunks := opts.makeP24Client(), opts.makeProgressBar(), make([]p24.Statements, len(statementsOpts))
+
+	for i, sOpts := range statementsOpts {
+		i, sOpts := i, sOpts
+		title := fmt.Sprintf("load: %s - %s", sOpts.StartDate.Format(inputTimeLayout), sOpts.EndDate.Format(inputTimeLayout))
+		bar := pb.NewSpinBar(title)
+		prg.AddBar(bar)
+
+		eg.Go(func() error {
+			log.Printf("[DEBUG] getting statements for %+v ...", sOpts)
+			statements, err := client.GetStatements(egCtx, sOpts)
+
+			// check context cancellation error and cancel bar if needed
+			if errors.Is(err, context.Canceled) {
+				log.Printf("[DEBUG] getting statements was canceled for %+v", sOpts)
+				bar.Cancel()
+				return err
+			}
+
+			// check http request timeout error and stop bar with timeout error if needed
+			if urlErr := (&url.Error{}); errors.As(err, &urlErr) && urlErr.Timeout() {
+				log.Printf("[DEBUG] getting statements was timeout for %+v", sOpts)
+				bar.StopWithErrMsg("timeout")
+				return err
+			}
+
+			if err != nil {
+				bar.StopWithErrMsg(errors.Cause(err).Error())
+				if p24Err := (&p24.Error{}); errors.As(err, &p24Err) {
+					log.Printf("[DEBUG] getting statements failed for %+v: req: %s, resp: %s", sOpts, p24Err.Req, p24Err.Resp)
+				} else {
+					log.Printf("[DEBUG] getting statements failed for %+v: ", sOpts)
+				}
+				return err
+			}
+
+			log.Printf("[DEBUG] getting statements was succeeded for %+v", sOpts)
+			bar.Stop()
+
+			// each goroutine owns its chunk, so chunks are merged in date ranges order
+			chunks[i] = statements
+			return nil
+		})
+	}
+
+	prg.Wait()
+	if err := eg.Wait(); err != nil {
+		return p24.Statements{}, err
+	}
+	if err := opts.verify(statementsOpts, chunks); err != nil {
+		return p24.Statements{}, err
+	}
+	return mergeStatements(chunks...), nil
+}
+
+// verify reports integrity findings of fetched chunks as warnings, see pipeline.Verify.
+// Returns an error if there are findings in strict mode
+func (opts *StatementsSourceOpts) verify(statementsOpts []p24.StatementsOpts, chunks []p24.Statements) error {
+	if opts.NoVerify {
+		return nil
+	}
+	count := 0
+	for i, sOpts := range statementsOpts {
+		for _, f := range pipeline.Verify(chunks[i], sOpts.StartDate, sOpts.EndDate) {
+			opts.report("warning: %s - %s statements %s",
+				sOpts.StartDate.Format(inputTimeLayout), sOpts.EndDate.Format(inputTimeLayout), f)
+			count++
+		}
+	}
+	for _, f := range pipeline.VerifyChunks(chunks) {
+		opts.report("warning: statements %s", f)
+		count++
+	}
+	if count > 0 && opts.Strict {
+		return errors.Errorf("fetched statements have %d integrity problems", count)
+	}
+	return nil
+}
+
+// SplitStatementsDateRange splits given date range into 90 intervals
+// and make StatementsOpts for each interval. Returns slice of StatementsOpts
+func SplitStatementsDateRange(startDate, endDate time.Time, card string) []p24.StatementsOpts {
+	days90 := 90 * 24 * time.Hour
+	n := 1
+	if dateRange := endDate.Sub(startDate); dateRange > days90 {
+		n = int(math.Ceil(float64(dateRange) / float64(days90)))
+	}
+	sd, ed, opts := startDate, startDate.Add(days90), make([]p24.StatementsOpts, n)
+
+	for i := 0; i < n; i++ {
+		if ed.After(endDate) {
+			ed = endDate
+		}
+		opts[i] = p24.StatementsOpts{
+			StartDate:  sd,
+			EndDate:    ed,
+			CardNumber: card,
+		}
+		sd = sd.Add(days90 + 24*time.Hour)
+		ed = ed.Add(days90 + 24*time.Hour)
+	}
+	return opts
+}
+
+// mergeStatements merges statements lists. Credit and Debet are summed, so lists must be in the same currency,
+// e.g. chunks of a card statements. Use pipeline.Recount otherwise
+func mergeStatements(statements ...p24.Statements) (res p24.Statements) {
+	for _, s := range statements {
+		res.Status = s.Status
+		res.Debet += s.Debet
+		res.Credit += s.Credit
+		res.Statements = append(res.Statements, s.Statements...)
+	}
+	return res
+}
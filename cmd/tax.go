@@ -0,0 +1,326 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/config"
+	"github.com/dimboknv/p24-cli/export"
+	"github.com/dimboknv/p24-cli/expr"
+	"github.com/dimboknv/p24-cli/fx"
+	"github.com/dimboknv/p24-cli/pipeline"
+	"github.com/dimboknv/p24-cli/tax"
+	log "github.com/go-pkgz/lgr"
+	"github.com/jessevdk/go-flags"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultLedgerFormat   = "Level|N|Date{2006-01-02}|Quarter|Received{precision=2}|Rate{precision=4}|RateDate|Income{precision=2}|Cumulative{precision=2}|Count|Card|Description|," // nolint
+	defaultLedgerEncoding = "table"
+)
+
+// TaxCmd set of sole proprietor single tax commands
+type TaxCmd struct {
+	LedgerCmd      TaxLedgerCmd      `command:"ledger" description:"Export income ledger of a quarter or year with quarterly subtotals"`
+	DeclarationCmd TaxDeclarationCmd `command:"declaration" description:"Render quarterly single tax declaration xml with cumulative income and print its summary"` // nolint
+}
+
+// TaxIncomeOpts set of flags for selecting income statements of a tax period
+// nolint:govet // need to save command arguments order
+type TaxIncomeOpts struct {
+	CategoryOpts
+	MerchantOpts
+	RefundOpts
+	TransferOpts
+	CounterpartyOpts
+	Year      int            `long:"year" description:"Tax year, the current year by default"`
+	Quarter   int            `long:"quarter" description:"Tax quarter 1-4, the whole year by default"`
+	Exclude   string         `long:"exclude" description:"Expression of incoming statements which aren't income, config file \"tax.exclude\" or \"IsTransfer || IsRefund\" by default"` // nolint
+	RatesFile flags.Filename `long:"rates" description:"Exchange rates to UAH csv/json table for foreign currency receipts, config file \"rates\" by default"`                          // nolint
+
+	period  tax.Period
+	include *expr.Expr // negated exclude expression
+	rates   *fx.Table
+}
+
+// TaxLedgerCmd set of flags for income ledger export
+// nolint:govet // need to save command arguments order
+type TaxLedgerCmd struct {
+	StatementsSourceOpts
+	TaxIncomeOpts
+	ExportOpts
+}
+
+// TaxDeclarationCmd set of flags for single tax declaration rendering
+// nolint:govet // need to save command arguments order
+type TaxDeclarationCmd struct {
+	StatementsSourceOpts
+	TaxIncomeOpts
+	Filled         string         `long:"filled" description:"Fill date of the declaration with \"dd.mm.yyyy\" layout, today by default"`
+	SchemaFile     flags.Filename `long:"schema" description:"Published XSD schema file of the form to validate the declaration xml, config file \"tax.form.schema\" by default"` // nolint
+	Force          bool           `long:"force" description:"Render the declaration xml without XSD schema validation if the schema isn't set"`
+	OutputFilename flags.Filename `short:"o" long:"out" description:"Save declaration xml to a file. If empty print it to stdout"`
+
+	payer  tax.Taxpayer
+	form   tax.Form
+	filled time.Time
+	schema *tax.Schema // nil if isn't set and validation is forced off
+}
+
+// Execute exports income ledger, entry point for "tax ledger" command
+func (cmd *TaxLedgerCmd) Execute(_ []string) error {
+	log.Printf("[INFO] \"tax ledger\" command is started year=%d quarter=%d", cmd.Year, cmd.Quarter)
+
+	if err := cmd.setup(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		defer cancel()
+		cmd.waitSigterm(ctx)
+	}()
+
+	entries, err := cmd.ledger(ctx, &cmd.StatementsSourceOpts)
+	if err != nil {
+		return err
+	}
+	total := entries[len(entries)-1]
+	cmd.report("%s income: %d receipts, %.2f %s", cmd.period, total.Count, total.Income.Float64(), tax.Currency)
+
+	if err = cmd.export(tax.Rows(entries)); err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] \"tax ledger\" command succeeded terminated")
+	return nil
+}
+
+func (cmd *TaxLedgerCmd) setup() error {
+	if err := cmd.setupIncome(&cmd.CommonOpts, &cmd.StatementsSourceOpts); err != nil {
+		return err
+	}
+
+	defaults := config.Preset{Format: defaultLedgerFormat, Encoding: defaultLedgerEncoding}
+	if err := cmd.setupExport(&cmd.CommonOpts, defaults, tax.Entry{}, tax.Fields()); err != nil {
+		return err
+	}
+	cmd.exportOpts = append(cmd.exportOpts, export.WithTitle(fmt.Sprintf("Income ledger of %s", cmd.period)))
+
+	return cmd.setupSource(cmd.fields())
+}
+
+// setupIncome checks the tax period, sets statements date range from the beginning of the year to the end of
+// the period if it is not set, loads exchange rates and parses the exclude expression
+func (opts *TaxIncomeOpts) setupIncome(common *CommonOpts, source *StatementsSourceOpts) (err error) {
+	cfg, err := common.loadConfig()
+	if err != nil {
+		return err
+	}
+
+	if opts.Year == 0 {
+		opts.Year = time.Now().In(p24.NewKievLocation()).Year()
+	}
+	opts.period = tax.Period{Year: opts.Year, Quarter: opts.Quarter}
+	if err = opts.period.Check(); err != nil {
+		return errors.Wrapf(err, "invalid tax period")
+	}
+	start, _ := tax.Period{Year: opts.Year}.Bounds()
+	_, end := opts.period.Bounds()
+	source.StartDateStr = firstNonEmpty(source.StartDateStr, start.Format(inputTimeLayout))
+	source.EndDateStr = firstNonEmpty(source.EndDateStr, end.Format(inputTimeLayout))
+
+	if err = opts.setupMerchants(common); err != nil {
+		return errors.Wrapf(err, "invalid merchant options")
+	}
+	if err = opts.setupCategorizer(common, opts.merchants); err != nil {
+		return errors.Wrapf(err, "invalid categorisation options")
+	}
+	if err = opts.setupRefunds(source); err != nil {
+		return errors.Wrapf(err, "invalid refund options")
+	}
+	if err = opts.setupTransfers(source); err != nil {
+		return errors.Wrapf(err, "invalid transfer options")
+	}
+	if err = opts.setupParser(common); err != nil {
+		return errors.Wrapf(err, "invalid description parsing options")
+	}
+
+	if opts.rates, err = loadRates(string(opts.RatesFile), cfg); err != nil {
+		return errors.Wrapf(err, "invalid rates")
+	}
+
+	exclude := firstNonEmpty(opts.Exclude, cfg.Tax.Exclude, tax.DefaultExclude)
+	if opts.include, err = pipeline.ParseWhere("!("+exclude+")", opts.fields()); err != nil {
+		return errors.Wrapf(err, "invalid exclude expression %q", exclude)
+	}
+	return nil
+}
+
+// ledger loads statements and returns income ledger entries of the tax period without excluded statements
+func (opts *TaxIncomeOpts) ledger(ctx context.Context, source *StatementsSourceOpts) ([]*tax.Entry, error) {
+	statements, err := source.loadStatements(ctx, opts.fields())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get statements list")
+	}
+
+	income, err := pipeline.Filter(statements.Statements, opts.include, opts.fields())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to exclude statements")
+	}
+	if excluded := receipts(statements.Statements) - receipts(income); excluded > 0 {
+		source.report("excluded %d incoming statements from income", excluded)
+	}
+
+	entries, err := tax.Ledger(income, opts.period, opts.rates)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build income ledger")
+	}
+	return entries, nil
+}
+
+// fields returns virtual fields of statements available for exclude and filters expressions
+func (opts *TaxIncomeOpts) fields() export.VirtualFields {
+	fields := pipeline.Fields()
+	for name, f := range opts.categoryFields() {
+		fields[name] = f
+	}
+	for name, f := range opts.merchantFields() {
+		fields[name] = f
+	}
+	for name, f := range opts.refundFields() {
+		fields[name] = f
+	}
+	for name, f := range opts.transferFields() {
+		fields[name] = f
+	}
+	for name, f := range opts.counterpartyFields() {
+		fields[name] = f
+	}
+	return fields
+}
+
+// receipts returns count of incoming statements
+func receipts(statements []p24.Statement) int {
+	n := 0
+	for i := range statements {
+		if statements[i].CardAmount.Amount > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// Execute renders single tax declaration, entry point for "tax declaration" command
+func (cmd *TaxDeclarationCmd) Execute(_ []string) error {
+	log.Printf("[INFO] \"tax declaration\" command is started year=%d quarter=%d", cmd.Year, cmd.Quarter)
+
+	if err := cmd.setup(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		defer cancel()
+		cmd.waitSigterm(ctx)
+	}()
+
+	entries, err := cmd.ledger(ctx, &cmd.StatementsSourceOpts)
+	if err != nil {
+		return err
+	}
+	declaration, err := tax.NewDeclaration(entries, cmd.payer, cmd.form, cmd.filled)
+	if err != nil {
+		return err
+	}
+	data, err := declaration.XML()
+	if err != nil {
+		return err
+	}
+	if cmd.schema != nil {
+		if err = cmd.schema.Validate(data); err != nil {
+			return err
+		}
+	} else {
+		cmd.report("WARNING: declaration xml isn't validated by the form XSD schema")
+	}
+	for _, line := range declaration.Summary() {
+		cmd.report("%s", line)
+	}
+
+	if cmd.OutputFilename == "" {
+		_, err = os.Stdout.Write(data)
+		return errors.Wrap(err, "failed to print declaration")
+	}
+	if err = os.WriteFile(string(cmd.OutputFilename), data, 0o600); err != nil {
+		return errors.Wrapf(err, "failed to save declaration %q", cmd.OutputFilename)
+	}
+
+	log.Printf("[INFO] \"tax declaration\" command succeeded terminated")
+	return nil
+}
+
+func (cmd *TaxDeclarationCmd) setup() (err error) {
+	if cmd.Quarter == 0 {
+		cmd.Quarter = 4 // the declaration of the year is the fourth quarter one
+	}
+	if err = cmd.setupIncome(&cmd.CommonOpts, &cmd.StatementsSourceOpts); err != nil {
+		return err
+	}
+
+	cmd.filled = time.Now().In(p24.NewKievLocation())
+	if cmd.Filled != "" {
+		if cmd.filled, err = time.ParseInLocation(inputTimeLayout, cmd.Filled, p24.NewKievLocation()); err != nil {
+			return errors.Wrapf(err, "invalid fill date")
+		}
+	}
+
+	cfg, err := cmd.loadConfig()
+	if err != nil {
+		return err
+	}
+	payer := cfg.Tax.Taxpayer
+	cmd.payer = tax.Taxpayer{
+		Name:       payer.Name,
+		TIN:        payer.TIN,
+		Address:    payer.Address,
+		Office:     payer.Office,
+		OfficeCode: payer.OfficeCode,
+		Rate:       payer.Rate,
+		Limit:      p24.Amount(math.Round(payer.Limit * float64(p24.DecimalPrecision))),
+	}
+	form := cfg.Tax.Form
+	cmd.form = tax.Form{
+		Code:          firstNonEmpty(form.Code, tax.DefaultFormCode),
+		Income:        form.Income,
+		QuarterIncome: form.QuarterIncome,
+		Tax:           form.Tax,
+		PreviousTax:   form.PreviousTax,
+		Due:           form.Due,
+	}
+	if cmd.payer.Rate == 0 {
+		cmd.payer.Rate = tax.DefaultRate
+	}
+	// taxpayer details and form codes are checked before loading statements
+	blank := tax.Declaration{Form: cmd.form, Taxpayer: cmd.payer, Period: cmd.period, Filled: cmd.filled}
+	if err = blank.Validate(); err != nil {
+		return errors.Wrapf(err, "invalid tax config")
+	}
+	switch schema := firstNonEmpty(string(cmd.SchemaFile), form.Schema); {
+	case schema != "":
+		if cmd.schema, err = tax.LoadSchema(schema); err != nil {
+			return err
+		}
+	case !cmd.Force:
+		return errors.New("the form XSD schema is required to validate the declaration, set it by --schema or skip validation by --force")
+	}
+
+	return cmd.setupSource(cmd.fields())
+}
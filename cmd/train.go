@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+
+	"github.com/dimboknv/p24-cli/category"
+	"github.com/dimboknv/p24-cli/importer"
+	log "github.com/go-pkgz/lgr"
+	"github.com/jessevdk/go-flags"
+	"github.com/pkg/errors"
+)
+
+// TrainCmd set of flags for training category classifier on labelled statements
+// nolint:govet // need to save command arguments order
+type TrainCmd struct {
+	Labelled  []flags.Filename `long:"labelled" required:"true" description:"Labelled xml/csv/xlsx export with category column. Can be repeated"` // nolint
+	Label     string           `long:"label" default:"Category" description:"Category column name"`
+	ModelFile flags.Filename   `long:"model" required:"true" description:"Classifier model file to save"`
+	Update    bool             `long:"update" description:"Add labelled statements to the existing model instead of replacing it"`
+	CommonOpts
+}
+
+// Execute trains category classifier and saves its model, entry point for "train" command
+func (cmd *TrainCmd) Execute(_ []string) error {
+	log.Printf("[INFO] \"train\" command is started model=%s labelled=%v", cmd.ModelFile, cmd.Labelled)
+
+	c := category.NewClassifier()
+	if _, err := os.Stat(string(cmd.ModelFile)); cmd.Update && err == nil {
+		if c, err = category.LoadClassifier(string(cmd.ModelFile)); err != nil {
+			return err
+		}
+	}
+
+	trained, label := c.Trained(), strings.ToLower(cmd.Label)
+	for _, filename := range cmd.Labelled {
+		records, err := importer.ReadRecords(string(filename))
+		if err != nil {
+			return err
+		}
+		for i, values := range records {
+			s, err := importer.MakeStatement(values)
+			if err != nil {
+				return errors.Wrapf(err, "%q record #%d", filename, i+1)
+			}
+			c.Train(&s, values[label])
+		}
+	}
+
+	if c.Trained() == trained {
+		return errors.Errorf("there are no statements labelled by %q column", cmd.Label)
+	}
+	if err := c.Save(string(cmd.ModelFile)); err != nil {
+		return err
+	}
+
+	cmd.report("model is trained on %d statements of %d categories", c.Trained()-trained, len(c.Docs))
+	log.Printf("[INFO] \"train\" command succeeded terminated")
+	return nil
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/dimboknv/p24-cli/export"
+	"github.com/dimboknv/p24-cli/transfer"
+	"github.com/pkg/errors"
+)
+
+// TransferOpts set of flags for own cards transfers detection
+type TransferOpts struct {
+	TransferWindow time.Duration `long:"transfer-window" default:"1h" description:"Maximal time between outgoing and incoming statements of a transfer"`
+	OwnCards       []string      `long:"own-card" description:"Own card number besides loaded cards for transfers detection. Can be repeated"`
+
+	transfers *transfer.Transfers
+}
+
+// setupTransfers makes empty transfers, they are detected by source analyzer after loading statements
+func (opts *TransferOpts) setupTransfers(source *StatementsSourceOpts) error {
+	if opts.TransferWindow < 0 {
+		return errors.Errorf("transfer window %s is negative", opts.TransferWindow)
+	}
+	opts.transfers = transfer.NewTransfers(transfer.Options{Window: opts.TransferWindow, Cards: opts.OwnCards})
+	source.analyzers = append(source.analyzers, opts.transfers.Detect)
+	return nil
+}
+
+// transferFields returns IsTransfer, TransferID and TransferCard virtual fields, see transfer.Fields
+func (opts *TransferOpts) transferFields() export.VirtualFields {
+	return transfer.Fields(opts.transfers)
+}
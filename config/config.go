@@ -0,0 +1,124 @@
+// Package config implements p24 yaml config file
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Config represents p24 config file
+type Config struct {
+	Presets   map[string]Preset `yaml:"presets"`
+	Rules     string            `yaml:"rules"`     // categorisation rules file
+	Model     string            `yaml:"model"`     // category classifier model file
+	Store     string            `yaml:"store"`     // manually assigned categories file
+	Rates     string            `yaml:"rates"`     // exchange rates table file
+	Base      string            `yaml:"base"`      // base currency of conversion
+	Patterns  string            `yaml:"patterns"`  // description parsing patterns file
+	Directory string            `yaml:"directory"` // counterparties directory file
+	Merchants string            `yaml:"merchants"` // merchant aliases file
+	Tax       Tax               `yaml:"tax"`
+}
+
+// Tax is sole proprietor single tax settings
+type Tax struct {
+	Exclude  string   `yaml:"exclude"` // expression of incoming statements which aren't income
+	Taxpayer Taxpayer `yaml:"taxpayer"`
+	Form     TaxForm  `yaml:"form"`
+}
+
+// Taxpayer is sole proprietor details of the single tax declaration
+type Taxpayer struct {
+	Name       string  `yaml:"name"`
+	TIN        string  `yaml:"tin"`
+	Address    string  `yaml:"address"`
+	Office     string  `yaml:"office"`      // tax office name
+	OfficeCode string  `yaml:"office_code"` // 4 digits code of the tax office region and district
+	Rate       float64 `yaml:"rate"`        // single tax rate in percents
+	Limit      float64 `yaml:"limit"`       // yearly income limit of the single tax group
+}
+
+// TaxForm overrides declaration form and amounts elements codes
+type TaxForm struct {
+	Code          string `yaml:"code"`
+	Income        string `yaml:"income"`
+	QuarterIncome string `yaml:"quarter_income"`
+	Tax           string `yaml:"tax"`
+	PreviousTax   string `yaml:"previous_tax"`
+	Due           string `yaml:"due"`
+	Schema        string `yaml:"schema"` // published XSD schema file of the form
+}
+
+// Preset is a named set of statements export options
+type Preset struct {
+	Format   string `yaml:"format"`
+	Encoding string `yaml:"encoding"`
+	Locale   string `yaml:"locale"`
+	Sheet    string `yaml:"sheet"`     // xlsx sheet name
+	NoHeader bool   `yaml:"no_header"` // csv without header row
+}
+
+// DefaultPath returns default config file path, "$XDG_CONFIG_HOME/p24/config.yml" on linux
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "p24", "config.yml")
+}
+
+// Load reads config from yaml file at path.
+// Returns empty Config if path is empty or default config file does not exist
+func Load(path string) (Config, error) {
+	isDefault := path == ""
+	if isDefault {
+		path = DefaultPath()
+	}
+	if path == "" {
+		return Config{}, nil
+	}
+
+	data, err := os.ReadFile(filepath.Clean(path))
+	if isDefault && errors.Is(err, os.ErrNotExist) {
+		return Config{}, nil
+	}
+	if err != nil {
+		return Config{}, errors.Wrapf(err, "failed to read config %q", path)
+	}
+
+	var c Config
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return Config{}, errors.Wrapf(err, "failed to parse config %q", path)
+	}
+	return c, nil
+}
+
+// Preset returns preset by name. Config presets override builtin ones
+func (c Config) Preset(name string) (Preset, error) {
+	if p, ok := c.Presets[name]; ok {
+		return p, nil
+	}
+	if p, ok := BuiltinPresets[name]; ok {
+		return p, nil
+	}
+	return Preset{}, errors.Errorf("unknown preset %q, available: %v", name, c.PresetNames())
+}
+
+// PresetNames returns sorted names of config and builtin presets
+func (c Config) PresetNames() []string {
+	names := make([]string, 0, len(c.Presets)+len(BuiltinPresets))
+	for name := range BuiltinPresets {
+		if _, ok := c.Presets[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	for name := range c.Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
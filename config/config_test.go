@@ -0,0 +1,91 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/export"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Load(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	data := `
+presets:
+  accountant:
+    format: "TranDate{02.01.2006}|CardAmount|Description|;"
+    encoding: csv
+    locale: uk
+    no_header: true
+  ynab:
+    format: "TranDate|CardAmount"
+    encoding: xlsx
+    sheet: YNAB
+`
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+
+	p, err := cfg.Preset("accountant")
+	require.NoError(t, err)
+	require.Equal(t, Preset{Format: "TranDate{02.01.2006}|CardAmount|Description|;", Encoding: "csv", Locale: "uk", NoHeader: true}, p)
+
+	p, err = cfg.Preset("ynab")
+	require.NoError(t, err)
+	require.Equal(t, "YNAB", p.Sheet, "config preset should override builtin")
+
+	p, err = cfg.Preset("actual")
+	require.NoError(t, err)
+	require.Equal(t, BuiltinPresets["actual"], p)
+
+	_, err = cfg.Preset("unknown")
+	require.Error(t, err)
+	require.Equal(t, []string{"accountant", "actual", "firefly", "ynab"}, cfg.PresetNames())
+
+	_, err = Load(filepath.Join(t.TempDir(), "not-exist.yml"))
+	require.Error(t, err)
+}
+
+func Test_BuiltinPresets(t *testing.T) {
+	statements := p24.Statements{Statements: []p24.Statement{
+		{
+			TranDate:    time.Date(2022, 3, 15, 10, 30, 0, 0, p24.NewKievLocation()),
+			Description: "Silpo",
+			Terminal:    "Kyiv",
+			Amount:      p24.Funds{Currency: "UAH", Amount: -29550},
+			CardAmount:  p24.Funds{Currency: "UAH", Amount: -29550},
+		},
+		{
+			TranDate:    time.Date(2022, 3, 16, 10, 30, 0, 0, p24.NewKievLocation()),
+			Description: "Netflix",
+			Terminal:    "Los Gatos",
+			Amount:      p24.Funds{Currency: "USD", Amount: -999},
+			CardAmount:  p24.Funds{Currency: "UAH", Amount: -29520},
+		},
+	}}
+	expected := map[string]string{
+		"ynab": "Date,Payee,Memo,Outflow,Inflow\n2022-03-15,Silpo,Kyiv,295.50,\n2022-03-16,Netflix,Los Gatos,295.20,\n",
+		"firefly": "date,description,opposing_name,amount,currency_code,foreign_amount,foreign_currency_code\n" +
+			"2022-03-15,Silpo,Kyiv,-295.50,UAH,,\n2022-03-16,Netflix,Los Gatos,-295.20,UAH,-9.99,USD\n",
+		"actual": "date,payee,notes,amount\n2022-03-15,Silpo,Kyiv,-295.50\n2022-03-16,Netflix,Los Gatos,-295.20\n",
+	}
+
+	for name, p := range BuiltinPresets {
+		name, p := name, p
+		t.Run(name, func(t *testing.T) {
+			f, err := export.MakeFormat(p.Format, export.DefaultFormatParser(p24.Statement{}))
+			require.NoError(t, err)
+			f.Locale, err = export.LocaleByName(p.Locale)
+			require.NoError(t, err)
+
+			buff := bytes.NewBuffer(nil)
+			require.NoError(t, export.NewCSV(statements).Export(buff, f))
+			require.Equal(t, expected[name], buff.String())
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package config
+
+// BuiltinPresets are csv layouts for importing statements into common budgeting tools
+var BuiltinPresets = map[string]Preset{
+	// https://support.ynab.com/en_us/formatting-a-csv-file-an-overview-BJvczkuRq
+	"ynab": {
+		Format: "Date=TranDate{2006-01-02}|Payee=Description|Memo=Terminal|" +
+			"Outflow=if(CardAmount < 0, abs(CardAmount), nil){precision=2}|" +
+			"Inflow=if(CardAmount > 0, abs(CardAmount), nil){precision=2}|,",
+		Encoding: "csv",
+		Locale:   "en",
+	},
+	// https://docs.firefly-iii.org/how-to/data-importer/import/csv/
+	"firefly": {
+		Format: "date=TranDate{2006-01-02}|description=Description|opposing_name=Terminal|" +
+			"amount=CardAmount.Amount{precision=2}|currency_code=CardAmount.Currency|" +
+			"foreign_amount=if(Amount.Currency != CardAmount.Currency, Amount.Amount, nil){precision=2}|" +
+			"foreign_currency_code=if(Amount.Currency != CardAmount.Currency, Amount.Currency, nil)|,",
+		Encoding: "csv",
+		Locale:   "en",
+	},
+	// https://actualbudget.org/docs/transactions/importing
+	"actual": {
+		Format:   "date=TranDate{2006-01-02}|payee=Description|notes=Terminal|amount=CardAmount.Amount{precision=2}|,",
+		Encoding: "csv",
+		Locale:   "en",
+	},
+}
@@ -0,0 +1,253 @@
+// Package counterparty parses statements descriptions into counterparty name, IBAN, EDRPOU code and payment purpose
+package counterparty
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/export"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Parsed fields names
+const (
+	FieldCounterparty = "Counterparty"
+	FieldIBAN         = "IBAN"
+	FieldEDRPOU       = "EDRPOU"
+	FieldPurpose      = "Purpose"
+)
+
+// fieldNames in order of parsing, the purpose is the rest of description if it has no label
+var fieldNames = []string{FieldIBAN, FieldEDRPOU, FieldCounterparty, FieldPurpose}
+
+// Builtin are builtin patterns, they are used after patterns of a file
+var Builtin = []*Pattern{
+	{Field: FieldIBAN, Regexp: `\b[A-Z]{2}\d{2}[A-Z0-9]{11,30}\b`},
+	{Field: FieldEDRPOU, Regexp: `(?i)(?:ЄДРПОУ|ЕДРПОУ|ІПН|ИНН|РНОКПП|EDRPOU|код)\W{0,5}(\d{10}|\d{8})(?:\D|$)`},
+	// standalone codes are often phone or account numbers, so they must have valid check digits
+	{Field: FieldEDRPOU, Regexp: `(?:^|\D)(\d{10}|\d{8})(?:\D|$)`, Checksum: true},
+	{Field: FieldCounterparty, Regexp: `(?i)(?:отримувач|одержувач|платник|контрагент|recipient|payer)\s*:\s*([^,;]+)`},
+	// a legal form with a quoted name or up to 3 capitalised words, e.g. ТОВ «Ромашка», ФОП Іваненко Іван Іванович
+	{Field: FieldCounterparty, Regexp: `(?:^|[\s,;:(])((?:ТОВ|ТзОВ|ФОП|ПП|ПрАТ|ПАТ|АТ|КП|ДП|LLC|LTD|Ltd|GmbH)(?:\s*["«“][^"»”]+["»”]|(?:\s+\p{Lu}[\p{L}'’.\-]*){1,3}))`},
+	{Field: FieldPurpose, Regexp: `(?i)(?:призначення(?:\s+платежу)?|призн\.?|purpose)\s*[:\-]\s*(.+)$`},
+}
+
+// Pattern extracts a field value from description by the "value" named group, the first group or the whole match
+type Pattern struct {
+	re *regexp.Regexp
+
+	Field    string `yaml:"field"`    // Counterparty, IBAN, EDRPOU or Purpose
+	Regexp   string `yaml:"regexp"`   // required
+	Checksum bool   `yaml:"checksum"` // values are EDRPOU or RNOKPP codes with valid check digits
+}
+
+// Patterns is a patterns file
+type Patterns struct {
+	NoBuiltin bool       `yaml:"no_builtin"` // don't use builtin patterns
+	Patterns  []*Pattern `yaml:"patterns"`
+}
+
+// Result is parsed description
+type Result struct {
+	Counterparty string
+	IBAN         string
+	EDRPOU       string // EDRPOU code of a company or IPN of a person
+	Purpose      string
+}
+
+// Parser parses descriptions by patterns and enriches results by a counterparties directory
+type Parser struct {
+	patterns  []*Pattern
+	Directory *Directory // optional
+}
+
+// NewParser returns Parser by patterns of file followed by Builtin patterns, file can be nil
+func NewParser(file *Patterns) (*Parser, error) {
+	var patterns []*Pattern
+	if file != nil {
+		patterns = append(patterns, file.Patterns...)
+	}
+	if file == nil || !file.NoBuiltin {
+		patterns = append(patterns, Builtin...)
+	}
+
+	p := &Parser{patterns: make([]*Pattern, len(patterns))}
+	for i := range patterns {
+		pattern := *patterns[i]
+		if err := pattern.compile(); err != nil {
+			return nil, errors.Wrapf(err, "pattern #%d", i+1)
+		}
+		p.patterns[i] = &pattern
+	}
+	return p, nil
+}
+
+// LoadPatterns reads patterns yaml file
+func LoadPatterns(filename string) (*Patterns, error) {
+	data, err := os.ReadFile(filepath.Clean(filename))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read patterns %q", filename)
+	}
+	var file Patterns
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse patterns %q", filename)
+	}
+	return &file, nil
+}
+
+// Parse returns fields of description by the first matched pattern of each field.
+// Matches of IBAN, EDRPOU and Counterparty are removed from description before parsing the next fields,
+// so Purpose is the rest of description if there is no labeled purpose.
+// Counterparty is the directory name if the directory has the parsed EDRPOU, IBAN or name,
+// missing EDRPOU and IBAN are filled by the directory too
+func (p *Parser) Parse(description string) Result {
+	var res Result
+	rest := description
+	for _, name := range fieldNames {
+		for _, pattern := range p.patterns {
+			if pattern.Field != name {
+				continue
+			}
+			if value, match := pattern.find(rest); value != "" {
+				res.set(name, value)
+				if name != FieldPurpose {
+					rest = strings.Replace(rest, match, " ", 1)
+				}
+				break
+			}
+		}
+	}
+	if res.Purpose == "" {
+		res.Purpose = clean(rest)
+	}
+
+	if p.Directory != nil {
+		if e, ok := p.Directory.Lookup(res); ok {
+			res.Counterparty = e.Name
+			if res.EDRPOU == "" {
+				res.EDRPOU = e.EDRPOU
+			}
+			if res.IBAN == "" {
+				res.IBAN = e.IBAN
+			}
+		}
+	}
+	return res
+}
+
+// Fields returns Counterparty, IBAN, EDRPOU and Purpose virtual fields of statements parsed from Description
+func Fields(p *Parser) export.VirtualFields {
+	field := func(f func(r Result) interface{}) export.VirtualField {
+		return func(obj interface{}) (interface{}, error) {
+			if p == nil {
+				return nil, errors.New("description parser is not set")
+			}
+			switch s := obj.(type) {
+			case *p24.Statement:
+				return f(p.Parse(s.Description)), nil
+			case p24.Statement:
+				return f(p.Parse(s.Description)), nil
+			default:
+				return nil, errors.Errorf("%T is not a statement", obj)
+			}
+		}
+	}
+
+	return export.VirtualFields{
+		FieldCounterparty: field(func(r Result) interface{} { return r.Counterparty }),
+		FieldIBAN:         field(func(r Result) interface{} { return r.IBAN }),
+		FieldEDRPOU:       field(func(r Result) interface{} { return r.EDRPOU }),
+		FieldPurpose:      field(func(r Result) interface{} { return r.Purpose }),
+	}
+}
+
+func (p *Pattern) compile() (err error) {
+	known := false
+	for _, name := range fieldNames {
+		known = known || name == p.Field
+	}
+	if !known {
+		return errors.Errorf("unknown field %q, available: %v", p.Field, fieldNames)
+	}
+	if p.Regexp == "" {
+		return errors.New("regexp is required")
+	}
+	p.re, err = regexp.Compile(p.Regexp)
+	return errors.Wrapf(err, "invalid regexp")
+}
+
+// find returns trimmed value and the whole match of the first match of pattern in str with valid checksum if it is required
+func (p *Pattern) find(str string) (value, match string) {
+	for _, m := range p.re.FindAllStringSubmatch(str, -1) {
+		value = m[0]
+		if i := p.re.SubexpIndex("value"); i > 0 {
+			value = m[i]
+		} else if len(m) > 1 {
+			value = m[1]
+		}
+		if value = clean(value); !p.Checksum || validCode(value) {
+			return value, m[0]
+		}
+	}
+	return "", ""
+}
+
+// validCode returns true if code is 8 digits EDRPOU or 10 digits RNOKPP with a valid check digit
+func validCode(code string) bool {
+	digits := make([]int, len(code))
+	for i, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+		digits[i] = int(r - '0')
+	}
+
+	sum := func(weights ...int) int {
+		s := 0
+		for i, w := range weights {
+			s += digits[i] * w
+		}
+		return s
+	}
+	switch len(digits) {
+	case 8:
+		weights := []int{1, 2, 3, 4, 5, 6, 7}
+		if code >= "30000000" && code < "60000000" {
+			weights = []int{7, 1, 2, 3, 4, 5, 6}
+		}
+		check := sum(weights...) % 11
+		if check == 10 {
+			for i := range weights {
+				weights[i] += 2
+			}
+			check = sum(weights...) % 11 % 10
+		}
+		return check == digits[7]
+	case 10:
+		return (sum(-1, 5, 7, 9, 4, 6, 10, 5, 7)%11+11)%11%10 == digits[9]
+	default:
+		return false
+	}
+}
+
+func (r *Result) set(name, value string) {
+	switch name {
+	case FieldCounterparty:
+		r.Counterparty = value
+	case FieldIBAN:
+		r.IBAN = strings.ToUpper(value)
+	case FieldEDRPOU:
+		r.EDRPOU = value
+	case FieldPurpose:
+		r.Purpose = value
+	}
+}
+
+// clean collapses spaces and trims spaces and punctuation of str
+func clean(str string) string {
+	return strings.Trim(strings.Join(strings.Fields(str), " "), " ,;:-")
+}
@@ -0,0 +1,122 @@
+package counterparty
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Parser_Parse(t *testing.T) {
+	custom := &Patterns{Patterns: []*Pattern{
+		{Field: FieldCounterparty, Regexp: `Переказ від (?P<value>\p{Lu}\S+ \p{Lu}\.)`},
+	}}
+	cases := []struct {
+		patterns    *Patterns
+		directory   *Directory
+		description string
+		expected    Result
+	}{
+		{
+			description: "Оплата від ТОВ «Ромашка», ЄДРПОУ 12345678, UA213223130000026007233566001, призначення: оплата за рахунком №15",
+			expected: Result{
+				Counterparty: "ТОВ «Ромашка»", IBAN: "UA213223130000026007233566001", EDRPOU: "12345678",
+				Purpose: "оплата за рахунком №15",
+			},
+		},
+		{
+			description: "ФОП Іваненко Іван Іванович 3012345670 Оплата за послуги",
+			expected:    Result{Counterparty: "ФОП Іваненко Іван Іванович", EDRPOU: "3012345670", Purpose: "Оплата за послуги"},
+		},
+		{
+			description: "Оплата 21673832 за зв'язок",
+			expected:    Result{EDRPOU: "21673832", Purpose: "Оплата за зв'язок"},
+		},
+		// standalone numbers without valid check digits are phone or account numbers
+		{
+			description: "Поповнення рахунок 0671234567",
+			expected:    Result{Purpose: "Поповнення рахунок 0671234567"},
+		},
+		{
+			description: "Переказ 12345670 на 3012345678",
+			expected:    Result{Purpose: "Переказ 12345670 на 3012345678"},
+		},
+		{
+			description: "Отримувач: Kyivstar; Поповнення рахунку",
+			expected:    Result{Counterparty: "Kyivstar", Purpose: "Поповнення рахунку"},
+		},
+		{
+			description: "Silpo 5168****1234",
+			expected:    Result{Purpose: "Silpo 5168****1234"},
+		},
+		{
+			patterns:    custom,
+			description: "Переказ від Петренко П. за квартиру",
+			expected:    Result{Counterparty: "Петренко П.", Purpose: "за квартиру"},
+		},
+		{
+			patterns:    &Patterns{NoBuiltin: true},
+			description: "ЄДРПОУ 12345678",
+			expected:    Result{Purpose: "ЄДРПОУ 12345678"},
+		},
+		{
+			directory:   NewDirectory(Entry{Name: "Romashka LLC", EDRPOU: "12345678", IBAN: "UA213223130000026007233566001"}),
+			description: "Оплата від ТОВ «Ромашка», ЄДРПОУ 12345678",
+			expected: Result{
+				Counterparty: "Romashka LLC", IBAN: "UA213223130000026007233566001", EDRPOU: "12345678", Purpose: "Оплата від",
+			},
+		},
+		{
+			directory:   NewDirectory(Entry{Name: "Kyivstar JSC", EDRPOU: "21673832"}),
+			description: "Отримувач: kyivstar jsc",
+			expected:    Result{Counterparty: "Kyivstar JSC", EDRPOU: "21673832"},
+		},
+	}
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			p, err := NewParser(c.patterns)
+			require.NoError(t, err)
+			p.Directory = c.directory
+			require.Equal(t, c.expected, p.Parse(c.description))
+		})
+	}
+}
+
+func Test_validCode(t *testing.T) {
+	for code, valid := range map[string]bool{
+		"21673832": true, "14360570": true, "00032129": true, "12345670": false,
+		"3012345670": true, "0671234567": false, "301234567": false, "30123456x0": false,
+	} {
+		require.Equal(t, valid, validCode(code), code)
+	}
+}
+
+func Test_NewParser(t *testing.T) {
+	_, err := NewParser(&Patterns{Patterns: []*Pattern{{Field: "Name", Regexp: "x"}}})
+	require.Error(t, err)
+	_, err = NewParser(&Patterns{Patterns: []*Pattern{{Field: FieldIBAN, Regexp: "("}}})
+	require.Error(t, err)
+	_, err = NewParser(&Patterns{Patterns: []*Pattern{{Field: FieldIBAN}}})
+	require.Error(t, err)
+}
+
+func Test_LoadDirectory(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "directory.csv")
+	require.NoError(t, os.WriteFile(filename, []byte("Name,EDRPOU,IBAN\nRomashka LLC,12345678,ua213223130000026007233566001\n"), 0o600))
+
+	d, err := LoadDirectory(filename)
+	require.NoError(t, err)
+	e, ok := d.Lookup(Result{IBAN: "UA213223130000026007233566001"})
+	require.True(t, ok)
+	require.Equal(t, Entry{Name: "Romashka LLC", EDRPOU: "12345678", IBAN: "UA213223130000026007233566001"}, e)
+	_, ok = d.Lookup(Result{})
+	require.False(t, ok)
+
+	require.NoError(t, os.WriteFile(filename, []byte("edrpou\n12345678\n"), 0o600))
+	_, err = LoadDirectory(filename)
+	require.Error(t, err)
+}
@@ -0,0 +1,102 @@
+package counterparty
+
+import (
+	"bytes"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Entry is a counterparty of a directory
+type Entry struct {
+	Name   string
+	EDRPOU string
+	IBAN   string
+}
+
+// Directory is a list of known counterparties
+type Directory struct {
+	byEDRPOU map[string]Entry
+	byIBAN   map[string]Entry
+	byName   map[string]Entry
+}
+
+// NewDirectory returns Directory of entries
+func NewDirectory(entries ...Entry) *Directory {
+	d := &Directory{byEDRPOU: map[string]Entry{}, byIBAN: map[string]Entry{}, byName: map[string]Entry{}}
+	for _, e := range entries {
+		e.IBAN = strings.ToUpper(e.IBAN)
+		if e.EDRPOU != "" {
+			d.byEDRPOU[e.EDRPOU] = e
+		}
+		if e.IBAN != "" {
+			d.byIBAN[e.IBAN] = e
+		}
+		d.byName[strings.ToLower(e.Name)] = e
+	}
+	return d
+}
+
+// LoadDirectory reads Directory from csv file with name and optional edrpou and iban columns
+func LoadDirectory(filename string) (*Directory, error) {
+	data, err := os.ReadFile(filepath.Clean(filename))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read directory %q", filename)
+	}
+	entries, err := parseCSV(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse directory %q", filename)
+	}
+	return NewDirectory(entries...), nil
+}
+
+// Lookup returns the entry of r EDRPOU, IBAN or case insensitive Counterparty name in this order
+func (d *Directory) Lookup(r Result) (Entry, bool) {
+	if e, ok := d.byEDRPOU[r.EDRPOU]; ok && r.EDRPOU != "" {
+		return e, true
+	}
+	if e, ok := d.byIBAN[strings.ToUpper(r.IBAN)]; ok && r.IBAN != "" {
+		return e, true
+	}
+	e, ok := d.byName[strings.ToLower(r.Counterparty)]
+	return e, ok && r.Counterparty != ""
+}
+
+func parseCSV(data []byte) ([]Entry, error) {
+	dec := csv.NewReader(bytes.NewReader(data))
+	dec.FieldsPerRecord = -1
+	records, err := dec.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	cols := map[string]int{}
+	for i, name := range records[0] {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := cols["name"]; !ok {
+		return nil, errors.New(`"name" column is required`)
+	}
+	value := func(record []string, name string) string {
+		if i, ok := cols[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	entries := make([]Entry, 0, len(records)-1)
+	for i, record := range records[1:] {
+		e := Entry{Name: value(record, "name"), EDRPOU: value(record, "edrpou"), IBAN: value(record, "iban")}
+		if e.Name == "" {
+			return nil, errors.Errorf("line %d: name is empty", i+2)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
@@ -0,0 +1,67 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+
+	"github.com/dimboknv/p24"
+	"github.com/pkg/errors"
+)
+
+// csvExporter export rows as csv with custom format
+type csvExporter struct {
+	rows Rows
+	opts options
+}
+
+// NewCSV returns new csv exporter of statements. Format delim is used as csv fields delimiter
+func NewCSV(statements p24.Statements, opts ...Option) Exporter {
+	return NewCSVRows(StatementsRows(statements), opts...)
+}
+
+// NewCSVRows returns new csv exporter of rows
+func NewCSVRows(rows Rows, opts ...Option) Exporter {
+	return &csvExporter{rows: rows, opts: makeOptions(opts)}
+}
+
+// Export rows to w writer as csv with given f Format
+func (ex *csvExporter) Export(w io.Writer, f Format) error {
+	// encode to temporary buffer for prevent incomplete write
+	buff := bytes.NewBuffer([]byte{})
+	enc := csv.NewWriter(buff)
+	enc.Comma = f.Delim
+	if err := ex.encode(enc, f); err != nil {
+		return errors.Wrap(err, "encode failed")
+	}
+
+	if _, err := w.Write(buff.Bytes()); err != nil {
+		return errors.Wrap(err, "failed to write encoded data")
+	}
+	return nil
+}
+
+func (ex *csvExporter) encode(enc *csv.Writer, f Format) error {
+	if !ex.opts.noHeader {
+		if err := enc.Write(f.Fields); err != nil {
+			return err
+		}
+	}
+
+	record := make([]string, len(f.Fields))
+	for _, item := range ex.rows.Items {
+		values, err := f.ValuesOf(item)
+		if err != nil {
+			return err
+		}
+		for k := range values {
+			record[k] = f.Text(f.Fields[k], values[k])
+		}
+		if err := enc.Write(record); err != nil {
+			return err
+		}
+	}
+
+	enc.Flush()
+	return enc.Error()
+}
@@ -5,14 +5,22 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/dimboknv/p24-cli/expr"
 	"github.com/pkg/errors"
 )
 
 // Format represents export format for Exporter.Export func
 type Format struct {
-	Str    string
-	Fields []string
-	Delim  rune
+	// Exprs holds expressions of computed fields by field name, nil if there are no computed fields
+	Exprs map[string]*expr.Expr
+	// Mods holds output modifiers by field name, nil if there are no modifiers
+	Mods map[string]Modifier
+	// Virtual holds virtual fields available for the format, nil if there are no virtual fields
+	Virtual VirtualFields
+	Locale  Locale
+	Str     string
+	Fields  []string
+	Delim   rune
 }
 
 // String returns Format string representation
@@ -21,22 +29,20 @@ func (f Format) String() string {
 }
 
 // FormatParser type is a function that parse str to Format
-type FormatParser func(str string) (fields []string, delim rune, err error)
+type FormatParser func(str string) (Format, error)
 
 // MakeFormat makes Format. Just set all Format properties received from FormatParser calling
 func MakeFormat(str string, fp FormatParser) (Format, error) {
-	fields, delim, err := fp(str)
+	f, err := fp(str)
 	if err != nil {
 		return Format{}, err
 	}
-	return Format{
-		Fields: fields,
-		Delim:  delim,
-		Str:    str,
-	}, nil
+	f.Str = str
+	return f, nil
 }
 
 // ValuesOf returns values of an obj fields in order to f.Fields.
+// Computed fields are evaluated with obj fields as identifiers.
 // Returns an error if obj is not struct or pointer to struct
 func (f *Format) ValuesOf(obj interface{}) ([]interface{}, error) {
 	objVal := reflect.ValueOf(obj)
@@ -51,7 +57,24 @@ func (f *Format) ValuesOf(obj interface{}) ([]interface{}, error) {
 
 	res := make([]interface{}, len(f.Fields))
 	for i := 0; i < len(f.Fields); i++ {
+		if e, ok := f.Exprs[f.Fields[i]]; ok {
+			v, err := f.Virtual.Env(obj).Eval(e)
+			if err != nil {
+				return nil, err
+			}
+			res[i] = v
+			continue
+		}
+
 		fieldVal := objVal.FieldByName(f.Fields[i])
+		if vf, ok := f.Virtual[f.Fields[i]]; ok && !fieldVal.IsValid() {
+			v, err := vf(obj)
+			if err != nil {
+				return nil, errors.Wrapf(err, "virtual field %q", f.Fields[i])
+			}
+			res[i] = v
+			continue
+		}
 		if !fieldVal.IsValid() {
 			return nil, errors.Errorf("%q field not exist", f.Fields[i])
 		}
@@ -63,48 +86,156 @@ func (f *Format) ValuesOf(obj interface{}) ([]interface{}, error) {
 	return res, nil
 }
 
-var nonWordRegexp = regexp.MustCompile(`^\W$`)
+var (
+	nonWordRegexp = regexp.MustCompile(`^\W$`)
+	identRegexp   = regexp.MustCompile(`^[A-Za-z_]\w*$`)
+)
 
 // DefaultFormatParser returns FormatParser func where
 // input str must be following format: "Field1|Field2|...|FieldN|," or "Field1|Field2|...|FieldN"
 // - ',' is delim (default is ',') must be non-word character
 // - 'FieldN' name of a valid exported field of struct
-func DefaultFormatParser(strct interface{}) FormatParser {
+// or virtual field name or computed field "Name=expression", e.g. "Month=month(TranDate)", see expr package.
+// Any field can have output modifier suffix, e.g. "TranDate{02.01.2006 15:04}", see Modifier
+func DefaultFormatParser(strct interface{}, virtual ...VirtualFields) FormatParser {
 	strctTyp := reflect.TypeOf(strct)
 	if k := strctTyp.Kind(); k != reflect.Struct {
-		return func(string) ([]string, rune, error) {
-			return nil, rune(0), errors.Errorf("kind %q is not supported", k)
+		return func(string) (Format, error) {
+			return Format{}, errors.Errorf("kind %q is not supported", k)
 		}
 	}
 
-	return func(str string) (fields []string, delim rune, err error) {
-		// get fields names
-		ff := strings.Split(str, "|")
-		if len(ff) == 0 { // maybe format str contains one field only
-			ff = append(ff, str)
+	vf := mergeVirtualFields(virtual)
+	isField := func(name string) bool {
+		f, ok := strctTyp.FieldByName(name)
+		if _, isVirtual := vf[name]; isVirtual && !ok {
+			return true
 		}
+		return ok && !f.Anonymous && f.PkgPath == ""
+	}
 
-		fields = make([]string, 0, len(ff))
-		delim = ',' // default delim
+	return func(str string) (Format, error) {
+		// get fields names
+		ff := splitFormat(str)
+		res := Format{
+			Fields:  make([]string, 0, len(ff)),
+			Delim:   ',', // default delim
+			Virtual: vf,
+		}
 
 		for i := 0; i < len(ff); i++ {
 			// check if latest field string is delim rune
 			if i == len(ff)-1 && nonWordRegexp.MatchString(ff[i]) {
-				delim = rune(ff[i][0])
+				res.Delim = rune(ff[i][0])
 				continue
 			}
 
-			// check if field name is valid
-			if f, ok := strctTyp.FieldByName(ff[i]); !ok || f.Anonymous {
-				return nil, rune(0), errors.Errorf("invalid field %q", ff[i])
+			if err := res.addField(ff[i], strctTyp, isField); err != nil {
+				return Format{}, err
 			}
-			fields = append(fields, ff[i])
 		}
 
-		if len(fields) == 0 {
-			return nil, rune(0), errors.New("no fields")
+		if len(res.Fields) == 0 {
+			return Format{}, errors.New("no fields")
 		}
 
-		return fields, delim, nil
+		return res, nil
+	}
+}
+
+// addField parses str field with optional modifier and adds it to f
+func (f *Format) addField(str string, strctTyp reflect.Type, isField func(string) bool) error {
+	field, mod, err := splitModifier(str)
+	if err != nil {
+		return err
+	}
+
+	name, e := field, (*expr.Expr)(nil)
+	if eq := strings.Index(field, "="); eq != -1 {
+		// computed field
+		if name, e, err = parseComputedField(field[:eq], field[eq+1:], isField); err != nil {
+			return err
+		}
+	} else if _, isVirtual := f.Virtual[field]; !isVirtual {
+		// check if field name is valid
+		if sf, ok := strctTyp.FieldByName(field); !ok || sf.Anonymous {
+			return errors.Errorf("invalid field %q", field)
+		}
+	}
+
+	for _, ff := range f.Fields {
+		if ff == name {
+			return errors.Errorf("duplicated field %q", name)
+		}
+	}
+	if e != nil && isField(name) {
+		return errors.Errorf("duplicated field %q", name)
+	}
+	f.Fields = append(f.Fields, name)
+
+	if e != nil {
+		if f.Exprs == nil {
+			f.Exprs = map[string]*expr.Expr{}
+		}
+		f.Exprs[name] = e
+	}
+	if mod != nil {
+		if f.Mods == nil {
+			f.Mods = map[string]Modifier{}
+		}
+		f.Mods[name] = *mod
+	}
+	return nil
+}
+
+func parseComputedField(name, src string, isField func(string) bool) (string, *expr.Expr, error) {
+	name = strings.TrimSpace(name)
+	if !identRegexp.MatchString(name) {
+		return "", nil, errors.Errorf("invalid computed field name %q", name)
+	}
+	e, err := expr.Parse(src)
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "invalid computed field %q", name)
+	}
+	for _, ident := range e.Idents() {
+		if !isField(ident) {
+			return "", nil, errors.Errorf("computed field %q: invalid field %q", name, ident)
+		}
+	}
+	return name, e, nil
+}
+
+// splitFormat splits format str by '|' separator. Separators inside
+// quotes, parentheses and '||' operator of computed fields are skipped
+func splitFormat(str string) []string {
+	var (
+		res   []string
+		depth int
+		quote rune
+		start int
+	)
+	runes := []rune(str)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+		case c == '(' || c == '{':
+			depth++
+		case c == ')' || c == '}':
+			depth--
+		case c == '|' && i+1 < len(runes) && runes[i+1] == '|':
+			i++
+		case c == '|' && depth == 0:
+			res = append(res, string(runes[start:i]))
+			start = i + 1
+		}
 	}
+	return append(res, string(runes[start:]))
 }
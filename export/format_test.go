@@ -3,7 +3,10 @@ package export
 import (
 	"strconv"
 	"testing"
+	"time"
 
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/expr"
 	"github.com/stretchr/testify/require"
 )
 
@@ -51,6 +54,38 @@ func Test_DefaultFormatParser(t *testing.T) {
 			}{},
 			withErr: true,
 		},
+		{
+			str: "A|D=A || B|E=abs(C)|;",
+			strct: struct {
+				A bool
+				B bool
+				C int
+			}{},
+			expected: Format{
+				Str:    "A|D=A || B|E=abs(C)|;",
+				Fields: []string{"A", "D", "E"},
+				Delim:  ';',
+				Exprs: map[string]*expr.Expr{
+					"D": expr.MustParse("A || B"),
+					"E": expr.MustParse("abs(C)"),
+				},
+			},
+		},
+		{
+			str:     "A|D=abs(Z)",
+			strct:   struct{ A int }{},
+			withErr: true,
+		},
+		{
+			str:     "A|A=abs(A)",
+			strct:   struct{ A int }{},
+			withErr: true,
+		},
+		{
+			str:     "B=abs(A)",
+			strct:   struct{ A, B int }{},
+			withErr: true,
+		},
 	}
 	for i, c := range cases {
 		c := c
@@ -99,3 +134,93 @@ func Test_ValuesOf(t *testing.T) {
 		})
 	}
 }
+
+func Test_ValuesOf_Computed(t *testing.T) {
+	type Obj struct {
+		A int
+		B string
+		C bool
+	}
+
+	format, err := MakeFormat("B|D=A * 2|E=C && A > 1", DefaultFormatParser(Obj{}))
+	require.NoError(t, err)
+
+	cases := []struct {
+		obj      interface{}
+		expected []interface{}
+		withErr  bool
+	}{
+		{obj: Obj{A: 1, B: "2", C: true}, expected: []interface{}{"2", 2.0, false}},
+		{obj: &Obj{A: 2, B: "3", C: true}, expected: []interface{}{"3", 4.0, true}},
+		{
+			obj: struct {
+				AA int
+				B  string
+				C  bool
+			}{AA: 1, B: "2", C: true},
+			withErr: true,
+		},
+	}
+
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			actual, err := format.ValuesOf(c.obj)
+			require.True(t, c.withErr == (err != nil), err)
+			require.Equal(t, c.expected, actual)
+		})
+	}
+}
+
+func Test_FormatText(t *testing.T) {
+	loc := p24.NewKievLocation()
+	statement := p24.Statement{
+		TranDate:   time.Date(2022, 3, 15, 10, 30, 0, 0, loc),
+		CardAmount: p24.Funds{Currency: "UAH", Amount: -29550},
+		Rest:       p24.Funds{Currency: "UAH", Amount: 100000},
+	}
+
+	cases := []struct {
+		str      string
+		locale   string
+		expected []string
+		withErr  bool
+	}{
+		{
+			str:      "TranDate{02.01.2006 15:04}|CardAmount{decimal=,}|Rest",
+			expected: []string{"15.03.2022 10:30", "-295,50 UAH", "1000 UAH"},
+		},
+		{
+			str:      "TranDate{layout=2006-01-02T15:04;tz=UTC}|CardAmount{precision=1}|Rest{precision=2}",
+			expected: []string{"2022-03-15T08:30", "-295.5 UAH", "1000.00 UAH"},
+		},
+		{
+			str:      "TranDate|CardAmount|Abs=abs(CardAmount){decimal=.}",
+			locale:   "uk",
+			expected: []string{"15.03.2022 10:30:00", "-295,50 UAH", "295.5"},
+		},
+		{str: "TranDate{tz=Unknown/Zone}", withErr: true},
+		{str: "CardAmount{precision=-1}", withErr: true},
+		{str: "CardAmount{unknown=1}", withErr: true},
+	}
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			f, err := MakeFormat(c.str, DefaultFormatParser(p24.Statement{}))
+			require.True(t, c.withErr == (err != nil), err)
+			if err != nil {
+				return
+			}
+			f.Locale, err = LocaleByName(c.locale)
+			require.NoError(t, err)
+
+			values, err := f.ValuesOf(&statement)
+			require.NoError(t, err)
+			actual := make([]string, len(values))
+			for k := range values {
+				actual[k] = f.Text(f.Fields[k], values[k])
+			}
+			require.Equal(t, c.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/dimboknv/p24"
+	"github.com/pkg/errors"
+)
+
+// jsonExporter export rows as json array of objects with format fields in order
+type jsonExporter struct {
+	rows Rows
+}
+
+// NewJSON returns new json exporter of statements
+func NewJSON(statements p24.Statements) Exporter {
+	return NewJSONRows(StatementsRows(statements))
+}
+
+// NewJSONRows returns new json exporter of rows
+func NewJSONRows(rows Rows) Exporter {
+	return &jsonExporter{rows: rows}
+}
+
+// Export rows to w writer as json with given f Format. Values are the same as xlsx cells:
+// numbers are numeric, times are RFC 3339 strings or formatted by layout, funds are text
+func (ex *jsonExporter) Export(w io.Writer, f Format) error {
+	buff := bytes.NewBuffer([]byte{})
+	if err := ex.encode(buff, f); err != nil {
+		return errors.Wrap(err, "encode failed")
+	}
+
+	if _, err := w.Write(buff.Bytes()); err != nil {
+		return errors.Wrap(err, "failed to write encoded data")
+	}
+	return nil
+}
+
+func (ex *jsonExporter) encode(buff *bytes.Buffer, f Format) error {
+	buff.WriteString("[")
+	for i, item := range ex.rows.Items {
+		values, err := f.ValuesOf(item)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			buff.WriteString(",")
+		}
+		buff.WriteString("\n  {")
+		for k := range values {
+			if k > 0 {
+				buff.WriteString(", ")
+			}
+			key, _ := json.Marshal(f.Fields[k])
+			value, err := json.Marshal(f.Cell(f.Fields[k], values[k]))
+			if err != nil {
+				return errors.Wrapf(err, "field %q", f.Fields[k])
+			}
+			buff.Write(key)
+			buff.WriteString(": ")
+			buff.Write(value)
+		}
+		buff.WriteString("}")
+	}
+	if len(ex.rows.Items) > 0 {
+		buff.WriteString("\n")
+	}
+	buff.WriteString("]\n")
+	return nil
+}
@@ -0,0 +1,26 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_JSON(t *testing.T) {
+	statements := p24.Statements{Statements: []p24.Statement{
+		{Appcode: "1", TranDate: time.Date(2022, 1, 2, 10, 0, 0, 0, time.UTC), CardAmount: p24.Funds{Currency: "UAH", Amount: -150}},
+	}}
+	format, err := MakeFormat("Appcode|TranDate{2006-01-02}|CardAmount|Value=CardAmount.Amount", DefaultFormatParser(p24.Statement{}))
+	require.NoError(t, err)
+
+	buff := bytes.NewBuffer(nil)
+	require.NoError(t, NewJSON(statements).Export(buff, format))
+	require.Equal(t, "[\n  {\"Appcode\": \"1\", \"TranDate\": \"2022-01-02\", \"CardAmount\": \"-1.50 UAH\", \"Value\": -1.5}\n]\n", buff.String())
+
+	buff.Reset()
+	require.NoError(t, NewJSON(p24.Statements{}).Export(buff, format))
+	require.Equal(t, "[]\n", buff.String())
+}
@@ -0,0 +1,210 @@
+package export
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/pkg/errors"
+)
+
+// Locale defines default representation of exported values
+type Locale struct {
+	Location   *time.Location // nil keeps values time zone
+	Name       string
+	TimeLayout string // empty keeps time.Time String() representation
+	Decimal    string // empty means "."
+}
+
+// Locales is a list of supported locales by name
+var Locales = map[string]Locale{
+	"en": {Name: "en", TimeLayout: "2006-01-02 15:04:05", Decimal: ".", Location: p24.NewKievLocation()},
+	"uk": {Name: "uk", TimeLayout: "02.01.2006 15:04:05", Decimal: ",", Location: p24.NewKievLocation()},
+}
+
+// LocaleByName returns Locale from Locales. Empty name is a default locale
+func LocaleByName(name string) (Locale, error) {
+	if name == "" {
+		return Locale{}, nil
+	}
+	l, ok := Locales[name]
+	if !ok {
+		return Locale{}, errors.Errorf("unknown locale %q", name)
+	}
+	return l, nil
+}
+
+// Modifier is a per-field output modifier, e.g. "TranDate{02.01.2006;tz=UTC}" or "CardAmount{decimal=,}".
+// Overrides Locale settings for the field
+type Modifier struct {
+	Location  *time.Location
+	Layout    string
+	Decimal   string
+	Precision int // -1 is the smallest number of digits necessary
+}
+
+// parseModifier parses modifier string with ';' separated items:
+// - "layout=<go time layout>" or just "<go time layout>"
+// - "tz=<IANA time zone>"
+// - "decimal=<decimal separator>"
+// - "precision=<number of digits after the decimal point>"
+func parseModifier(str string) (Modifier, error) {
+	m := Modifier{Precision: -1}
+	for _, item := range strings.Split(str, ";") {
+		key, value := "layout", item
+		if i := strings.Index(item, "="); i != -1 {
+			key, value = strings.TrimSpace(item[:i]), item[i+1:]
+		}
+
+		switch key {
+		case "layout":
+			m.Layout = value
+		case "tz":
+			loc, err := loadLocation(value)
+			if err != nil {
+				return Modifier{}, errors.Wrapf(err, "invalid time zone %q", value)
+			}
+			m.Location = loc
+		case "decimal":
+			if value == "" {
+				return Modifier{}, errors.New("empty decimal separator")
+			}
+			m.Decimal = value
+		case "precision":
+			p, err := strconv.Atoi(value)
+			if err != nil || p < 0 {
+				return Modifier{}, errors.Errorf("invalid precision %q", value)
+			}
+			m.Precision = p
+		default:
+			return Modifier{}, errors.Errorf("unknown modifier %q", key)
+		}
+	}
+	return m, nil
+}
+
+// splitModifier splits field str to field and modifier if field has "{...}" suffix
+func splitModifier(str string) (field string, mod *Modifier, err error) {
+	i := strings.LastIndex(str, "{")
+	if i == -1 || !strings.HasSuffix(str, "}") {
+		return str, nil, nil
+	}
+	m, err := parseModifier(str[i+1 : len(str)-1])
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "invalid %q modifier", str)
+	}
+	return str[:i], &m, nil
+}
+
+func loadLocation(name string) (*time.Location, error) {
+	switch name {
+	case "Europe/Kyiv", "Europe/Kiev", "Kyiv", "Kiev":
+		return p24.NewKievLocation(), nil
+	default:
+		return time.LoadLocation(name)
+	}
+}
+
+// modifier returns effective Modifier for the field merged with f.Locale
+func (f *Format) modifier(field string) Modifier {
+	m := Modifier{
+		Location:  f.Locale.Location,
+		Layout:    f.Locale.TimeLayout,
+		Decimal:   f.Locale.Decimal,
+		Precision: -1,
+	}
+	if fm, ok := f.Mods[field]; ok {
+		if fm.Location != nil {
+			m.Location = fm.Location
+		}
+		if fm.Layout != "" {
+			m.Layout = fm.Layout
+		}
+		if fm.Decimal != "" {
+			m.Decimal = fm.Decimal
+		}
+		m.Precision = fm.Precision
+	}
+	return m
+}
+
+// Text returns string representation of the field value
+// with respect to the field Modifier and f.Locale
+func (f *Format) Text(field string, value interface{}) string {
+	m := f.modifier(field)
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		if m.Location != nil {
+			v = v.In(m.Location)
+		}
+		if m.Layout == "" {
+			return v.String()
+		}
+		return v.Format(m.Layout)
+	case p24.Funds:
+		return fmt.Sprintf("%s %s", m.number(v.Amount), v.Currency)
+	case p24.Amount, float64, float32:
+		return m.number(v)
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Cell returns value of the field for spreadsheet cell with respect to the field Modifier and f.Locale.
+// Numbers are kept numeric. Times are converted to the time zone and formatted if layout is set
+func (f *Format) Cell(field string, value interface{}) interface{} {
+	m := f.modifier(field)
+	switch v := value.(type) {
+	case time.Time:
+		if m.Location != nil {
+			v = v.In(m.Location)
+		}
+		if m.Layout != "" {
+			return v.Format(m.Layout)
+		}
+		return v
+	case p24.Amount:
+		return m.round(v.Float64())
+	case float64:
+		return m.round(v)
+	case p24.Funds: // computed and virtual fields of funds are single column
+		return f.Text(field, v)
+	default:
+		return value
+	}
+}
+
+func (m Modifier) number(v interface{}) string {
+	var str string
+	switch n := v.(type) {
+	case p24.Amount:
+		str = n.String()
+		if m.Precision >= 0 {
+			str = strconv.FormatFloat(n.Float64(), 'f', m.Precision, 64)
+		}
+	case float64:
+		str = strconv.FormatFloat(n, 'f', m.Precision, 64)
+	case float32:
+		str = strconv.FormatFloat(float64(n), 'f', m.Precision, 32)
+	}
+	if m.Decimal != "" && m.Decimal != "." {
+		str = strings.Replace(str, ".", m.Decimal, 1)
+	}
+	return str
+}
+
+func (m Modifier) round(v float64) float64 {
+	if m.Precision < 0 {
+		return v
+	}
+	f, _ := strconv.ParseFloat(strconv.FormatFloat(v, 'f', m.Precision, 64), 64)
+	return f
+}
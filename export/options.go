@@ -0,0 +1,68 @@
+package export
+
+// Option func type. Each exporter uses only options it supports
+type Option func(o *options)
+
+type options struct {
+	charts    []Chart
+	dropdowns map[string][]string
+	sheet     string
+	title     string
+	noHeader  bool
+}
+
+func makeOptions(opts []Option) options {
+	o := options{sheet: "Sheet1"}
+	for _, f := range opts {
+		f(&o)
+	}
+	return o
+}
+
+// WithSheet sets xlsx sheet name, "Sheet1" by default
+func WithSheet(name string) Option {
+	return func(o *options) {
+		if name != "" {
+			o.sheet = name
+		}
+	}
+}
+
+// WithTitle sets pdf title above the table
+func WithTitle(title string) Option {
+	return func(o *options) {
+		o.title = title
+	}
+}
+
+// WithoutHeader disables csv header row
+func WithoutHeader() Option {
+	return func(o *options) {
+		o.noHeader = true
+	}
+}
+
+// WithDropdown sets xlsx data validation drop-down list of values for field column.
+// Values are written to hidden "Lists" sheet, so the list length is unlimited
+func WithDropdown(field string, values []string) Option {
+	return func(o *options) {
+		if o.dropdowns == nil {
+			o.dropdowns = map[string][]string{}
+		}
+		o.dropdowns[field] = values
+	}
+}
+
+// Chart is a xlsx line chart of Y fields values by X field values of exported rows
+type Chart struct {
+	Title string
+	X     string
+	Y     []string
+}
+
+// WithChart adds xlsx line chart next to the rows table. Fields missing in export format are skipped
+func WithChart(c Chart) Option {
+	return func(o *options) {
+		o.charts = append(o.charts, c)
+	}
+}
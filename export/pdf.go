@@ -0,0 +1,390 @@
+package export
+
+import (
+	"bytes"
+	"compress/zlib"
+	_ "embed" // embedded font of pdf exports
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dimboknv/p24"
+	"github.com/pkg/errors"
+)
+
+// A4 landscape page layout in points
+const (
+	pdfPageWidth   = 842
+	pdfPageHeight  = 595
+	pdfMargin      = 36
+	pdfPadding     = 3 // horizontal padding of cells
+	pdfFontSize    = 8
+	pdfMinFontSize = 5
+	pdfTitleSize   = 12
+	pdfLineHeight  = 1.6 // row height in font sizes
+)
+
+// goRegular is Go Regular TrueType font with Cyrillic glyphs, see fonts/LICENSE
+//
+//go:embed fonts/Go-Regular.ttf
+var goRegular []byte
+
+var (
+	pdfFontOnce sync.Once
+	pdfFont     *ttfFont
+	pdfFontErr  error
+)
+
+// pdfExporter export rows as a table of A4 landscape pages of pdf document with embedded font
+type pdfExporter struct {
+	rows Rows
+	opts options
+}
+
+// pdfCell is a text of a table cell
+type pdfCell struct {
+	text  string
+	right bool // numbers are right aligned
+}
+
+// NewPDF returns new pdf exporter of statements
+func NewPDF(statements p24.Statements, opts ...Option) Exporter {
+	return NewPDFRows(StatementsRows(statements), opts...)
+}
+
+// NewPDFRows returns new pdf exporter of rows. Format delim is not used.
+// Columns are shrunk to the page width, the header row is repeated on each page
+func NewPDFRows(rows Rows, opts ...Option) Exporter {
+	return &pdfExporter{rows: rows, opts: makeOptions(opts)}
+}
+
+// Export rows to w writer as pdf with given f Format
+func (ex *pdfExporter) Export(w io.Writer, f Format) error {
+	pdfFontOnce.Do(func() { pdfFont, pdfFontErr = parseTTF(goRegular) })
+	if pdfFontErr != nil {
+		return errors.Wrap(pdfFontErr, "failed to load font")
+	}
+
+	// encode to temporary buffer for prevent incomplete write
+	data, err := ex.encode(pdfFont, f)
+	if err != nil {
+		return errors.Wrap(err, "encode failed")
+	}
+	if _, err := w.Write(data); err != nil {
+		return errors.Wrap(err, "failed to write encoded data")
+	}
+	return nil
+}
+
+func (ex *pdfExporter) encode(font *ttfFont, f Format) ([]byte, error) {
+	var header []pdfCell
+	if !ex.opts.noHeader {
+		for _, field := range f.Fields {
+			header = append(header, pdfCell{text: field})
+		}
+	}
+	rows := make([][]pdfCell, 0, len(ex.rows.Items))
+	for _, item := range ex.rows.Items {
+		values, err := f.ValuesOf(item)
+		if err != nil {
+			return nil, err
+		}
+		cells := make([]pdfCell, len(values))
+		for k := range values {
+			text := strings.Join(strings.Fields(f.Text(f.Fields[k], values[k])), " ")
+			cells[k] = pdfCell{text: text, right: isNumber(values[k])}
+		}
+		rows = append(rows, cells)
+	}
+
+	size, widths := pdfColumns(font, len(f.Fields), append([][]pdfCell{header}, rows...))
+	doc := &pdfDocument{font: font, glyphs: map[uint16]rune{}}
+	lineHeight := size * pdfLineHeight
+	top := float64(pdfPageHeight - pdfMargin)
+	y := top
+	newPage := func() {
+		doc.pages = append(doc.pages, &bytes.Buffer{})
+		y = top
+		if len(doc.pages) == 1 && ex.opts.title != "" {
+			y -= pdfTitleSize
+			doc.text(pdfMargin, y, pdfTitleSize, ex.opts.title)
+			y -= pdfTitleSize
+		}
+		if header != nil {
+			y -= lineHeight
+			fmt.Fprintf(doc.page(), "0.9 g %.2f %.2f %.2f %.2f re f 0 g\n", float64(pdfMargin), y, sum(widths), lineHeight)
+			doc.row(y, size, widths, header)
+		}
+	}
+	newPage()
+	for _, cells := range rows {
+		if y-lineHeight < pdfMargin {
+			newPage()
+		}
+		y -= lineHeight
+		doc.row(y, size, widths, cells)
+		fmt.Fprintf(doc.page(), "0.8 G 0.3 w %d %.2f m %.2f %.2f l S\n", pdfMargin, y, pdfMargin+sum(widths), y)
+	}
+	for i := range doc.pages {
+		footer := fmt.Sprintf("%d / %d", i+1, len(doc.pages))
+		x := pdfPageWidth - pdfMargin - font.width(footer, pdfMinFontSize)
+		doc.textOn(doc.pages[i], x, pdfMargin/2, pdfMinFontSize, footer)
+	}
+	return doc.bytes()
+}
+
+// pdfColumns returns font size and columns widths of rows fitting the page width.
+// The font size is decreased down to pdfMinFontSize first, then the widest columns are narrowed
+func pdfColumns(font *ttfFont, n int, rows [][]pdfCell) (size float64, widths []float64) {
+	natural := make([]float64, n) // at font size 1
+	for _, cells := range rows {
+		for k := 0; k < n && k < len(cells); k++ {
+			if w := font.width(cells[k].text, 1); w > natural[k] {
+				natural[k] = w
+			}
+		}
+	}
+	available := float64(pdfPageWidth - 2*pdfMargin)
+	textWidth := func(size float64) float64 {
+		return sum(natural)*size + float64(2*pdfPadding*n)
+	}
+
+	size = pdfFontSize
+	if w := textWidth(size); w > available {
+		// rounded down to fit despite of float errors
+		size = math.Floor(10*size*(available-float64(2*pdfPadding*n))/(w-float64(2*pdfPadding*n))) / 10
+		if size < pdfMinFontSize {
+			size = pdfMinFontSize
+		}
+	}
+	widths = make([]float64, n)
+	for k := range widths {
+		widths[k] = natural[k]*size + 2*pdfPadding
+	}
+	if sum(widths) <= available {
+		return size, widths
+	}
+
+	// narrow columns wider than limit, the limit is found by the sorted widths
+	sorted := append([]float64(nil), widths...)
+	sort.Float64s(sorted)
+	limit, rest := available/float64(n), available
+	for i, w := range sorted {
+		if w*float64(n-i) > rest {
+			limit = rest / float64(n-i)
+			break
+		}
+		rest -= w
+	}
+	for k := range widths {
+		if widths[k] > limit {
+			widths[k] = limit
+		}
+	}
+	return size, widths
+}
+
+// pdfDocument is pdf pages contents with text of the embedded font
+type pdfDocument struct {
+	font   *ttfFont
+	pages  []*bytes.Buffer
+	glyphs map[uint16]rune // used glyphs and their runes
+}
+
+func (d *pdfDocument) page() *bytes.Buffer {
+	return d.pages[len(d.pages)-1]
+}
+
+// row writes cells of a row with y baseline of the bottom border, texts are truncated by columns widths
+func (d *pdfDocument) row(y, size float64, widths []float64, cells []pdfCell) {
+	x := float64(pdfMargin)
+	// vertically centered text box of ascent and descent
+	baseline := y + size*pdfLineHeight/2 - size*float64(d.font.ascent+d.font.descent)/float64(d.font.unitsPerEm)/2
+	for k, w := range widths {
+		if k < len(cells) && cells[k].text != "" {
+			text := d.truncate(cells[k].text, size, w-2*pdfPadding)
+			left := x + pdfPadding
+			if cells[k].right {
+				left = x + w - pdfPadding - d.font.width(text, size)
+			}
+			d.text(left, baseline, size, text)
+		}
+		x += w
+	}
+}
+
+// truncate returns text shortened with ellipsis to fit width
+func (d *pdfDocument) truncate(text string, size, width float64) string {
+	if d.font.width(text, size) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 && d.font.width(string(runes)+"…", size) > width {
+		runes = runes[:len(runes)-1]
+	}
+	if len(runes) == 0 {
+		return ""
+	}
+	return string(runes) + "…"
+}
+
+func (d *pdfDocument) text(x, y, size float64, text string) {
+	d.textOn(d.page(), x, y, size, text)
+}
+
+func (d *pdfDocument) textOn(page *bytes.Buffer, x, y, size float64, text string) {
+	var hex strings.Builder
+	for _, r := range text {
+		g := d.font.glyph(r)
+		if _, ok := d.font.glyphs[r]; !ok {
+			r = '?'
+		}
+		if _, ok := d.glyphs[g]; !ok {
+			d.glyphs[g] = r
+		}
+		fmt.Fprintf(&hex, "%04X", g)
+	}
+	fmt.Fprintf(page, "BT /F1 %.2f Tf %.2f %.2f Td <%s> Tj ET\n", size, x, y, hex.String())
+}
+
+// bytes returns pdf document with Type0 font of Identity-H encoding, its glyphs are CIDs of the embedded font
+func (d *pdfDocument) bytes() ([]byte, error) {
+	const firstPage = 8 // objects of a page and its content follow catalog, pages and font objects
+	w := &pdfWriter{}
+	w.buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	w.object(1, "<< /Type /Catalog /Pages 2 0 R >>")
+	w.object(2, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	w.object(3, "<< /Type /Font /Subtype /Type0 /BaseFont /GoRegular /Encoding /Identity-H /DescendantFonts [4 0 R] /ToUnicode 7 0 R >>")
+
+	used := make([]int, 0, len(d.glyphs))
+	for g := range d.glyphs {
+		used = append(used, int(g))
+	}
+	sort.Ints(used)
+	var widths, unicode strings.Builder
+	for i, g := range used {
+		fmt.Fprintf(&widths, "%d [%d] ", g, d.font.scale(d.font.advances[g]))
+		if i%100 == 0 {
+			if i > 0 {
+				unicode.WriteString("endbfchar\n")
+			}
+			fmt.Fprintf(&unicode, "%d beginbfchar\n", minInt(100, len(used)-i))
+		}
+		fmt.Fprintf(&unicode, "<%04X> <%04X>\n", g, d.glyphs[uint16(g)])
+	}
+	if len(used) > 0 {
+		unicode.WriteString("endbfchar\n")
+	}
+	w.object(4, fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /GoRegular "+
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor 5 0 R "+
+		"/DW %d /W [%s] /CIDToGIDMap /Identity >>", d.font.scale(d.font.advances[0]), strings.TrimSpace(widths.String())))
+
+	f := d.font
+	w.object(5, fmt.Sprintf("<< /Type /FontDescriptor /FontName /GoRegular /Flags 32 /FontBBox [%d %d %d %d] "+
+		"/ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 6 0 R >>",
+		f.scale(f.bbox[0]), f.scale(f.bbox[1]), f.scale(f.bbox[2]), f.scale(f.bbox[3]),
+		f.scale(f.ascent), f.scale(f.descent), f.scale(f.capHeight)))
+	if err := w.stream(6, fmt.Sprintf("/Length1 %d", len(f.data)), f.data); err != nil {
+		return nil, err
+	}
+	cmap := "/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n" + unicode.String() +
+		"endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n"
+	if err := w.stream(7, "", []byte(cmap)); err != nil {
+		return nil, err
+	}
+
+	for i, page := range d.pages {
+		num := firstPage + 2*i
+		w.object(num, fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] "+
+			"/Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", pdfPageWidth, pdfPageHeight, num+1))
+		if err := w.stream(num+1, "", page.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+	return w.finish(), nil
+}
+
+// pdfWriter writes pdf objects and cross-reference table of their offsets
+type pdfWriter struct {
+	buf     bytes.Buffer
+	offsets map[int]int // by object number
+}
+
+func (w *pdfWriter) object(num int, body string) {
+	if w.offsets == nil {
+		w.offsets = map[int]int{}
+	}
+	w.offsets[num] = w.buf.Len()
+	fmt.Fprintf(&w.buf, "%d 0 obj\n%s\nendobj\n", num, body)
+}
+
+// stream writes flate compressed stream object with extra dictionary entries
+func (w *pdfWriter) stream(num int, dict string, data []byte) error {
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	if _, err := zw.Write(data); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	if dict != "" {
+		dict = " " + dict
+	}
+	w.object(num, fmt.Sprintf("<< /Length %d /Filter /FlateDecode%s >>\nstream\n%s\nendstream", compressed.Len(), dict, compressed.Bytes()))
+	return nil
+}
+
+func (w *pdfWriter) finish() []byte {
+	size := 0
+	for num := range w.offsets {
+		if num >= size {
+			size = num + 1
+		}
+	}
+	xref := w.buf.Len()
+	fmt.Fprintf(&w.buf, "xref\n0 %d\n0000000000 65535 f \n", size)
+	for num := 1; num < size; num++ {
+		if off, ok := w.offsets[num]; ok {
+			fmt.Fprintf(&w.buf, "%010d 00000 n \n", off)
+		} else {
+			w.buf.WriteString("0000000000 65535 f \n")
+		}
+	}
+	fmt.Fprintf(&w.buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", size, xref)
+	return w.buf.Bytes()
+}
+
+// isNumber returns true for numeric values and funds
+func isNumber(v interface{}) bool {
+	if _, ok := v.(p24.Funds); ok {
+		return true
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+func sum(values []float64) float64 {
+	s := 0.0
+	for _, v := range values {
+		s += v
+	}
+	return s
+}
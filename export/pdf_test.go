@@ -0,0 +1,99 @@
+package export
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/dimboknv/p24"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_PDF(t *testing.T) {
+	var statements p24.Statements
+	for i := 0; i < 100; i++ {
+		statements.Statements = append(statements.Statements, p24.Statement{
+			Appcode:     strconv.Itoa(i),
+			CardAmount:  p24.Funds{Currency: "UAH", Amount: p24.Amount(-100 * i)},
+			Description: "Оплата послуг «Київстар» №" + strconv.Itoa(i),
+		})
+	}
+	format, err := MakeFormat("Appcode|CardAmount|Description", DefaultFormatParser(p24.Statement{}))
+	require.NoError(t, err)
+
+	buff := bytes.NewBuffer(nil)
+	require.NoError(t, NewPDF(statements, WithTitle("Statements")).Export(buff, format))
+	data := buff.Bytes()
+	require.True(t, bytes.HasPrefix(data, []byte("%PDF-1.4\n")))
+	require.True(t, bytes.HasSuffix(data, []byte("%%EOF\n")))
+	require.Contains(t, string(data), "/Count 3 ")
+
+	// cross-reference offsets point to objects
+	xref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(data)
+	require.NotNil(t, xref)
+	start, err := strconv.Atoi(string(xref[1]))
+	require.NoError(t, err)
+	offsets := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(data[start:], -1)
+	require.Len(t, offsets, 13)
+	for i, off := range offsets {
+		n, err := strconv.Atoi(string(off[1]))
+		require.NoError(t, err)
+		require.True(t, bytes.HasPrefix(data[n:], []byte(fmt.Sprintf("%d 0 obj\n", i+1))), "object %d", i+1)
+	}
+
+	// the ToUnicode cmap maps glyphs to Cyrillic runes
+	font, err := parseTTF(goRegular)
+	require.NoError(t, err)
+	require.NotZero(t, font.glyphs['ї'])
+	cmap := stream(t, data, 7)
+	require.Contains(t, cmap, fmt.Sprintf("<%04X> <0457>", font.glyph('ї')))
+	require.Contains(t, cmap, fmt.Sprintf("<%04X> <00AB>", font.glyph('«')))
+}
+
+func Test_PDFColumns(t *testing.T) {
+	font, err := parseTTF(goRegular)
+	require.NoError(t, err)
+	long := string(bytes.Repeat([]byte("long description "), 40))
+
+	cases := []struct {
+		rows   [][]pdfCell
+		size   float64 // zero if the font size is between the default and the minimal
+		narrow bool
+	}{
+		{rows: [][]pdfCell{{{text: "a"}, {text: "b"}}}, size: pdfFontSize},
+		{rows: [][]pdfCell{{{text: "a"}, {text: long[:300]}}}},
+		{rows: [][]pdfCell{{{text: "a"}, {text: long}}}, size: pdfMinFontSize, narrow: true},
+	}
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			size, widths := pdfColumns(font, 2, c.rows)
+			require.Len(t, widths, 2)
+			require.LessOrEqual(t, sum(widths), float64(pdfPageWidth-2*pdfMargin)+0.01)
+			require.InDelta(t, font.width("a", size)+2*pdfPadding, widths[0], 0.01, "narrow column isn't shrunk")
+			require.Equal(t, c.narrow, font.width(c.rows[0][1].text, size)+2*pdfPadding > widths[1])
+			if c.size != 0 {
+				require.Equal(t, c.size, size)
+				return
+			}
+			require.Less(t, size, float64(pdfFontSize))
+			require.Greater(t, size, float64(pdfMinFontSize))
+		})
+	}
+}
+
+// stream returns decompressed stream of num pdf object
+func stream(t *testing.T, data []byte, num int) string {
+	t.Helper()
+	obj := regexp.MustCompile(fmt.Sprintf(`(?s)\n%d 0 obj\n[^\n]*\nstream\n(.*?)\nendstream`, num)).FindSubmatch(data)
+	require.NotNil(t, obj)
+	r, err := zlib.NewReader(bytes.NewReader(obj[1]))
+	require.NoError(t, err)
+	text, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(text)
+}
@@ -0,0 +1,42 @@
+package export
+
+import "github.com/dimboknv/p24"
+
+// Rows is a list of exported structs with xml element names and top level attributes
+type Rows struct {
+	Root  string        // xml top level element name
+	Elem  string        // xml row element name
+	Attrs []Attr        // xml top level element attributes
+	Items []interface{} // structs or pointers to structs of the same type
+	Extra []Elem        // xml elements after rows, e.g. totals per currency. Other encodings skip them
+}
+
+// Elem is an extra xml element of exported rows
+type Elem struct {
+	Name  string
+	Attrs []Attr
+}
+
+// Attr is a top level attribute of exported rows, Value is formatted by Format.Text
+type Attr struct {
+	Name  string
+	Value interface{}
+}
+
+// StatementsRows returns rows of statements list with status, credit and debet attributes
+func StatementsRows(statements p24.Statements) Rows {
+	items := make([]interface{}, len(statements.Statements))
+	for i := range statements.Statements {
+		items[i] = &statements.Statements[i]
+	}
+	return Rows{
+		Root: "statements",
+		Elem: "statement",
+		Attrs: []Attr{
+			{Name: "status", Value: statements.Status},
+			{Name: "credit", Value: statements.Credit},
+			{Name: "debet", Value: statements.Debet},
+		},
+		Items: items,
+	}
+}
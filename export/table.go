@@ -0,0 +1,74 @@
+package export
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dimboknv/p24"
+	"github.com/pkg/errors"
+)
+
+// tableExporter export rows as aligned text table for terminal
+type tableExporter struct {
+	rows Rows
+	opts options
+}
+
+// NewTable returns new table exporter of statements
+func NewTable(statements p24.Statements, opts ...Option) Exporter {
+	return NewTableRows(StatementsRows(statements), opts...)
+}
+
+// NewTableRows returns new table exporter of rows. Format delim is not used
+func NewTableRows(rows Rows, opts ...Option) Exporter {
+	return &tableExporter{rows: rows, opts: makeOptions(opts)}
+}
+
+// Export rows to w writer as text table with given f Format
+func (ex *tableExporter) Export(w io.Writer, f Format) error {
+	// encode to temporary buffer for prevent incomplete write
+	buff := bytes.NewBuffer([]byte{})
+	tw := tabwriter.NewWriter(buff, 0, 0, 2, ' ', 0)
+	if err := ex.encode(tw, f); err != nil {
+		return errors.Wrap(err, "encode failed")
+	}
+
+	if _, err := w.Write(buff.Bytes()); err != nil {
+		return errors.Wrap(err, "failed to write encoded data")
+	}
+	return nil
+}
+
+func (ex *tableExporter) encode(tw *tabwriter.Writer, f Format) error {
+	write := func(cells []string) error {
+		// tabs and new lines of values break table layout
+		for i := range cells {
+			cells[i] = strings.NewReplacer("\t", " ", "\n", " ").Replace(cells[i])
+		}
+		_, err := io.WriteString(tw, strings.Join(cells, "\t")+"\n")
+		return err
+	}
+
+	if !ex.opts.noHeader {
+		if err := write(append([]string(nil), f.Fields...)); err != nil {
+			return err
+		}
+	}
+
+	for _, item := range ex.rows.Items {
+		values, err := f.ValuesOf(item)
+		if err != nil {
+			return err
+		}
+		cells := make([]string, len(values))
+		for k := range values {
+			cells[k] = f.Text(f.Fields[k], values[k])
+		}
+		if err := write(cells); err != nil {
+			return err
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,163 @@
+package export
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+)
+
+// ttfFont is a TrueType font metrics and unicode to glyph mapping needed to embed it to pdf
+type ttfFont struct {
+	data       []byte
+	unitsPerEm int
+	bbox       [4]int // xMin, yMin, xMax, yMax
+	ascent     int
+	descent    int
+	capHeight  int
+	advances   []int // advance widths by glyph
+	glyphs     map[rune]uint16
+}
+
+// parseTTF parses head, hhea, maxp, hmtx, OS/2 and format 4 unicode cmap tables of TrueType font data
+func parseTTF(data []byte) (*ttfFont, error) {
+	if len(data) < 12 {
+		return nil, errors.New("font is too short")
+	}
+	tables := map[string][]byte{}
+	for i, n := 0, int(binary.BigEndian.Uint16(data[4:])); i < n; i++ {
+		rec := 12 + 16*i
+		if rec+16 > len(data) {
+			return nil, errors.New("invalid tables directory")
+		}
+		off, size := int(binary.BigEndian.Uint32(data[rec+8:])), int(binary.BigEndian.Uint32(data[rec+12:]))
+		if off+size > len(data) {
+			return nil, errors.Errorf("table %q is out of font data", data[rec:rec+4])
+		}
+		tables[string(data[rec:rec+4])] = data[off : off+size]
+	}
+	for _, name := range []string{"head", "hhea", "maxp", "hmtx", "cmap"} {
+		if len(tables[name]) == 0 {
+			return nil, errors.Errorf("%q table is required", name)
+		}
+	}
+
+	head, hhea, maxp, hmtx := tables["head"], tables["hhea"], tables["maxp"], tables["hmtx"]
+	if len(head) < 54 || len(hhea) < 36 || len(maxp) < 6 {
+		return nil, errors.New("invalid head, hhea or maxp table")
+	}
+	f := &ttfFont{
+		data:       data,
+		unitsPerEm: int(binary.BigEndian.Uint16(head[18:])),
+		ascent:     int(int16(binary.BigEndian.Uint16(hhea[4:]))),
+		descent:    int(int16(binary.BigEndian.Uint16(hhea[6:]))),
+	}
+	for i := range f.bbox {
+		f.bbox[i] = int(int16(binary.BigEndian.Uint16(head[36+2*i:])))
+	}
+	if f.unitsPerEm == 0 {
+		return nil, errors.New("units per em are zero")
+	}
+	f.capHeight = f.ascent
+	if os2 := tables["OS/2"]; len(os2) >= 90 && binary.BigEndian.Uint16(os2) >= 2 {
+		f.capHeight = int(int16(binary.BigEndian.Uint16(os2[88:])))
+	}
+
+	// glyphs after the last horizontal metric have its advance width
+	metrics, numGlyphs := int(binary.BigEndian.Uint16(hhea[34:])), int(binary.BigEndian.Uint16(maxp[4:]))
+	if metrics == 0 || len(hmtx) < 4*metrics {
+		return nil, errors.New("invalid hmtx table")
+	}
+	f.advances = make([]int, numGlyphs)
+	for g := range f.advances {
+		f.advances[g] = int(binary.BigEndian.Uint16(hmtx[4*minInt(g, metrics-1):]))
+	}
+
+	var err error
+	if f.glyphs, err = parseCmap(tables["cmap"]); err != nil {
+		return nil, errors.Wrap(err, "invalid cmap table")
+	}
+	return f, nil
+}
+
+// parseCmap returns glyphs of format 4 windows unicode BMP or unicode platform subtable
+func parseCmap(cmap []byte) (map[rune]uint16, error) {
+	if len(cmap) < 4 {
+		return nil, errors.New("cmap is too short")
+	}
+	var sub []byte
+	for i, n := 0, int(binary.BigEndian.Uint16(cmap[2:])); i < n && 4+8*i+8 <= len(cmap); i++ {
+		rec := cmap[4+8*i:]
+		platform, encoding := binary.BigEndian.Uint16(rec), binary.BigEndian.Uint16(rec[2:])
+		off := int(binary.BigEndian.Uint32(rec[4:]))
+		if off+4 > len(cmap) || binary.BigEndian.Uint16(cmap[off:]) != 4 {
+			continue
+		}
+		if (platform == 3 && encoding == 1) || (platform == 0 && sub == nil) {
+			sub = cmap[off:]
+		}
+	}
+	if len(sub) < 14 {
+		return nil, errors.New("there is no format 4 unicode subtable")
+	}
+
+	segX2 := int(binary.BigEndian.Uint16(sub[6:]))
+	ends, starts := 14, 16+segX2
+	deltas, ranges := starts+segX2, starts+2*segX2
+	if ranges+segX2 > len(sub) {
+		return nil, errors.New("format 4 subtable is too short")
+	}
+	u16 := func(off int) int {
+		if off+2 > len(sub) {
+			return 0
+		}
+		return int(binary.BigEndian.Uint16(sub[off:]))
+	}
+
+	glyphs := map[rune]uint16{}
+	for s := 0; s < segX2; s += 2 {
+		start, end, delta, rangeOff := u16(starts+s), u16(ends+s), u16(deltas+s), u16(ranges+s)
+		for c := start; c <= end && c != 0xFFFF; c++ {
+			g := c + delta
+			if rangeOff != 0 {
+				if g = u16(ranges + s + rangeOff + 2*(c-start)); g != 0 {
+					g += delta
+				}
+			}
+			if g &= 0xFFFF; g != 0 {
+				glyphs[rune(c)] = uint16(g)
+			}
+		}
+	}
+	return glyphs, nil
+}
+
+// glyph returns glyph of r, the glyph of '?' if the font has no r glyph
+func (f *ttfFont) glyph(r rune) uint16 {
+	if g, ok := f.glyphs[r]; ok {
+		return g
+	}
+	return f.glyphs['?']
+}
+
+// width returns width of str in points by font size
+func (f *ttfFont) width(str string, size float64) float64 {
+	w := 0
+	for _, r := range str {
+		if g := int(f.glyph(r)); g < len(f.advances) {
+			w += f.advances[g]
+		}
+	}
+	return float64(w) * size / float64(f.unitsPerEm)
+}
+
+// scale converts font units to pdf glyph space of 1000 units per em
+func (f *ttfFont) scale(v int) int {
+	return v * 1000 / f.unitsPerEm
+}
+
+func minInt(x, y int) int {
+	if x < y {
+		return x
+	}
+	return y
+}
@@ -0,0 +1,84 @@
+package export
+
+import (
+	"reflect"
+
+	"github.com/dimboknv/p24-cli/expr"
+	"github.com/pkg/errors"
+)
+
+// VirtualField computes value of a field that is not declared by exported struct, e.g. statement fingerprint
+type VirtualField func(obj interface{}) (interface{}, error)
+
+// VirtualFields is a set of virtual fields by name
+type VirtualFields map[string]VirtualField
+
+// mergeVirtualFields merges list of VirtualFields to the single one, later fields override earlier.
+// Returns nil if there are no fields
+func mergeVirtualFields(list []VirtualFields) VirtualFields {
+	var res VirtualFields
+	for _, vf := range list {
+		for name, f := range vf {
+			if res == nil {
+				res = VirtualFields{}
+			}
+			res[name] = f
+		}
+	}
+	return res
+}
+
+// Env returns expr.Env that resolves obj struct fields and vf virtual fields.
+// Struct fields take precedence over virtual ones
+func (vf VirtualFields) Env(obj interface{}) *Env {
+	return &Env{obj: obj, virtual: vf, structEnv: expr.StructEnv(obj)}
+}
+
+// Check returns an error if e uses identifiers that are neither strct fields nor vf fields
+func (vf VirtualFields) Check(strct interface{}, e *expr.Expr) error {
+	strctTyp := reflect.TypeOf(strct)
+	for _, ident := range e.Idents() {
+		if f, ok := strctTyp.FieldByName(ident); ok && !f.Anonymous && f.PkgPath == "" {
+			continue
+		}
+		if _, ok := vf[ident]; ok {
+			continue
+		}
+		return errors.Errorf("unknown field %q", ident)
+	}
+	return nil
+}
+
+// Env is expr.Env of struct and virtual fields, see VirtualFields.Env
+type Env struct {
+	obj       interface{}
+	virtual   VirtualFields
+	structEnv expr.Env
+	err       error
+}
+
+// Lookup satisfies expr.Env interface
+func (env *Env) Lookup(name string) (interface{}, bool) {
+	if v, ok := env.structEnv.Lookup(name); ok {
+		return v, true
+	}
+	f, ok := env.virtual[name]
+	if !ok {
+		return nil, false
+	}
+	v, err := f(env.obj)
+	if err != nil {
+		env.err = errors.Wrapf(err, "virtual field %q", name)
+		return nil, false
+	}
+	return v, true
+}
+
+// Eval evaluates e with env and returns virtual field error if any
+func (env *Env) Eval(e *expr.Expr) (interface{}, error) {
+	v, err := e.Eval(env)
+	if env.err != nil {
+		return nil, env.err
+	}
+	return v, err
+}
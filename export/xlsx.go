@@ -1,31 +1,46 @@
 package export
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
+	"sort"
 
 	"github.com/dimboknv/p24"
 	"github.com/pkg/errors"
 	"github.com/xuri/excelize/v2"
 )
 
-// xlsxExporter export statements as xlsx with custom format
+// listsSheet is a hidden sheet with values of drop-down lists
+const listsSheet = "Lists"
+
+// xlsxExporter export rows as xlsx with custom format
 type xlsxExporter struct {
-	xlsx       *excelize.File
-	sheet      string
-	statements p24.Statements
-	row        int
-	col        int
-	startCol   int
-	startRow   int
+	xlsx      *excelize.File
+	charts    []Chart
+	dropdowns map[string][]string
+	fieldCols map[string]int
+	sheet     string
+	rows      Rows
+	row       int
+	col       int
+	width     int // the next column after the table
+	startCol  int
+	startRow  int
+}
+
+// NewXLSX returns new xlsx exporter of statements
+func NewXLSX(statements p24.Statements, opts ...Option) Exporter {
+	return NewXLSXRows(StatementsRows(statements), opts...)
 }
 
-// NewXLSX returns new xlsx exporter
-func NewXLSX(statements p24.Statements) Exporter {
-	return &xlsxExporter{statements: statements, sheet: "Sheet1", startCol: 2, startRow: 2}
+// NewXLSXRows returns new xlsx exporter of rows
+func NewXLSXRows(rows Rows, opts ...Option) Exporter {
+	o := makeOptions(opts)
+	return &xlsxExporter{rows: rows, sheet: o.sheet, dropdowns: o.dropdowns, charts: o.charts, startCol: 2, startRow: 2}
 }
 
-// Export statements to w writer as xlsx with given f Format
+// Export rows to w writer as xlsx with given f Format
 func (ex *xlsxExporter) Export(w io.Writer, f Format) error {
 	if _, err := excelize.CoordinatesToCellName(ex.startCol, ex.startRow); err != nil {
 		return err
@@ -33,11 +48,22 @@ func (ex *xlsxExporter) Export(w io.Writer, f Format) error {
 
 	// default "Sheet1" created by excelize.NewFile()
 	ex.xlsx = excelize.NewFile()
-	ex.xlsx.SetActiveSheet(ex.xlsx.NewSheet(ex.sheet))
-	ex.row, ex.col = ex.startRow, ex.startCol
+	ex.xlsx.NewSheet(ex.sheet)
+	if ex.sheet != "Sheet1" {
+		ex.xlsx.DeleteSheet("Sheet1")
+	}
+	ex.xlsx.SetActiveSheet(ex.xlsx.GetSheetIndex(ex.sheet))
+	ex.row, ex.col, ex.fieldCols = ex.startRow, ex.startCol, map[string]int{}
 	if err := ex.encode(f); err != nil {
 		return errors.Wrap(err, "encode failed")
 	}
+	if err := ex.encodeDropdowns(); err != nil {
+		return errors.Wrap(err, "failed to add drop-down lists")
+	}
+
+	if err := ex.encodeCharts(); err != nil {
+		return errors.Wrap(err, "failed to add charts")
+	}
 
 	if err := ex.xlsx.Write(w); err != nil {
 		return errors.Wrap(err, "failed to write encoded data")
@@ -51,22 +77,23 @@ func (ex *xlsxExporter) nextRow() {
 }
 
 func (ex *xlsxExporter) encode(f Format) error {
-	// encode Statements table headers
+	// encode rows table headers
 	for i := 0; i < len(f.Fields); i++ {
 		if err := ex.encodeHeader(f.Fields[i]); err != nil {
 			return err
 		}
 	}
+	ex.width = ex.col
 	ex.nextRow()
 
-	// encode Statements table content
-	for i := 0; i < len(ex.statements.Statements); i++ {
-		values, err := f.ValuesOf(&ex.statements.Statements[i])
+	// encode rows table content
+	for _, item := range ex.rows.Items {
+		values, err := f.ValuesOf(item)
 		if err != nil {
 			return err
 		}
 		for k := 0; k < len(values); k++ {
-			if err := ex.encodeValue(f.Fields[k], values[k]); err != nil {
+			if err := ex.encodeValue(&f, f.Fields[k], values[k]); err != nil {
 				return err
 			}
 		}
@@ -76,6 +103,7 @@ func (ex *xlsxExporter) encode(f Format) error {
 }
 
 func (ex *xlsxExporter) encodeHeader(field string) error {
+	ex.fieldCols[field] = ex.col
 	switch field {
 	case "Amount", "CardAmount", "Rest":
 		if err := ex.setCellValue(field); err != nil {
@@ -100,11 +128,11 @@ func (ex *xlsxExporter) setCellValue(value interface{}) error {
 	return nil
 }
 
-func (ex *xlsxExporter) encodeValue(field string, value interface{}) error {
+func (ex *xlsxExporter) encodeValue(f *Format, field string, value interface{}) error {
 	switch field {
 	case "Amount", "CardAmount", "Rest":
 		amount := value.(p24.Funds)
-		if err := ex.setCellValue(amount.Amount.Float64()); err != nil {
+		if err := ex.setCellValue(f.Cell(field, amount.Amount)); err != nil {
 			return err
 		}
 
@@ -112,9 +140,104 @@ func (ex *xlsxExporter) encodeValue(field string, value interface{}) error {
 			return err
 		}
 	default:
-		if err := ex.setCellValue(value); err != nil {
+		if err := ex.setCellValue(f.Cell(field, value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeDropdowns adds drop-down lists to columns of exported fields. List values are written to hidden sheet
+func (ex *xlsxExporter) encodeDropdowns() error {
+	fields := make([]string, 0, len(ex.dropdowns))
+	for field := range ex.dropdowns {
+		if _, ok := ex.fieldCols[field]; ok && len(ex.dropdowns[field]) > 0 && len(ex.rows.Items) > 0 {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	sort.Strings(fields)
+
+	ex.xlsx.NewSheet(listsSheet)
+	for i, field := range fields {
+		values := ex.dropdowns[field]
+		for k, v := range values {
+			cell, _ := excelize.CoordinatesToCellName(i+1, k+1)
+			if err := ex.xlsx.SetCellValue(listsSheet, cell, v); err != nil {
+				return err
+			}
+		}
+		first, _ := excelize.CoordinatesToCellName(i+1, 1, true)
+		last, _ := excelize.CoordinatesToCellName(i+1, len(values), true)
+
+		dv := excelize.NewDataValidation(true)
+		top, _ := excelize.CoordinatesToCellName(ex.fieldCols[field], ex.startRow+1)
+		bottom, _ := excelize.CoordinatesToCellName(ex.fieldCols[field], ex.row-1)
+		dv.SetSqref(top + ":" + bottom)
+		// excelize supports only current sheet references, but excel accepts other sheets references too
+		if err := dv.SetSqrefDropList(fmt.Sprintf("%s!%s:%s", listsSheet, first, last), true); err != nil {
+			return err
+		}
+		if err := ex.xlsx.AddDataValidation(ex.sheet, dv); err != nil {
+			return err
+		}
+	}
+	return ex.xlsx.SetSheetVisible(listsSheet, false)
+}
+
+// encodeCharts adds line charts to the right of the rows table one under another
+func (ex *xlsxExporter) encodeCharts() error {
+	if len(ex.rows.Items) == 0 {
+		return nil
+	}
+	ref := func(col, fromRow, toRow int) string {
+		from, _ := excelize.CoordinatesToCellName(col, fromRow, true)
+		if fromRow == toRow {
+			return fmt.Sprintf("'%s'!%s", ex.sheet, from)
+		}
+		to, _ := excelize.CoordinatesToCellName(col, toRow, true)
+		return fmt.Sprintf("'%s'!%s:%s", ex.sheet, from, to)
+	}
+	type series struct {
+		Name       string `json:"name"`
+		Categories string `json:"categories"`
+		Values     string `json:"values"`
+	}
+
+	for i, c := range ex.charts {
+		x, ok := ex.fieldCols[c.X]
+		if !ok {
+			continue
+		}
+		var list []series
+		for _, y := range c.Y {
+			if col, ok := ex.fieldCols[y]; ok {
+				list = append(list, series{
+					Name:       ref(col, ex.startRow, ex.startRow),
+					Categories: ref(x, ex.startRow+1, ex.row-1),
+					Values:     ref(col, ex.startRow+1, ex.row-1),
+				})
+			}
+		}
+		if len(list) == 0 {
+			continue
+		}
+
+		format, err := json.Marshal(map[string]interface{}{
+			"type":   excelize.Line,
+			"series": list,
+			"title":  map[string]string{"name": c.Title},
+			"legend": map[string]string{"position": "bottom"},
+		})
+		if err != nil {
 			return err
 		}
+		cell, _ := excelize.CoordinatesToCellName(ex.width+1, ex.startRow+i*16)
+		if err := ex.xlsx.AddChart(ex.sheet, cell, string(format)); err != nil {
+			return errors.Wrapf(err, "chart %q", c.Title)
+		}
 	}
 	return nil
 }
@@ -0,0 +1,71 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/dimboknv/p24"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func Test_XLSXDropdown(t *testing.T) {
+	statements := p24.Statements{Statements: []p24.Statement{
+		{Appcode: "1", CardAmount: p24.Funds{Currency: "UAH", Amount: -100}},
+		{Appcode: "2", CardAmount: p24.Funds{Currency: "UAH", Amount: 200}},
+	}}
+	virtual := VirtualFields{"Category": func(obj interface{}) (interface{}, error) { return "", nil }}
+	format, err := MakeFormat("Appcode|CardAmount|Category", DefaultFormatParser(p24.Statement{}, virtual))
+	require.NoError(t, err)
+
+	buff := bytes.NewBuffer(nil)
+	exporter := NewXLSX(statements, WithDropdown("Category", []string{"groceries", "taxes"}), WithDropdown("Unknown", []string{"a"}))
+	require.NoError(t, exporter.Export(buff, format))
+
+	xlsx, err := excelize.OpenReader(bytes.NewReader(buff.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, []string{"Sheet1", "Lists"}, xlsx.GetSheetList())
+	require.False(t, xlsx.GetSheetVisible("Lists"))
+	rows, err := xlsx.GetRows("Lists")
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"groceries"}, {"taxes"}}, rows)
+
+	// Appcode is B, CardAmount is C and D, Category is E column, statements are 3 and 4 rows
+	zr, err := zip.NewReader(bytes.NewReader(buff.Bytes()), int64(buff.Len()))
+	require.NoError(t, err)
+	sheet, err := zr.Open("xl/worksheets/sheet1.xml")
+	require.NoError(t, err)
+	data, err := io.ReadAll(sheet)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `sqref="E3:E4"`)
+	require.Contains(t, string(data), `<formula1>Lists!$A$1:$A$2</formula1>`)
+}
+
+func Test_XLSXChart(t *testing.T) {
+	statements := p24.Statements{Statements: []p24.Statement{
+		{Appcode: "1", CardAmount: p24.Funds{Currency: "UAH", Amount: -100}},
+		{Appcode: "2", CardAmount: p24.Funds{Currency: "UAH", Amount: 200}},
+	}}
+	format, err := MakeFormat("Appcode|CardAmount", DefaultFormatParser(p24.Statement{}))
+	require.NoError(t, err)
+
+	buff := bytes.NewBuffer(nil)
+	exporter := NewXLSX(statements,
+		WithChart(Chart{Title: "Amounts", X: "Appcode", Y: []string{"CardAmount"}}),
+		WithChart(Chart{Title: "Skipped", X: "Unknown", Y: []string{"CardAmount"}}),
+	)
+	require.NoError(t, exporter.Export(buff, format))
+
+	zr, err := zip.NewReader(bytes.NewReader(buff.Bytes()), int64(buff.Len()))
+	require.NoError(t, err)
+	chart, err := zr.Open("xl/charts/chart1.xml")
+	require.NoError(t, err)
+	data, err := io.ReadAll(chart)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `<f>&#39;Sheet1&#39;!$B$3:$B$4</f>`)
+	require.Contains(t, string(data), `<f>&#39;Sheet1&#39;!$C$3:$C$4</f>`)
+	_, err = zr.Open("xl/charts/chart2.xml")
+	require.Error(t, err)
+}
@@ -3,28 +3,29 @@ package export
 import (
 	"bytes"
 	"encoding/xml"
-	"fmt"
 	"io"
-	"reflect"
 	"strings"
 
 	"github.com/dimboknv/p24"
 	"github.com/pkg/errors"
 )
 
-var stringer = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
-
-// xmlExporter export statements as xml with custom format
+// xmlExporter export rows as xml with custom format
 type xmlExporter struct {
-	statements p24.Statements
+	rows Rows
 }
 
-// NewXML returns new xmlExporter
+// NewXML returns new xmlExporter of statements
 func NewXML(statements p24.Statements) Exporter {
-	return &xmlExporter{statements}
+	return NewXMLRows(StatementsRows(statements))
+}
+
+// NewXMLRows returns new xmlExporter of rows
+func NewXMLRows(rows Rows) Exporter {
+	return &xmlExporter{rows}
 }
 
-// Export statements to w Writer as xml with given f Format
+// Export rows to w Writer as xml with given f Format
 func (ex *xmlExporter) Export(w io.Writer, f Format) error {
 	// encode to temporary buffer for prevent incomplete write
 	buff := bytes.NewBuffer([]byte{})
@@ -43,30 +44,41 @@ func (ex *xmlExporter) Export(w io.Writer, f Format) error {
 }
 
 func (ex *xmlExporter) encode(enc *xml.Encoder, f Format) error {
-	// encode top level statements token <statements status="" credit="" debet="">
-	if err := enc.EncodeToken(ex.statementsTopLvlStartElem()); err != nil {
+	// encode top level token, e.g. <statements status="" credit="" debet="">
+	if err := enc.EncodeToken(ex.topLvlStartElem(f)); err != nil {
 		return err
 	}
 
-	// encode inner statements list
+	// encode inner rows list, e.g.
 	// <statement Card="" Appcode="" Trantime="" Trandate="" Amount="" CardAmount="" Rest="" Terminal="" Description=""></statement>
 	// ...
-	if err := ex.encodeStatementsList(enc, f); err != nil {
+	if err := ex.encodeList(enc, f); err != nil {
 		return err
 	}
 
-	// close top level statements token
-	if err := enc.EncodeToken(ex.statementsTopLvlStartElem().End()); err != nil {
+	// encode extra elements, e.g. <total currency="" credit="" debet=""></total>
+	for _, e := range ex.rows.Extra {
+		elem := xml.StartElement{Name: xml.Name{Local: e.Name}, Attr: ex.attrs(f, e.Attrs)}
+		if err := enc.EncodeToken(elem); err != nil {
+			return err
+		}
+		if err := enc.EncodeToken(elem.End()); err != nil {
+			return err
+		}
+	}
+
+	// close top level token
+	if err := enc.EncodeToken(ex.topLvlStartElem(f).End()); err != nil {
 		return err
 	}
 
 	return enc.Flush()
 }
 
-func (ex *xmlExporter) encodeStatementsList(enc *xml.Encoder, f Format) error {
+func (ex *xmlExporter) encodeList(enc *xml.Encoder, f Format) error {
 	stmStartElem := xml.StartElement{
 		Name: xml.Name{
-			Local: "statement",
+			Local: ex.rows.Elem,
 		},
 		Attr: make([]xml.Attr, len(f.Fields)),
 	}
@@ -76,16 +88,11 @@ func (ex *xmlExporter) encodeStatementsList(enc *xml.Encoder, f Format) error {
 
 	updateStmStartElemAttrs := func(sse *xml.StartElement, values []interface{}) {
 		for i := 0; i < len(values); i++ {
-			v := reflect.ValueOf(values[i])
-			if v.CanInterface() && v.Type().Implements(stringer) {
-				sse.Attr[i].Value = v.Interface().(fmt.Stringer).String()
-				continue
-			}
-			sse.Attr[i].Value = v.String()
+			sse.Attr[i].Value = f.Text(f.Fields[i], values[i])
 		}
 	}
-	for i := range ex.statements.Statements {
-		values, err := f.ValuesOf(&ex.statements.Statements[i])
+	for _, item := range ex.rows.Items {
+		values, err := f.ValuesOf(item)
 		if err != nil {
 			return err
 		}
@@ -100,15 +107,19 @@ func (ex *xmlExporter) encodeStatementsList(enc *xml.Encoder, f Format) error {
 	return nil
 }
 
-func (ex *xmlExporter) statementsTopLvlStartElem() xml.StartElement {
+func (ex *xmlExporter) topLvlStartElem(f Format) xml.StartElement {
 	return xml.StartElement{
 		Name: xml.Name{
-			Local: "statements",
-		},
-		Attr: []xml.Attr{
-			{Name: xml.Name{Local: "status"}, Value: ex.statements.Status},
-			{Name: xml.Name{Local: "credit"}, Value: ex.statements.Credit.String()},
-			{Name: xml.Name{Local: "debet"}, Value: ex.statements.Debet.String()},
+			Local: ex.rows.Root,
 		},
+		Attr: ex.attrs(f, ex.rows.Attrs),
+	}
+}
+
+func (ex *xmlExporter) attrs(f Format, attrs []Attr) []xml.Attr {
+	res := make([]xml.Attr, len(attrs))
+	for i, a := range attrs {
+		res[i] = xml.Attr{Name: xml.Name{Local: a.Name}, Value: f.Text("", a.Value)}
 	}
+	return res
 }
@@ -0,0 +1,372 @@
+package expr
+
+import (
+	"math"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/pkg/errors"
+)
+
+// timeLayouts are used to convert strings to time.Time in comparisons and date() function
+var timeLayouts = []string{
+	"2006-01-02",
+	"02.01.2006",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"02.01.2006 15:04:05",
+	"02.01.2006 15:04",
+	time.RFC3339,
+}
+
+var kievLocation = p24.NewKievLocation()
+
+// regexps caches patterns that are not literals, e.g. `Description ~ Terminal`
+var regexps sync.Map
+
+func (n *literalNode) eval(Env) (interface{}, error) {
+	return n.value, nil
+}
+
+func (n *identNode) eval(env Env) (interface{}, error) {
+	v, ok := env.Lookup(n.name)
+	if !ok {
+		return nil, errors.Errorf("unknown identifier %q", n.name)
+	}
+	return normalize(v), nil
+}
+
+func (n *memberNode) eval(env Env) (interface{}, error) {
+	obj, err := n.obj.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := fieldOf(obj, n.name)
+	if !ok {
+		return nil, errors.Errorf("%T has no field %q", obj, n.name)
+	}
+	return v, nil
+}
+
+func (n *callNode) eval(env Env) (interface{}, error) {
+	args := make([]interface{}, len(n.args))
+	for i, a := range n.args {
+		v, err := a.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	v, err := n.fn.call(args)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s()", n.name)
+	}
+	return normalize(v), nil
+}
+
+func (n *unaryNode) eval(env Env) (interface{}, error) {
+	x, err := n.x.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "!" {
+		b, ok := x.(bool)
+		if !ok {
+			return nil, errors.Errorf("operator ! is not defined on %T", x)
+		}
+		return !b, nil
+	}
+	f, err := numeric(x)
+	if err != nil {
+		return nil, errors.Wrap(err, "operator -")
+	}
+	return -f, nil
+}
+
+func (n *matchNode) eval(env Env) (interface{}, error) {
+	x, err := n.x.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := x.(string)
+	if !ok {
+		return nil, errors.Errorf("regexp matching is not defined on %T", x)
+	}
+	return n.re.MatchString(s) != n.neg, nil
+}
+
+func (n *binaryNode) eval(env Env) (interface{}, error) {
+	x, err := n.x.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	// short circuit boolean operators
+	if n.op == "&&" || n.op == "||" {
+		b, ok := x.(bool)
+		if !ok {
+			return nil, errors.Errorf("operator %s is not defined on %T", n.op, x)
+		}
+		if b == (n.op == "||") {
+			return b, nil
+		}
+		y, err := n.y.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		if b, ok = y.(bool); !ok {
+			return nil, errors.Errorf("operator %s is not defined on %T", n.op, y)
+		}
+		return b, nil
+	}
+
+	y, err := n.y.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==", "!=":
+		eq, err := equal(x, y)
+		return eq == (n.op == "=="), err
+	case "<", "<=", ">", ">=":
+		return compareOp(n.op, x, y)
+	case "~", "!~":
+		return match(n.op, x, y)
+	default:
+		return arithmetic(n.op, x, y)
+	}
+}
+
+func compareOp(op string, x, y interface{}) (bool, error) {
+	c, err := Compare(x, y)
+	if err != nil {
+		return false, err
+	}
+	switch op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	default:
+		return c >= 0, nil
+	}
+}
+
+func match(op string, x, y interface{}) (bool, error) {
+	s, ok1 := x.(string)
+	pattern, ok2 := y.(string)
+	if !ok1 || !ok2 {
+		return false, errors.Errorf("operator %s is not defined on %T and %T", op, x, y)
+	}
+
+	cached, ok := regexps.Load(pattern)
+	if !ok {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return false, err
+		}
+		cached, _ = regexps.LoadOrStore(pattern, re)
+	}
+	return cached.(*regexp.Regexp).MatchString(s) == (op == "~"), nil
+}
+
+func arithmetic(op string, x, y interface{}) (interface{}, error) {
+	if sx, ok := x.(string); ok && op == "+" {
+		if sy, ok := y.(string); ok {
+			return sx + sy, nil
+		}
+	}
+
+	fx, err := numeric(x)
+	if err != nil {
+		return nil, errors.Wrapf(err, "operator %s", op)
+	}
+	fy, err := numeric(y)
+	if err != nil {
+		return nil, errors.Wrapf(err, "operator %s", op)
+	}
+
+	switch op {
+	case "+":
+		return fx + fy, nil
+	case "-":
+		return fx - fy, nil
+	case "*":
+		return fx * fy, nil
+	case "/":
+		if fy == 0 {
+			return nil, errors.New("division by zero")
+		}
+		return fx / fy, nil
+	default:
+		if fy == 0 {
+			return nil, errors.New("division by zero")
+		}
+		return math.Mod(fx, fy), nil
+	}
+}
+
+func equal(x, y interface{}) (bool, error) {
+	if x == nil || y == nil {
+		return x == nil && y == nil, nil
+	}
+	if bx, ok := x.(bool); ok {
+		by, ok := y.(bool)
+		if !ok {
+			return false, errors.Errorf("can't compare %T and %T", x, y)
+		}
+		return bx == by, nil
+	}
+	c, err := Compare(x, y)
+	return c == 0, err
+}
+
+// Compare returns an integer comparing two normalized values. The result will be
+// 0 if x == y, -1 if x < y, and +1 if x > y. nil is less than any value.
+// Supported pairs are numbers, strings, booleans, times, funds with the same currency.
+// Funds are compared with numbers by amount and times are compared with strings
+// parsed with one of well known date layouts
+func Compare(x, y interface{}) (int, error) {
+	x, y = normalize(x), normalize(y)
+	switch {
+	case x == nil && y == nil:
+		return 0, nil
+	case x == nil:
+		return -1, nil
+	case y == nil:
+		return 1, nil
+	}
+
+	switch xv := x.(type) {
+	case float64:
+		if yv, ok := y.(p24.Funds); ok {
+			return compareFloats(xv, yv.Amount.Float64()), nil
+		}
+		if yv, ok := y.(float64); ok {
+			return compareFloats(xv, yv), nil
+		}
+	case string:
+		switch yv := y.(type) {
+		case string:
+			return strings.Compare(xv, yv), nil
+		case time.Time:
+			c, err := Compare(yv, xv)
+			return -c, err
+		}
+	case bool:
+		if yv, ok := y.(bool); ok {
+			return compareFloats(boolFloat(xv), boolFloat(yv)), nil
+		}
+	case time.Time:
+		if s, ok := y.(string); ok {
+			t, err := parseTime(s)
+			if err != nil {
+				return 0, err
+			}
+			y = t
+		}
+		if yv, ok := y.(time.Time); ok {
+			return compareTimes(xv, yv), nil
+		}
+	case p24.Funds:
+		switch yv := y.(type) {
+		case p24.Funds:
+			if xv.Currency != yv.Currency {
+				return 0, errors.Errorf("can't compare %s and %s funds", xv.Currency, yv.Currency)
+			}
+			return compareFloats(xv.Amount.Float64(), yv.Amount.Float64()), nil
+		case float64:
+			return compareFloats(xv.Amount.Float64(), yv), nil
+		}
+	}
+	return 0, errors.Errorf("can't compare %T and %T", x, y)
+}
+
+func compareFloats(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareTimes(x, y time.Time) int {
+	switch {
+	case x.Before(y):
+		return -1
+	case x.After(y):
+		return 1
+	default:
+		return 0
+	}
+}
+
+func boolFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// numeric converts normalized v to float64. Funds are converted to its amount
+func numeric(v interface{}) (float64, error) {
+	switch t := v.(type) {
+	case float64:
+		return t, nil
+	case p24.Funds:
+		return t.Amount.Float64(), nil
+	default:
+		return 0, errors.Errorf("%T is not a number", v)
+	}
+}
+
+func parseTime(s string) (time.Time, error) {
+	for _, l := range timeLayouts {
+		if t, err := time.ParseInLocation(l, s, kievLocation); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.Errorf("%q is not a date", s)
+}
+
+// normalize converts v to one of the expression value types.
+// Numbers become float64, p24.Amount becomes float64 in major units, pointers are dereferenced
+func normalize(v interface{}) interface{} {
+	switch t := v.(type) {
+	case nil, bool, float64, string, time.Time, p24.Funds:
+		return v
+	case p24.Amount:
+		return t.Float64()
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint())
+	case reflect.Float32:
+		return rv.Float()
+	case reflect.String:
+		return rv.String()
+	case reflect.Bool:
+		return rv.Bool()
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return nil
+		}
+		return normalize(rv.Elem().Interface())
+	default:
+		return v
+	}
+}
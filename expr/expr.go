@@ -0,0 +1,127 @@
+// Package expr implements a small safe expression language used for computed
+// export columns and statements filtering.
+//
+// An expression is built of identifiers resolved by Env, member access ("CardAmount.Amount"),
+// number/string/bool literals, function calls ("month(TranDate)"), arithmetic "+ - * / %",
+// comparisons "== != < <= > >=", regexp matching "~ !~" and boolean logic "&& || !".
+// Expressions have no side effects and can't loop, so evaluation always terminates.
+package expr
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Env resolves identifiers of an expression
+type Env interface {
+	Lookup(name string) (interface{}, bool)
+}
+
+// EnvFunc type is an adapter to allow the use of ordinary functions as Env
+type EnvFunc func(name string) (interface{}, bool)
+
+// Lookup calls f(name)
+func (f EnvFunc) Lookup(name string) (interface{}, bool) { return f(name) }
+
+// StructEnv returns Env that resolves identifiers as exported fields of obj.
+// obj must be struct or pointer to struct
+func StructEnv(obj interface{}) Env {
+	return EnvFunc(func(name string) (interface{}, bool) {
+		return fieldOf(obj, name)
+	})
+}
+
+// Expr is a parsed expression
+type Expr struct {
+	root   node
+	src    string
+	idents []string
+}
+
+// Parse parses src to Expr
+func Parse(src string) (*Expr, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens, idents: map[string]struct{}{}}
+	root, err := p.parseBinary(1)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, errors.Errorf("unexpected %q at %d", t.text, t.pos)
+	}
+
+	idents := make([]string, 0, len(p.idents))
+	for name := range p.idents {
+		idents = append(idents, name)
+	}
+	sort.Strings(idents)
+	return &Expr{root: root, src: src, idents: idents}, nil
+}
+
+// MustParse is like Parse but panics if src can't be parsed
+func MustParse(src string) *Expr {
+	e, err := Parse(src)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// String returns source of e
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Idents returns sorted list of top level identifiers used by e
+func (e *Expr) Idents() []string {
+	return e.idents
+}
+
+// Eval evaluates e with identifiers resolved by env.
+// Result is one of nil, bool, float64, string, time.Time, p24.Funds or a value resolved by env
+func (e *Expr) Eval(env Env) (interface{}, error) {
+	v, err := e.root.eval(env)
+	if err != nil {
+		return nil, errors.Wrapf(err, "evaluate %q", e.src)
+	}
+	return v, nil
+}
+
+// EvalBool evaluates e and returns an error if result is not a boolean
+func (e *Expr) EvalBool(env Env) (bool, error) {
+	v, err := e.Eval(env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, errors.Errorf("%q is not a boolean expression", e.src)
+	}
+	return b, nil
+}
+
+// fieldOf returns normalized value of exported field name of obj
+func fieldOf(obj interface{}, name string) (interface{}, bool) {
+	v := reflect.ValueOf(obj)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, false
+	}
+
+	f, ok := v.Type().FieldByName(name)
+	if !ok || f.PkgPath != "" {
+		return nil, false
+	}
+	return normalize(v.FieldByIndex(f.Index).Interface()), true
+}
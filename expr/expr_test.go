@@ -0,0 +1,92 @@
+package expr
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Eval(t *testing.T) {
+	loc := p24.NewKievLocation()
+	obj := p24.Statement{
+		Card:        "5168123412341234",
+		TranDate:    time.Date(2022, 3, 15, 10, 30, 0, 0, loc),
+		Description: "Uber trip",
+		Amount:      p24.Funds{Currency: "USD", Amount: -1000},
+		CardAmount:  p24.Funds{Currency: "UAH", Amount: -29500},
+	}
+
+	cases := []struct {
+		src      string
+		expected interface{}
+		withErr  bool
+	}{
+		{src: "month(TranDate)", expected: "2022-03"},
+		{src: "week(TranDate)", expected: "2022-W11"},
+		{src: "year(TranDate)", expected: 2022.0},
+		{src: "weekday(TranDate)", expected: "Tuesday"},
+		{src: "sign(CardAmount.Amount)", expected: -1.0},
+		{src: "abs(CardAmount)", expected: 295.0},
+		{src: "direction(CardAmount)", expected: "debit"},
+		{src: "rate(Amount, CardAmount)", expected: 10.0 / 295},
+		{src: "rate(Amount, 0)", expected: nil},
+		{src: "mask(Card)", expected: "5168********1234"},
+		{src: "1 + 2 * 3 - -1", expected: 8.0},
+		{src: `"a" + 'b'`, expected: "ab"},
+		{src: `CardAmount.Amount < 0 && Description ~ "(?i)uber" && Terminal != ""`, expected: false},
+		{src: `CardAmount.Amount < 0 && Description ~ "(?i)uber" && Terminal == ""`, expected: true},
+		{src: `Description !~ "^Uber"`, expected: false},
+		{src: `TranDate >= "2022-03-15" && TranDate < date("16.03.2022")`, expected: true},
+		{src: `CardAmount < -100`, expected: true},
+		{src: `!(CardAmount.Currency == "UAH") || false`, expected: false},
+		{src: `if(CardAmount < 0, "out", "in")`, expected: "out"},
+		{src: `CardAmount < Amount`, withErr: true},
+		{src: `Unknown == 1`, withErr: true},
+		{src: `Card.Number`, withErr: true},
+		{src: `1 / 0`, withErr: true},
+		{src: `Description && true`, withErr: true},
+	}
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			e, err := Parse(c.src)
+			require.NoError(t, err)
+			actual, err := e.Eval(StructEnv(&obj))
+			require.True(t, c.withErr == (err != nil), err)
+			require.Equal(t, c.expected, actual)
+		})
+	}
+}
+
+func Test_Parse(t *testing.T) {
+	cases := []struct {
+		src     string
+		idents  []string
+		withErr bool
+	}{
+		{src: "month(TranDate)", idents: []string{"TranDate"}},
+		{src: "CardAmount.Amount > Rest.Amount || Card == 'x'", idents: []string{"Card", "CardAmount", "Rest"}},
+		{src: "unknown(TranDate)", withErr: true},
+		{src: "month(TranDate, Card)", withErr: true},
+		{src: "(1 + 2", withErr: true},
+		{src: "1 +", withErr: true},
+		{src: `Description ~ "("`, withErr: true},
+		{src: `"unterminated`, withErr: true},
+		{src: "a $ b", withErr: true},
+		{src: "1 < Rest < 3", withErr: true},
+		{src: "(1 < Rest) == (Rest < 3)", idents: []string{"Rest"}},
+	}
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			e, err := Parse(c.src)
+			require.True(t, c.withErr == (err != nil), err)
+			if err == nil {
+				require.Equal(t, c.idents, e.Idents())
+			}
+		})
+	}
+}
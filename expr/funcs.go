@@ -0,0 +1,197 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// function is a builtin expression function. maxArgs < 0 means variadic
+type function struct {
+	call    func(args []interface{}) (interface{}, error)
+	minArgs int
+	maxArgs int
+}
+
+// functions is a set of builtin functions, all of them are pure
+var functions = map[string]*function{
+	"year":      {call: timeFunc(func(t time.Time) interface{} { return t.Year() }), minArgs: 1, maxArgs: 1},
+	"month":     {call: timeFunc(func(t time.Time) interface{} { return t.Format("2006-01") }), minArgs: 1, maxArgs: 1},
+	"week":      {call: timeFunc(isoWeek), minArgs: 1, maxArgs: 1},
+	"day":       {call: timeFunc(func(t time.Time) interface{} { return t.Day() }), minArgs: 1, maxArgs: 1},
+	"hour":      {call: timeFunc(func(t time.Time) interface{} { return t.Hour() }), minArgs: 1, maxArgs: 1},
+	"weekday":   {call: timeFunc(func(t time.Time) interface{} { return t.Weekday().String() }), minArgs: 1, maxArgs: 1},
+	"date":      {call: dateFunc, minArgs: 1, maxArgs: 1},
+	"format":    {call: formatFunc, minArgs: 2, maxArgs: 2},
+	"abs":       {call: numFunc(math.Abs), minArgs: 1, maxArgs: 1},
+	"sign":      {call: numFunc(sign), minArgs: 1, maxArgs: 1},
+	"round":     {call: roundFunc, minArgs: 1, maxArgs: 2},
+	"rate":      {call: rateFunc, minArgs: 2, maxArgs: 2},
+	"direction": {call: directionFunc, minArgs: 1, maxArgs: 1},
+	"mask":      {call: strFunc(mask), minArgs: 1, maxArgs: 1},
+	"lower":     {call: strFunc(strings.ToLower), minArgs: 1, maxArgs: 1},
+	"upper":     {call: strFunc(strings.ToUpper), minArgs: 1, maxArgs: 1},
+	"trim":      {call: strFunc(strings.TrimSpace), minArgs: 1, maxArgs: 1},
+	"contains":  {call: containsFunc, minArgs: 2, maxArgs: 2},
+	"if":        {call: ifFunc, minArgs: 3, maxArgs: 3},
+}
+
+func timeFunc(f func(t time.Time) interface{}) func(args []interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		t, err := timeArg(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return f(t), nil
+	}
+}
+
+func numFunc(f func(float64) float64) func(args []interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		x, err := numeric(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return f(x), nil
+	}
+}
+
+func strFunc(f func(string) string) func(args []interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, errors.Errorf("%T is not a string", args[0])
+		}
+		return f(s), nil
+	}
+}
+
+func timeArg(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case string:
+		return parseTime(t)
+	default:
+		return time.Time{}, errors.Errorf("%T is not a time", v)
+	}
+}
+
+func dateFunc(args []interface{}) (interface{}, error) {
+	t, err := timeArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()), nil
+}
+
+func formatFunc(args []interface{}) (interface{}, error) {
+	t, err := timeArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	layout, ok := args[1].(string)
+	if !ok {
+		return nil, errors.Errorf("layout %T is not a string", args[1])
+	}
+	return t.Format(layout), nil
+}
+
+func sign(x float64) float64 {
+	switch {
+	case x < 0:
+		return -1
+	case x > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func roundFunc(args []interface{}) (interface{}, error) {
+	x, err := numeric(args[0])
+	if err != nil {
+		return nil, err
+	}
+	precision := 0.0
+	if len(args) > 1 {
+		if precision, err = numeric(args[1]); err != nil {
+			return nil, err
+		}
+	}
+	p := math.Pow(10, precision)
+	return math.Round(x*p) / p, nil
+}
+
+// rateFunc returns x/y ratio, e.g. effective exchange rate rate(Amount, CardAmount).
+// Returns nil if y is zero
+func rateFunc(args []interface{}) (interface{}, error) {
+	x, err := numeric(args[0])
+	if err != nil {
+		return nil, err
+	}
+	y, err := numeric(args[1])
+	if err != nil {
+		return nil, err
+	}
+	if y == 0 {
+		return nil, nil
+	}
+	return x / y, nil
+}
+
+// directionFunc returns "debit" for negative, "credit" for positive and "" for zero amount
+func directionFunc(args []interface{}) (interface{}, error) {
+	x, err := numeric(args[0])
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case x < 0:
+		return "debit", nil
+	case x > 0:
+		return "credit", nil
+	default:
+		return "", nil
+	}
+}
+
+// mask hides characters of a card number except the first four and the last four ones, short numbers keep the last four only
+func mask(s string) string {
+	r := []rune(s)
+	for i := range r {
+		if (i >= 4 || len(r) <= 8) && i < len(r)-4 {
+			r[i] = '*'
+		}
+	}
+	return string(r)
+}
+
+func containsFunc(args []interface{}) (interface{}, error) {
+	s, ok1 := args[0].(string)
+	sub, ok2 := args[1].(string)
+	if !ok1 || !ok2 {
+		return nil, errors.Errorf("%T and %T are not strings", args[0], args[1])
+	}
+	return strings.Contains(s, sub), nil
+}
+
+func ifFunc(args []interface{}) (interface{}, error) {
+	cond, ok := args[0].(bool)
+	if !ok {
+		return nil, errors.Errorf("condition %T is not a boolean", args[0])
+	}
+	if cond {
+		return args[1], nil
+	}
+	return args[2], nil
+}
+
+// isoWeek returns ISO 8601 week of t, e.g. "2022-W01"
+func isoWeek(t time.Time) interface{} {
+	year, week := t.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
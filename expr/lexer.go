@@ -0,0 +1,123 @@
+package expr
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+	tokDot
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// operators sorted by length, the longest are matched first
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "!~", "<", ">", "~", "!", "+", "-", "*", "/", "%"}
+
+// lex splits src into a list of tokens terminated by tokEOF
+func lex(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c, size := utf8.DecodeRuneInString(src[i:])
+		switch {
+		case unicode.IsSpace(c):
+			i += size
+		case c == '(':
+			tokens = append(tokens, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokRParen, ")", i})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{tokComma, ",", i})
+			i++
+		case c == '.' && (i+1 >= len(src) || !isDigit(src[i+1])):
+			tokens = append(tokens, token{tokDot, ".", i})
+			i++
+		case c == '"' || c == '\'' || c == '`':
+			str, n, err := lexString(src[i:])
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid string at %d", i)
+			}
+			tokens = append(tokens, token{tokString, str, i})
+			i += n
+		case isDigit(src[i]) || c == '.':
+			n := i
+			for n < len(src) && (isDigit(src[n]) || src[n] == '.') {
+				n++
+			}
+			if _, err := strconv.ParseFloat(src[i:n], 64); err != nil {
+				return nil, errors.Errorf("invalid number %q at %d", src[i:n], i)
+			}
+			tokens = append(tokens, token{tokNumber, src[i:n], i})
+			i = n
+		case c == '_' || unicode.IsLetter(c):
+			n := i
+			for n < len(src) {
+				r, rs := utf8.DecodeRuneInString(src[n:])
+				if r != '_' && !unicode.IsDigit(r) && !unicode.IsLetter(r) {
+					break
+				}
+				n += rs
+			}
+			tokens = append(tokens, token{tokIdent, src[i:n], i})
+			i = n
+		default:
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(src[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, errors.Errorf("unexpected character %q at %d", c, i)
+			}
+			tokens = append(tokens, token{tokOp, op, i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{tokEOF, "", len(src)}), nil
+}
+
+// lexString reads quoted string from the beginning of src.
+// Double quoted strings support go escape sequences, single quoted and backticked are raw.
+// Returns unquoted string and count of consumed bytes
+func lexString(src string) (string, int, error) {
+	quote := src[0]
+	for i := 1; i < len(src); i++ {
+		switch {
+		case src[i] == '\\' && quote == '"':
+			i++
+		case src[i] == quote:
+			if quote != '"' {
+				return src[1:i], i + 1, nil
+			}
+			str, err := strconv.Unquote(src[:i+1])
+			return str, i + 1, err
+		}
+	}
+	return "", 0, errors.New("unterminated string")
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
@@ -0,0 +1,238 @@
+package expr
+
+import (
+	"regexp"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// maxDepth limits nesting of an expression
+const maxDepth = 64
+
+// node is an expression AST node
+type node interface {
+	eval(env Env) (interface{}, error)
+}
+
+type (
+	literalNode struct {
+		value interface{}
+	}
+	identNode struct {
+		name string
+	}
+	memberNode struct {
+		obj  node
+		name string
+	}
+	callNode struct {
+		fn   *function
+		name string
+		args []node
+	}
+	unaryNode struct {
+		x  node
+		op string
+	}
+	binaryNode struct {
+		x, y node
+		op   string
+	}
+	// matchNode is a regexp matching with a pre-compiled literal pattern
+	matchNode struct {
+		x   node
+		re  *regexp.Regexp
+		neg bool
+	}
+)
+
+// comparisonPrec is precedence of comparison operators, they are non-associative
+const comparisonPrec = 3
+
+// binary operators precedence, greater binds tighter
+var precedence = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"==": 3, "!=": 3, "<": 3, "<=": 3, ">": 3, ">=": 3, "~": 3, "!~": 3,
+	"+": 4, "-": 4,
+	"*": 5, "/": 5, "%": 5,
+}
+
+type parser struct {
+	tokens []token
+	idents map[string]struct{}
+	pos    int
+	depth  int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) expect(kind tokenKind, text string) error {
+	if t := p.next(); t.kind != kind {
+		return errors.Errorf("expected %q at %d, got %q", text, t.pos, t.text)
+	}
+	return nil
+}
+
+// parseBinary parses binary operators with precedence climbing
+func (p *parser) parseBinary(minPrec int) (node, error) {
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		prec, ok := precedence[t.text]
+		if t.kind != tokOp || !ok || prec < minPrec {
+			return x, nil
+		}
+		p.next()
+
+		// operators are left-associative, so right operand binds tighter
+		y, err := p.parseBinary(prec + 1)
+		if err != nil {
+			return nil, err
+		}
+		x, err = makeBinary(t, x, y)
+		if err != nil {
+			return nil, err
+		}
+
+		// chained comparisons like "a < b < c" are ambiguous
+		if n := p.peek(); prec == comparisonPrec && n.kind == tokOp && precedence[n.text] == comparisonPrec {
+			return nil, errors.Errorf("comparison %q at %d can't be chained, use parentheses", n.text, n.pos)
+		}
+	}
+}
+
+func makeBinary(t token, x, y node) (node, error) {
+	if t.text != "~" && t.text != "!~" {
+		return &binaryNode{x: x, y: y, op: t.text}, nil
+	}
+
+	// compile literal patterns once
+	lit, ok := y.(*literalNode)
+	if !ok {
+		return &binaryNode{x: x, y: y, op: t.text}, nil
+	}
+	pattern, ok := lit.value.(string)
+	if !ok {
+		return nil, errors.Errorf("regexp pattern at %d should be a string", t.pos)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid regexp at %d", t.pos)
+	}
+	return &matchNode{x: x, re: re, neg: t.text == "!~"}, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxDepth {
+		return nil, errors.New("expression is too deep")
+	}
+
+	if t := p.peek(); t.kind == tokOp && (t.text == "!" || t.text == "-") {
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{x: x, op: t.text}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	x, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokDot {
+		p.next()
+		t := p.next()
+		if t.kind != tokIdent {
+			return nil, errors.Errorf("expected field name at %d, got %q", t.pos, t.text)
+		}
+		x = &memberNode{obj: x, name: t.text}
+	}
+	return x, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid number at %d", t.pos)
+		}
+		return &literalNode{f}, nil
+	case tokString:
+		return &literalNode{t.text}, nil
+	case tokLParen:
+		x, err := p.parseBinary(1)
+		if err != nil {
+			return nil, err
+		}
+		return x, p.expect(tokRParen, ")")
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return &literalNode{true}, nil
+		case "false":
+			return &literalNode{false}, nil
+		case "nil":
+			return &literalNode{nil}, nil
+		}
+		if p.peek().kind == tokLParen {
+			return p.parseCall(t)
+		}
+		p.idents[t.text] = struct{}{}
+		return &identNode{t.text}, nil
+	case tokEOF:
+		return nil, errors.New("unexpected end of expression")
+	default:
+		return nil, errors.Errorf("unexpected %q at %d", t.text, t.pos)
+	}
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	fn, ok := functions[name.text]
+	if !ok {
+		return nil, errors.Errorf("unknown function %q at %d", name.text, name.pos)
+	}
+	p.next() // skip (
+
+	var args []node
+	for p.peek().kind != tokRParen {
+		if len(args) > 0 {
+			if err := p.expect(tokComma, ","); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := p.parseBinary(1)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	p.next() // skip )
+
+	if len(args) < fn.minArgs || (fn.maxArgs >= 0 && len(args) > fn.maxArgs) {
+		return nil, errors.Errorf("invalid number of arguments for %q at %d", name.text, name.pos)
+	}
+	return &callNode{fn: fn, name: name.text, args: args}, nil
+}
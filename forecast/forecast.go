@@ -0,0 +1,113 @@
+// Package forecast projects daily card balance by recurring statements series
+package forecast
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/export"
+	"github.com/dimboknv/p24-cli/recurring"
+)
+
+// Options of forecast
+type Options struct {
+	Start     time.Time  // the balance date, forecast starts the next day
+	Days      int        // count of forecast days
+	Balance   p24.Funds  // the balance at Start
+	Threshold p24.Amount // days with the balance below threshold are flagged
+}
+
+// Event is an expected statement of a recurring series
+type Event struct {
+	Date   time.Time
+	Name   string
+	Period string
+	Amount p24.Funds
+}
+
+// Day is a forecast day balance with expected events
+type Day struct {
+	Date     time.Time
+	Currency string
+	Change   p24.Amount // sum of events amounts
+	Balance  p24.Amount // balance at the end of the day
+	Below    bool       // balance is below threshold
+	Events   string     // e.g. "Netflix -169.00; Salary +10000.00"
+
+	events []Event
+}
+
+// Forecast returns daily balances from the day after opts.Start for opts.Days days.
+// Events are expected dates of series in the balance currency with the latest series amounts.
+// Overdue charges of series which are not missing yet are expected at the first day
+func Forecast(series []*recurring.Series, opts Options) []*Day {
+	kiev := p24.NewKievLocation()
+	start := date(opts.Start.In(kiev))
+	days := make([]*Day, opts.Days)
+	for i := range days {
+		days[i] = &Day{Date: start.AddDate(0, 0, i+1), Currency: opts.Balance.Currency}
+	}
+	if len(days) == 0 {
+		return days
+	}
+	end := days[len(days)-1].Date
+
+	for _, s := range series {
+		if s.Missing || s.Latest.Currency != opts.Balance.Currency {
+			continue
+		}
+		for _, t := range s.Dates(end.Add(24*time.Hour - time.Nanosecond)) {
+			i := int(math.Round(date(t.In(kiev)).Sub(start).Hours()/24)) - 1
+			if i < 0 {
+				i = 0
+			}
+			days[i].events = append(days[i].events, Event{Date: t, Name: s.Name, Period: s.Period, Amount: s.Latest})
+		}
+	}
+
+	balance := opts.Balance.Amount
+	for _, d := range days {
+		sort.SliceStable(d.events, func(i, j int) bool { return d.events[i].Date.Before(d.events[j].Date) })
+		texts := make([]string, len(d.events))
+		for i, e := range d.events {
+			d.Change += e.Amount.Amount
+			texts[i] = fmt.Sprintf("%s %s", e.Name, signed(e.Amount.Amount))
+		}
+		balance += d.Change
+		d.Balance, d.Below, d.Events = balance, balance < opts.Threshold, strings.Join(texts, "; ")
+	}
+	return days
+}
+
+// Rows returns export rows of forecast days
+func Rows(days []*Day) export.Rows {
+	items := make([]interface{}, len(days))
+	for i := range days {
+		items[i] = days[i]
+	}
+	return export.Rows{Root: "forecast", Elem: "day", Items: items}
+}
+
+// Chart returns xlsx chart of forecast balance
+func Chart() export.Chart {
+	return export.Chart{Title: "Balance", X: "Date", Y: []string{"Balance"}}
+}
+
+// date returns midnight of t date in t location
+func date(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// signed returns amount with sign and 2 decimal digits, e.g. "+10.00"
+func signed(a p24.Amount) string {
+	str := strconv.FormatFloat(a.Float64(), 'f', 2, 64)
+	if a > 0 {
+		str = "+" + str
+	}
+	return str
+}
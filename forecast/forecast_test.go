@@ -0,0 +1,59 @@
+package forecast
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/recurring"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Forecast(t *testing.T) {
+	kiev := p24.NewKievLocation()
+	var statements []p24.Statement
+	for m := time.January; m <= time.March; m++ {
+		statements = append(statements,
+			p24.Statement{
+				TranDate: time.Date(2022, m, 5, 10, 0, 0, 0, kiev), Description: "Netflix",
+				CardAmount: p24.Funds{Currency: "UAH", Amount: -16900},
+			},
+			p24.Statement{
+				TranDate: time.Date(2022, m, 25, 10, 0, 0, 0, kiev), Description: "Salary",
+				CardAmount: p24.Funds{Currency: "UAH", Amount: 1000000},
+			},
+			p24.Statement{
+				TranDate: time.Date(2022, m, 10, 10, 0, 0, 0, kiev), Description: "Cloud",
+				CardAmount: p24.Funds{Currency: "USD", Amount: -1000},
+			},
+		)
+	}
+	start := time.Date(2022, 3, 31, 12, 0, 0, 0, kiev)
+	series := recurring.Detect(statements, recurring.Options{At: start})
+	require.Len(t, series, 3)
+
+	days := Forecast(series, Options{
+		Start: start, Days: 30, Balance: p24.Funds{Currency: "UAH", Amount: 20000}, Threshold: 10000,
+	})
+	require.Len(t, days, 30)
+	require.Equal(t, time.Date(2022, 4, 1, 0, 0, 0, 0, kiev), days[0].Date)
+	require.Equal(t, time.Date(2022, 4, 30, 0, 0, 0, 0, kiev), days[29].Date)
+
+	netflix, salary := days[4], days[24]
+	require.Equal(t, p24.Amount(-16900), netflix.Change)
+	require.Equal(t, p24.Amount(3100), netflix.Balance)
+	require.Equal(t, "Netflix -169.00", netflix.Events)
+	require.True(t, netflix.Below)
+	require.False(t, days[3].Below)
+	require.Equal(t, "Salary +10000.00", salary.Events)
+	require.Equal(t, p24.Amount(1003100), salary.Balance)
+	require.False(t, salary.Below)
+	// USD series is skipped
+	require.Empty(t, days[9].Events)
+
+	// overdue charges are expected at the first day
+	days = Forecast(series, Options{Start: time.Date(2022, 4, 7, 0, 0, 0, 0, kiev), Days: 1, Balance: p24.Funds{Currency: "UAH"}})
+	require.Equal(t, "Netflix -169.00", days[0].Events)
+
+	require.Empty(t, Forecast(series, Options{Start: start}))
+}
@@ -0,0 +1,60 @@
+package fx
+
+import (
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/export"
+	"github.com/pkg/errors"
+)
+
+// Converter converts statements CardAmount to the base currency by rates at TranDate
+type Converter struct {
+	Table *Table
+	Base  string
+}
+
+// Convert returns s CardAmount in the base currency and used rate
+func (c *Converter) Convert(s *p24.Statement) (p24.Funds, Rate, error) {
+	return c.Table.Convert(s.CardAmount, c.Base, s.TranDate)
+}
+
+// Amount returns s CardAmount in the base currency, see pipeline.Totals
+func (c *Converter) Amount(s *p24.Statement) (p24.Funds, error) {
+	f, _, err := c.Convert(s)
+	return f, err
+}
+
+// Fields returns virtual fields of statements converted by c:
+// - BaseAmount is CardAmount in the base currency
+// - BaseRate is the rate of CardAmount currency to the base currency
+// - RateDate is the date of the rate
+//
+// Fields return an error if c is nil
+func Fields(c *Converter) export.VirtualFields {
+	field := func(f func(amount p24.Funds, r Rate) interface{}) export.VirtualField {
+		return func(obj interface{}) (interface{}, error) {
+			if c == nil {
+				return nil, errors.New("exchange rates are not set")
+			}
+			var s p24.Statement
+			switch v := obj.(type) {
+			case *p24.Statement:
+				s = *v
+			case p24.Statement:
+				s = v
+			default:
+				return nil, errors.Errorf("%T is not a statement", obj)
+			}
+			amount, r, err := c.Convert(&s)
+			if err != nil {
+				return nil, err
+			}
+			return f(amount, r), nil
+		}
+	}
+
+	return export.VirtualFields{
+		"BaseAmount": field(func(amount p24.Funds, _ Rate) interface{} { return amount }),
+		"BaseRate":   field(func(_ p24.Funds, r Rate) interface{} { return r.Rate }),
+		"RateDate":   field(func(_ p24.Funds, r Rate) interface{} { return r.Date.Format(DateLayout) }),
+	}
+}
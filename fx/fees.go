@@ -0,0 +1,149 @@
+package fx
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/export"
+	"github.com/pkg/errors"
+)
+
+// Fee row levels
+const (
+	LevelTransaction = "transaction"
+	LevelTotal       = "total"
+)
+
+// FeeRow is a foreign currency transaction or a total of transactions of a month and currencies pair.
+// Fee is the difference between CardAmount and Amount converted by the reference rate, positive fee is a cost.
+// Markup is the fee in percents of the reference amount
+type FeeRow struct {
+	tranDate        time.Time
+	Level           string
+	Month           string
+	Description     string
+	Count           int
+	Amount          p24.Funds
+	CardAmount      p24.Funds
+	EffectiveRate   float64
+	ReferenceRate   float64
+	RateDate        string
+	ReferenceAmount p24.Funds
+	Fee             p24.Funds
+	Markup          float64
+}
+
+// Fees returns rows of statements with Amount currency other than CardAmount currency
+// followed by totals per month, Amount and CardAmount currencies.
+// Reference rates are the rates of Amount currency to CardAmount currency from t.
+// Statements without reference rates are skipped and returned as unrated
+func Fees(statements []p24.Statement, t *Table) (rows []*FeeRow, unrated []p24.Statement) {
+	totals := map[[3]string]*FeeRow{}
+	for i := range statements {
+		s := &statements[i]
+		if s.Amount.Currency == "" || s.CardAmount.Currency == "" || s.Amount.Currency == s.CardAmount.Currency || s.Amount.Amount == 0 {
+			continue
+		}
+
+		ref, r, err := t.Convert(s.Amount, s.CardAmount.Currency, s.TranDate)
+		if err != nil {
+			unrated = append(unrated, *s)
+			continue
+		}
+		row := &FeeRow{
+			Level:           LevelTransaction,
+			Month:           s.TranDate.Format("2006-01"),
+			tranDate:        s.TranDate,
+			Description:     s.Description,
+			Count:           1,
+			Amount:          s.Amount,
+			CardAmount:      s.CardAmount,
+			ReferenceRate:   r.Rate,
+			RateDate:        r.Date.Format(DateLayout),
+			ReferenceAmount: ref,
+		}
+		rows = append(rows, row.finish())
+
+		key := [3]string{row.Month, s.Amount.Currency, s.CardAmount.Currency}
+		if totals[key] == nil {
+			totals[key] = &FeeRow{
+				Level:           LevelTotal,
+				Month:           row.Month,
+				Amount:          p24.Funds{Currency: s.Amount.Currency},
+				CardAmount:      p24.Funds{Currency: s.CardAmount.Currency},
+				ReferenceAmount: p24.Funds{Currency: s.CardAmount.Currency},
+			}
+		}
+		total := totals[key]
+		total.Count++
+		total.Amount.Amount += s.Amount.Amount
+		total.CardAmount.Amount += s.CardAmount.Amount
+		total.ReferenceAmount.Amount += ref.Amount
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].tranDate.Before(rows[j].tranDate) })
+
+	keys := make([][3]string, 0, len(totals))
+	for key := range totals {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		for k := range keys[i] {
+			if keys[i][k] != keys[j][k] {
+				return keys[i][k] < keys[j][k]
+			}
+		}
+		return false
+	})
+	for _, key := range keys {
+		rows = append(rows, totals[key].finish())
+	}
+	return rows, unrated
+}
+
+// FeeRows returns export rows of fees
+func FeeRows(rows []*FeeRow) export.Rows {
+	items := make([]interface{}, len(rows))
+	for i := range rows {
+		items[i] = rows[i]
+	}
+	return export.Rows{Root: "fees", Elem: "row", Items: items}
+}
+
+// FeeFields returns TranDate virtual field of fee rows, it is empty for totals
+func FeeFields() export.VirtualFields {
+	return export.VirtualFields{
+		"TranDate": func(obj interface{}) (interface{}, error) {
+			var row FeeRow
+			switch r := obj.(type) {
+			case *FeeRow:
+				row = *r
+			case FeeRow:
+				row = r
+			default:
+				return nil, errors.Errorf("%T is not a fee row", obj)
+			}
+			if row.Level == LevelTotal {
+				return nil, nil
+			}
+			return row.tranDate, nil
+		},
+	}
+}
+
+// finish computes effective rate, fee and markup by amounts
+func (r *FeeRow) finish() *FeeRow {
+	if r.Amount.Amount != 0 {
+		r.EffectiveRate = float64(r.CardAmount.Amount) / float64(r.Amount.Amount)
+	}
+	if r.Level == LevelTotal && r.ReferenceAmount.Amount != 0 {
+		// the average reference rate of total
+		r.ReferenceRate = float64(r.ReferenceAmount.Amount) / float64(r.Amount.Amount)
+	}
+	r.Fee = p24.Funds{Currency: r.CardAmount.Currency, Amount: r.ReferenceAmount.Amount - r.CardAmount.Amount}
+	if r.ReferenceAmount.Amount != 0 {
+		r.Markup = float64(r.Fee.Amount) / math.Abs(float64(r.ReferenceAmount.Amount)) * 100
+	}
+	return r
+}
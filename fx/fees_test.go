@@ -0,0 +1,95 @@
+package fx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Fees(t *testing.T) {
+	kiev := p24.NewKievLocation()
+	table := NewTable(
+		Rate{Date: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), Currency: "USD", Base: "UAH", Rate: 27},
+		Rate{Date: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), Currency: "EUR", Base: "UAH", Rate: 30},
+	)
+	statements := []p24.Statement{
+		{
+			TranDate: time.Date(2022, 1, 3, 10, 0, 0, 0, kiev), Description: "app",
+			Amount: p24.Funds{Currency: "USD", Amount: -1000}, CardAmount: p24.Funds{Currency: "UAH", Amount: -27540},
+		},
+		{
+			TranDate: time.Date(2022, 1, 2, 10, 0, 0, 0, kiev), Description: "shop",
+			Amount: p24.Funds{Currency: "UAH", Amount: -100}, CardAmount: p24.Funds{Currency: "UAH", Amount: -100},
+		},
+		{
+			TranDate: time.Date(2022, 1, 2, 10, 0, 0, 0, kiev), Description: "refund",
+			Amount: p24.Funds{Currency: "USD", Amount: 100}, CardAmount: p24.Funds{Currency: "UAH", Amount: 2646},
+		},
+		{
+			TranDate: time.Date(2022, 1, 4, 10, 0, 0, 0, kiev), Description: "hotel",
+			Amount: p24.Funds{Currency: "EUR", Amount: -1000}, CardAmount: p24.Funds{Currency: "USD", Amount: -1122},
+		},
+	}
+
+	rows, unrated := Fees(statements, table)
+	require.Empty(t, unrated)
+	require.Len(t, rows, 5)
+
+	levels, descriptions := make([]string, len(rows)), make([]string, len(rows))
+	for i, r := range rows {
+		levels[i], descriptions[i] = r.Level, r.Description
+	}
+	require.Equal(t, []string{LevelTransaction, LevelTransaction, LevelTransaction, LevelTotal, LevelTotal}, levels)
+	require.Equal(t, []string{"refund", "app", "hotel", "", ""}, descriptions)
+
+	refund, app, hotel := rows[0], rows[1], rows[2]
+	require.Equal(t, p24.Funds{Currency: "UAH", Amount: 2700}, refund.ReferenceAmount)
+	require.Equal(t, p24.Funds{Currency: "UAH", Amount: 54}, refund.Fee)
+	require.InDelta(t, 2, refund.Markup, 1e-9)
+	require.Equal(t, p24.Funds{Currency: "UAH", Amount: 540}, app.Fee)
+	require.InDelta(t, 27.54, app.EffectiveRate, 1e-9)
+	require.InDelta(t, 27, app.ReferenceRate, 1e-9)
+	require.Equal(t, "2022-01-01", app.RateDate)
+	// EUR/USD cross rate by UAH rates
+	require.InDelta(t, 30.0/27, hotel.ReferenceRate, 1e-9)
+	require.Equal(t, p24.Funds{Currency: "USD", Amount: 11}, hotel.Fee)
+
+	eur, usd := rows[3], rows[4]
+	require.Equal(t, "EUR", eur.Amount.Currency)
+	require.Equal(t, 1, eur.Count)
+	require.Equal(t, "USD", usd.Amount.Currency)
+	require.Equal(t, 2, usd.Count)
+	require.Equal(t, p24.Funds{Currency: "UAH", Amount: -24894}, usd.CardAmount)
+	require.Equal(t, p24.Funds{Currency: "UAH", Amount: 594}, usd.Fee)
+
+	v, err := FeeFields()["TranDate"](usd)
+	require.NoError(t, err)
+	require.Nil(t, v)
+	v, err = FeeFields()["TranDate"](*app)
+	require.NoError(t, err)
+	require.Equal(t, app.tranDate, v)
+
+	// statements without reference rates are skipped
+	rows, unrated = Fees(statements, NewTable(Rate{Date: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), Currency: "USD", Base: "UAH", Rate: 27}))
+	require.Len(t, rows, 3)
+	require.Equal(t, []string{"refund", "app", LevelTotal}, []string{rows[0].Description, rows[1].Description, rows[2].Level})
+	require.Len(t, unrated, 1)
+	require.Equal(t, "hotel", unrated[0].Description)
+
+	// EUR card paying in USD by rates without base, they are UAH rates
+	table = NewTable(
+		Rate{Date: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), Currency: "USD", Rate: 38},
+		Rate{Date: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), Currency: "EUR", Rate: 41},
+	)
+	rows, unrated = Fees([]p24.Statement{{
+		TranDate: time.Date(2022, 1, 3, 10, 0, 0, 0, kiev), Description: "app",
+		Amount: p24.Funds{Currency: "USD", Amount: -1000}, CardAmount: p24.Funds{Currency: "EUR", Amount: -930},
+	}}, table)
+	require.Empty(t, unrated)
+	require.Len(t, rows, 2)
+	require.InDelta(t, 38.0/41, rows[0].ReferenceRate, 1e-9)
+	require.Equal(t, p24.Funds{Currency: "EUR", Amount: -927}, rows[0].ReferenceAmount)
+	require.Equal(t, p24.Funds{Currency: "EUR", Amount: 3}, rows[0].Fee)
+}
@@ -0,0 +1,112 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/export"
+	"github.com/pkg/errors"
+)
+
+// PrivatURL is PrivatBank public api url of exchange rates archive
+const PrivatURL = "https://api.privatbank.ua/p24api/exchange_rates"
+
+// PrivatClient fetches NBU and PrivatBank cash exchange rates from PrivatBank public api
+type PrivatClient struct {
+	HTTP p24.Doer
+	URL  string // PrivatURL by default
+}
+
+// privatRates is PrivatBank exchange rates archive response
+type privatRates struct {
+	Date         string `json:"date"`
+	ExchangeRate []struct {
+		BaseCurrency   string  `json:"baseCurrency"`
+		Currency       string  `json:"currency"`
+		SaleRateNB     float64 `json:"saleRateNB"`
+		PurchaseRateNB float64 `json:"purchaseRateNB"`
+		SaleRate       float64 `json:"saleRate"`
+		PurchaseRate   float64 `json:"purchaseRate"`
+	} `json:"exchangeRate"`
+}
+
+// Fetch returns rates at the date of at. Rate is the NBU rate, Buy and Sale are PrivatBank cash rates if any
+func (c *PrivatClient) Fetch(ctx context.Context, at time.Time) ([]Rate, error) {
+	u := c.URL
+	if u == "" {
+		u = PrivatURL
+	}
+	u += "?json&date=" + url.QueryEscape(at.Format("02.01.2006"))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make request")
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read response")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected status %d: %s", resp.StatusCode, body)
+	}
+
+	var data privatRates
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse response %q", body)
+	}
+	d, err := parseDate(data.Date)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid response date")
+	}
+
+	rates := make([]Rate, 0, len(data.ExchangeRate))
+	for _, r := range data.ExchangeRate {
+		if r.Currency == "" || strings.EqualFold(r.Currency, r.BaseCurrency) || r.SaleRateNB == 0 {
+			continue
+		}
+		rates = append(rates, Rate{
+			Date: d, Currency: r.Currency, Base: r.BaseCurrency, Rate: r.SaleRateNB, Buy: r.PurchaseRate, Sale: r.SaleRate,
+		})
+	}
+	return rates, nil
+}
+
+// DefaultArchivePath returns default path of fetched rates archive, "$XDG_CONFIG_HOME/p24/rates.json" on linux
+func DefaultArchivePath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "p24", "rates.json")
+}
+
+// OpenArchive loads rates table from filename. Returns empty table if the file does not exist
+func OpenArchive(filename string) (*Table, error) {
+	if _, err := os.Stat(filename); errors.Is(err, os.ErrNotExist) {
+		return NewTable(), nil
+	}
+	return LoadTable(filename)
+}
+
+// Rows returns export rows of rates
+func Rows(rates []Rate) export.Rows {
+	items := make([]interface{}, len(rates))
+	for i := range rates {
+		items[i] = rates[i]
+	}
+	return export.Rows{Root: "rates", Elem: "rate", Items: items}
+}
@@ -0,0 +1,85 @@
+package fx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_PrivatClient_Fetch(t *testing.T) {
+	cases := []struct {
+		status   int
+		body     string
+		expected []Rate
+		withErr  bool
+	}{
+		{
+			status: http.StatusOK,
+			body: `{"date":"01.12.2014","bank":"PB","baseCurrency":980,"baseCurrencyLit":"UAH","exchangeRate":[
+				{"baseCurrency":"UAH","currency":"UAH","saleRateNB":1,"purchaseRateNB":1},
+				{"baseCurrency":"UAH","currency":"AUD","saleRateNB":12.83,"purchaseRateNB":12.83},
+				{"baseCurrency":"UAH","currency":"USD","saleRateNB":15.05,"purchaseRateNB":15.05,"saleRate":15.5,"purchaseRate":15}]}`,
+			expected: []Rate{
+				{Date: time.Date(2014, 12, 1, 0, 0, 0, 0, time.UTC), Currency: "AUD", Base: "UAH", Rate: 12.83},
+				{Date: time.Date(2014, 12, 1, 0, 0, 0, 0, time.UTC), Currency: "USD", Base: "UAH", Rate: 15.05, Buy: 15, Sale: 15.5},
+			},
+		},
+		{status: http.StatusOK, body: `{"date":"01.12.2014","exchangeRate":[]}`, expected: []Rate{}},
+		{status: http.StatusOK, body: `{"date":"","exchangeRate":[]}`, withErr: true},
+		{status: http.StatusOK, body: `<xml/>`, withErr: true},
+		{status: http.StatusInternalServerError, body: `{}`, withErr: true},
+	}
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "01.12.2014", r.URL.Query().Get("date"))
+				w.WriteHeader(c.status)
+				_, _ = w.Write([]byte(c.body))
+			}))
+			defer srv.Close()
+
+			client := &PrivatClient{HTTP: srv.Client(), URL: srv.URL}
+			rates, err := client.Fetch(context.Background(), time.Date(2014, 12, 1, 0, 0, 0, 0, time.UTC))
+			require.True(t, c.withErr == (err != nil), err)
+			require.Equal(t, c.expected, rates)
+		})
+	}
+}
+
+func Test_Table_Save(t *testing.T) {
+	table := NewTable(
+		Rate{Date: time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC), Currency: "USD", Base: "UAH", Rate: 27.5, Buy: 27.3, Sale: 27.9},
+		Rate{Date: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), Currency: "EUR", Rate: 31},
+	)
+	require.True(t, table.HasDate(time.Date(2022, 1, 1, 23, 0, 0, 0, time.UTC)))
+	require.False(t, table.HasDate(time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC)))
+	// holiday without rates is kept as fetched
+	holiday := time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC)
+	table.AddEmptyDate(holiday)
+	table.AddEmptyDate(holiday)
+	require.True(t, table.HasDate(holiday))
+	require.Len(t, table.Rates(), 2)
+	_, ok := table.Lookup("", "UAH", holiday)
+	require.False(t, ok)
+
+	for _, name := range []string{"rates.csv", "dir/rates.json"} {
+		filename := filepath.Join(t.TempDir(), name)
+		require.NoError(t, table.Save(filename))
+		loaded, err := OpenArchive(filename)
+		require.NoError(t, err)
+		require.Equal(t, table.Rates(), loaded.Rates())
+		require.True(t, loaded.HasDate(holiday))
+	}
+	require.Error(t, table.Save(filepath.Join(t.TempDir(), "rates.txt")))
+
+	empty, err := OpenArchive(filepath.Join(t.TempDir(), "none.json"))
+	require.NoError(t, err)
+	require.Empty(t, empty.Rates())
+}
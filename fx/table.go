@@ -0,0 +1,340 @@
+// Package fx implements exchange rates tables and currency conversion of statements
+package fx
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/pkg/errors"
+)
+
+// DateLayout is a layout of rates dates
+const DateLayout = "2006-01-02"
+
+// crossCurrency is a currency of cross rates, see Table.Lookup
+const crossCurrency = "UAH"
+
+var dateLayouts = []string{DateLayout, "02.01.2006"}
+
+// Rate is an exchange rate of a currency to the base currency at a date: 1 Currency = Rate Base, Base is UAH if empty.
+// Buy and Sale are optional bank cash rates, Rate is used for conversion
+type Rate struct {
+	Date     time.Time `json:"-"`
+	Currency string    `json:"currency"`
+	Base     string    `json:"base,omitempty"`
+	Rate     float64   `json:"rate"`
+	Buy      float64   `json:"buy,omitempty"`
+	Sale     float64   `json:"sale,omitempty"`
+}
+
+// rateJSON is json representation of Rate with "2006-01-02" date
+type rateJSON struct {
+	Date string `json:"date"`
+	Rate
+}
+
+// Table is a list of exchange rates by currency and date
+type Table struct {
+	rates map[string][]Rate // rates by currency sorted by date, rates without currency are empty dates, see AddEmptyDate
+}
+
+// NewTable returns Table of rates
+func NewTable(rates ...Rate) *Table {
+	t := &Table{rates: map[string][]Rate{}}
+	t.Add(rates...)
+	return t
+}
+
+// LoadTable reads Table from csv or json file with date, currency, rate and optional base, buy, sale columns.
+// Dates have "2006-01-02" or "02.01.2006" layouts, rows without currency are empty dates, see AddEmptyDate
+func LoadTable(filename string) (*Table, error) {
+	data, err := os.ReadFile(filepath.Clean(filename))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read rates %q", filename)
+	}
+
+	var rates []Rate
+	switch ext := strings.ToLower(path.Ext(filename)); ext {
+	case ".csv":
+		rates, err = parseCSV(data)
+	case ".json":
+		rates, err = parseJSON(data)
+	default:
+		return nil, errors.Errorf("%q extname is unsupported", ext)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse rates %q", filename)
+	}
+	return NewTable(rates...), nil
+}
+
+// Save writes the table to csv or json file by filename extname, the file directory is created if needed
+func (t *Table) Save(filename string) error {
+	var (
+		data []byte
+		err  error
+	)
+	switch ext := strings.ToLower(path.Ext(filename)); ext {
+	case ".csv":
+		data, err = t.MarshalCSV()
+	case ".json":
+		data, err = json.MarshalIndent(t, "", "  ")
+	default:
+		return errors.Errorf("%q extname is unsupported", ext)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to encode rates")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0o700); err != nil {
+		return errors.Wrapf(err, "failed to create rates directory")
+	}
+	return errors.Wrapf(os.WriteFile(filename, data, 0o600), "failed to write rates %q", filename)
+}
+
+// Add adds rates to the table, a rate of the same currency, base and date is replaced
+func (t *Table) Add(rates ...Rate) {
+	for _, r := range rates {
+		r.Currency, r.Base = strings.ToUpper(r.Currency), strings.ToUpper(r.Base)
+		r.Date = date(r.Date)
+		list := t.rates[r.Currency]
+		i := sort.Search(len(list), func(i int) bool { return !list[i].Date.Before(r.Date) })
+		replaced := false
+		for k := i; k < len(list) && list[k].Date.Equal(r.Date) && !replaced; k++ {
+			if list[k].Base == r.Base {
+				list[k], replaced = r, true
+			}
+		}
+		if replaced {
+			continue
+		}
+		list = append(list, Rate{})
+		copy(list[i+1:], list[i:])
+		list[i] = r
+		t.rates[r.Currency] = list
+	}
+}
+
+// AddEmptyDate adds a date without rates, e.g. a holiday, so HasDate returns true for it.
+// Empty dates are saved as rates without currency and rate
+func (t *Table) AddEmptyDate(at time.Time) {
+	if !t.HasDate(at) {
+		t.Add(Rate{Date: at})
+	}
+}
+
+// HasDate returns true if the table has any rate or an empty date at the date of at
+func (t *Table) HasDate(at time.Time) bool {
+	at = date(at)
+	for _, list := range t.rates {
+		i := sort.Search(len(list), func(i int) bool { return !list[i].Date.Before(at) })
+		if i < len(list) && list[i].Date.Equal(at) {
+			return true
+		}
+	}
+	return false
+}
+
+// Rates returns all rates sorted by date and currency, empty dates are skipped
+func (t *Table) Rates() []Rate {
+	return t.list(false)
+}
+
+// list returns rates sorted by date and currency, rates without currency of empty dates are included if empty is true
+func (t *Table) list(empty bool) []Rate {
+	var res []Rate
+	for currency, list := range t.rates {
+		if currency != "" || empty {
+			res = append(res, list...)
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		if !res[i].Date.Equal(res[j].Date) {
+			return res[i].Date.Before(res[j].Date)
+		}
+		return res[i].Currency < res[j].Currency
+	})
+	return res
+}
+
+// Lookup returns the latest rate of currency to base at or before date t.
+// Rates without base are rates to UAH. If there is no direct rate, the inverse of base to
+// currency rate is returned, otherwise a cross rate by rates of both currencies to UAH is returned with
+// the date of the older one
+func (t *Table) Lookup(currency, base string, at time.Time) (Rate, bool) {
+	currency, base = strings.ToUpper(currency), strings.ToUpper(base)
+	if currency == base {
+		return Rate{Date: date(at), Currency: currency, Base: base, Rate: 1}, true
+	}
+	if r, ok := t.pair(currency, base, at); ok || currency == crossCurrency || base == crossCurrency {
+		return r, ok
+	}
+
+	x, ok := t.pair(currency, crossCurrency, at)
+	if !ok {
+		return Rate{}, false
+	}
+	y, ok := t.pair(base, crossCurrency, at)
+	if !ok || y.Rate == 0 {
+		return Rate{}, false
+	}
+	d := x.Date
+	if y.Date.Before(d) {
+		d = y.Date
+	}
+	return Rate{Date: d, Currency: currency, Base: base, Rate: x.Rate / y.Rate}, true
+}
+
+// pair returns the latest of direct and inverse rates of currency to base at or before date t
+func (t *Table) pair(currency, base string, at time.Time) (Rate, bool) {
+	r, ok := t.lookup(currency, base, at)
+	inv, invOK := t.lookup(base, currency, at)
+	if invOK && inv.Rate != 0 && (!ok || inv.Date.After(r.Date)) {
+		return Rate{Date: inv.Date, Currency: currency, Base: base, Rate: 1 / inv.Rate}, true
+	}
+	return r, ok
+}
+
+func (t *Table) lookup(currency, base string, at time.Time) (Rate, bool) {
+	if currency == "" {
+		return Rate{}, false
+	}
+	list, at := t.rates[currency], date(at)
+	for i := sort.Search(len(list), func(i int) bool { return list[i].Date.After(at) }) - 1; i >= 0; i-- {
+		if list[i].Base == base || (list[i].Base == "" && base == crossCurrency) {
+			return list[i], true
+		}
+	}
+	return Rate{}, false
+}
+
+// Convert converts funds to base currency by the rate at date t
+func (t *Table) Convert(f p24.Funds, base string, at time.Time) (p24.Funds, Rate, error) {
+	r, ok := t.Lookup(f.Currency, base, at)
+	if !ok {
+		return p24.Funds{}, Rate{}, errors.Errorf("there is no %s/%s rate at %s", f.Currency, base, at.Format(DateLayout))
+	}
+	amount := p24.Amount(math.Round(float64(f.Amount) * r.Rate))
+	return p24.Funds{Currency: strings.ToUpper(base), Amount: amount}, r, nil
+}
+
+// MarshalJSON returns json array of rates and empty dates sorted by date and currency
+func (t *Table) MarshalJSON() ([]byte, error) {
+	rates := t.list(true)
+	res := make([]rateJSON, len(rates))
+	for i, r := range rates {
+		res[i] = rateJSON{Date: r.Date.Format(DateLayout), Rate: r}
+	}
+	return json.Marshal(res)
+}
+
+// MarshalCSV returns csv of rates and empty dates sorted by date and currency with header row, see LoadTable
+func (t *Table) MarshalCSV() ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	_ = w.Write([]string{"date", "currency", "base", "rate", "buy", "sale"})
+	number := func(f float64) string {
+		if f == 0 {
+			return ""
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	for _, r := range t.list(true) {
+		_ = w.Write([]string{r.Date.Format(DateLayout), r.Currency, r.Base, number(r.Rate), number(r.Buy), number(r.Sale)})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+func parseJSON(data []byte) ([]Rate, error) {
+	var list []rateJSON
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	rates := make([]Rate, len(list))
+	for i, r := range list {
+		d, err := parseDate(r.Date)
+		if err != nil {
+			return nil, errors.Wrapf(err, "rate #%d", i+1)
+		}
+		rates[i], rates[i].Date = r.Rate, d
+	}
+	return rates, nil
+}
+
+func parseCSV(data []byte) ([]Rate, error) {
+	dec := csv.NewReader(bytes.NewReader(data))
+	dec.FieldsPerRecord = -1
+	records, err := dec.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	cols := map[string]int{}
+	for i, name := range records[0] {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"date", "currency", "rate"} {
+		if _, ok := cols[name]; !ok {
+			return nil, errors.Errorf("%q column is required", name)
+		}
+	}
+	value := func(record []string, name string) string {
+		if i, ok := cols[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	rates := make([]Rate, 0, len(records)-1)
+	for i, record := range records[1:] {
+		d, err := parseDate(value(record, "date"))
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", i+2)
+		}
+		r := Rate{Date: d, Currency: value(record, "currency"), Base: value(record, "base")}
+		for _, v := range []struct {
+			name     string
+			dst      *float64
+			optional bool
+		}{{"rate", &r.Rate, r.Currency == ""}, {"buy", &r.Buy, true}, {"sale", &r.Sale, true}} {
+			str := strings.Replace(value(record, v.name), ",", ".", 1)
+			if str == "" && v.optional {
+				continue
+			}
+			if *v.dst, err = strconv.ParseFloat(str, 64); err != nil {
+				return nil, errors.Wrapf(err, "line %d", i+2)
+			}
+		}
+		rates = append(rates, r)
+	}
+	return rates, nil
+}
+
+func parseDate(str string) (time.Time, error) {
+	for _, l := range dateLayouts {
+		if t, err := time.Parse(l, str); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.Errorf("%q has unknown date layout", str)
+}
+
+// date truncates t to the date in t location as UTC, so dates of rates and statements are comparable
+func date(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,91 @@
+package fx
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_LoadTable(t *testing.T) {
+	cases := []struct {
+		name    string
+		data    string
+		rates   int
+		withErr bool
+	}{
+		{name: "rates.csv", data: "date,currency,rate\n2022-01-01,USD,27.5\n03.01.2022,usd,\"27,8\"\n2022-01-01,EUR,31\n", rates: 3},
+		{name: "rates.csv", data: "Date,Currency,Rate,Base\n2022-01-01,USD,27.5,UAH\n2022-01-01,USD,27.6,UAH\n", rates: 1},
+		{name: "rates.json", data: `[{"date":"2022-01-01","currency":"USD","rate":27.5},{"date":"2022-01-02","currency":"EUR","rate":31}]`, rates: 2},
+		{name: "rates.csv", data: "date,rate\n2022-01-01,1\n", withErr: true},
+		{name: "rates.csv", data: "date,currency,rate\n2022/01/01,USD,1\n", withErr: true},
+		{name: "rates.csv", data: "date,currency,rate\n2022-01-01,USD,x\n", withErr: true},
+		{name: "rates.json", data: `[{"date":"01/01/2022","currency":"USD","rate":1}]`, withErr: true},
+		{name: "rates.txt", data: "", withErr: true},
+	}
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), c.name)
+			require.NoError(t, os.WriteFile(filename, []byte(c.data), 0o600))
+			table, err := LoadTable(filename)
+			require.True(t, c.withErr == (err != nil), err)
+			if err == nil {
+				require.Len(t, table.Rates(), c.rates)
+			}
+		})
+	}
+}
+
+func Test_Convert(t *testing.T) {
+	kiev := p24.NewKievLocation()
+	table := NewTable(
+		Rate{Date: time.Date(2022, 1, 3, 23, 30, 0, 0, kiev), Currency: "USD", Rate: 28},
+		Rate{Date: time.Date(2022, 1, 1, 23, 30, 0, 0, kiev), Currency: "USD", Rate: 27.5},
+		Rate{Date: time.Date(2022, 1, 1, 23, 30, 0, 0, kiev), Currency: "EUR", Base: "USD", Rate: 1.1},
+	)
+
+	cases := []struct {
+		funds    p24.Funds
+		base     string
+		at       time.Time
+		expected p24.Funds
+		withErr  bool
+	}{
+		{p24.Funds{Currency: "USD", Amount: -1000}, "UAH", time.Date(2022, 1, 1, 23, 30, 0, 0, kiev), p24.Funds{Currency: "UAH", Amount: -27500}, false},
+		{p24.Funds{Currency: "USD", Amount: -1000}, "UAH", time.Date(2022, 1, 2, 23, 30, 0, 0, kiev), p24.Funds{Currency: "UAH", Amount: -27500}, false},
+		{p24.Funds{Currency: "USD", Amount: 1}, "uah", time.Date(2022, 1, 5, 23, 30, 0, 0, kiev), p24.Funds{Currency: "UAH", Amount: 28}, false},
+		{p24.Funds{Currency: "UAH", Amount: 100}, "UAH", time.Date(2022, 1, 1, 23, 30, 0, 0, kiev), p24.Funds{Currency: "UAH", Amount: 100}, false},
+		{p24.Funds{Currency: "EUR", Amount: 100}, "USD", time.Date(2022, 1, 1, 23, 30, 0, 0, kiev), p24.Funds{Currency: "USD", Amount: 110}, false},
+		// inverse of USD/UAH rate
+		{p24.Funds{Currency: "UAH", Amount: 2800}, "usd", time.Date(2022, 1, 3, 23, 30, 0, 0, kiev), p24.Funds{Currency: "USD", Amount: 100}, false},
+		{p24.Funds{Currency: "UAH", Amount: -5500}, "USD", time.Date(2022, 1, 2, 23, 30, 0, 0, kiev), p24.Funds{Currency: "USD", Amount: -200}, false},
+		// rates without base are UAH rates, EUR/USD rate is inverted
+		{p24.Funds{Currency: "USD", Amount: 110}, "EUR", time.Date(2022, 1, 1, 23, 30, 0, 0, kiev), p24.Funds{Currency: "EUR", Amount: 100}, false},
+		{p24.Funds{Currency: "EUR", Amount: 100}, "UAH", time.Date(2022, 1, 1, 23, 30, 0, 0, kiev), p24.Funds{}, true},
+		{p24.Funds{Currency: "USD", Amount: 100}, "UAH", time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC), p24.Funds{}, true},
+	}
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			actual, _, err := table.Convert(c.funds, c.base, c.at)
+			require.True(t, c.withErr == (err != nil), err)
+			require.Equal(t, c.expected, actual)
+		})
+	}
+
+	conv := &Converter{Table: table, Base: "UAH"}
+	s := p24.Statement{TranDate: time.Date(2022, 1, 3, 23, 30, 0, 0, kiev), CardAmount: p24.Funds{Currency: "USD", Amount: 200}}
+	v, err := Fields(conv)["BaseAmount"](&s)
+	require.NoError(t, err)
+	require.Equal(t, p24.Funds{Currency: "UAH", Amount: 5600}, v)
+	v, err = Fields(conv)["RateDate"](s)
+	require.NoError(t, err)
+	require.Equal(t, "2022-01-03", v)
+	_, err = Fields(nil)["BaseRate"](&s)
+	require.Error(t, err)
+}
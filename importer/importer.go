@@ -0,0 +1,340 @@
+// Package importer reads statements previously exported by p24 or received from p24 api
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/pkg/errors"
+	"github.com/xuri/excelize/v2"
+)
+
+// timeLayouts are layouts of TranDate values. The first one is time.Time String() used by default export format
+var timeLayouts = []string{
+	"2006-01-02 15:04:05 -0700 MST",
+	"2006-01-02 15:04:05",
+	"02.01.2006 15:04:05",
+	"2006-01-02 15:04",
+	"02.01.2006 15:04",
+	"2006-01-02",
+	"02.01.2006",
+	time.RFC3339,
+}
+
+var kievLocation = p24.NewKievLocation()
+
+// ReadFile reads statements from a file. Encoding is detected by file extname, xml, csv and xlsx are supported
+func ReadFile(filename string) (p24.Statements, error) {
+	f, err := os.Open(filepath.Clean(filename))
+	if err != nil {
+		return p24.Statements{}, err
+	}
+	defer f.Close() // nolint:errcheck,gosec // read only file
+
+	var statements p24.Statements
+	switch ext := strings.ToLower(path.Ext(filename)); ext {
+	case ".xml":
+		statements, err = ReadXML(f)
+	case ".csv":
+		statements, err = ReadCSV(f)
+	case ".xlsx":
+		statements, err = ReadXLSX(f)
+	default:
+		return p24.Statements{}, errors.Errorf("%q extname is unsupported", ext)
+	}
+	return statements, errors.Wrapf(err, "failed to read %q", filename)
+}
+
+// ReadRecords reads raw rows of xml, csv or xlsx file by lower case column names,
+// e.g. to get columns that are not p24.Statement fields like "category"
+func ReadRecords(filename string) ([]map[string]string, error) {
+	f, err := os.Open(filepath.Clean(filename))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close() // nolint:errcheck,gosec // read only file
+
+	var records []map[string]string
+	switch ext := strings.ToLower(path.Ext(filename)); ext {
+	case ".xml":
+		records, _, err = readXMLRecords(f)
+	case ".csv":
+		records, err = readCSVRecords(f)
+	case ".xlsx":
+		records, err = readXLSXRecords(f)
+	default:
+		return nil, errors.Errorf("%q extname is unsupported", ext)
+	}
+	return records, errors.Wrapf(err, "failed to read %q", filename)
+}
+
+// ReadXML reads statements from xml exported by p24 or received from p24 api.
+// Attributes are mapped to p24.Statement fields by case insensitive names, unknown attributes are skipped
+func ReadXML(r io.Reader) (p24.Statements, error) {
+	records, res, err := readXMLRecords(r)
+	if err != nil {
+		return p24.Statements{}, err
+	}
+	res.Statements, err = makeStatements(records, "statement #%d", 1)
+	return res, err
+}
+
+// ReadCSV reads statements from csv with header exported by p24.
+// Delimiter is detected by header, columns are mapped to p24.Statement fields by case insensitive names
+func ReadCSV(r io.Reader) (p24.Statements, error) {
+	records, err := readCSVRecords(r)
+	if err != nil {
+		return p24.Statements{}, err
+	}
+	statements, err := makeStatements(records, "line %d", 2)
+	return p24.Statements{Statements: statements}, err
+}
+
+// ReadXLSX reads statements from the first sheet of xlsx exported by p24.
+// The first non empty row is a header, "<Field> Currency" columns are joined with "<Field>" amount columns
+func ReadXLSX(r io.Reader) (p24.Statements, error) {
+	records, err := readXLSXRecords(r)
+	if err != nil {
+		return p24.Statements{}, err
+	}
+	statements, err := makeStatements(records, "row %d", 1)
+	return p24.Statements{Statements: statements}, err
+}
+
+// makeStatements makes statements from records, errors are wrapped with position format of a record starting from first
+func makeStatements(records []map[string]string, position string, first int) ([]p24.Statement, error) {
+	var res []p24.Statement
+	for i, values := range records {
+		s, err := MakeStatement(values)
+		if err != nil {
+			return nil, errors.Wrapf(err, position, i+first)
+		}
+		res = append(res, s)
+	}
+	return res, nil
+}
+
+func readXMLRecords(r io.Reader) ([]map[string]string, p24.Statements, error) {
+	var (
+		records []map[string]string
+		totals  p24.Statements
+	)
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return records, totals, nil
+		}
+		if err != nil {
+			return nil, p24.Statements{}, err
+		}
+
+		elem, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch elem.Name.Local {
+		case "statements":
+			if err := setStatementsAttrs(&totals, elem.Attr); err != nil {
+				return nil, p24.Statements{}, err
+			}
+		case "statement":
+			records = append(records, attrsMap(elem.Attr))
+		}
+	}
+}
+
+func readCSVRecords(r io.Reader) ([]map[string]string, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(br.Size())
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, err
+	}
+	if i := bytes.IndexByte(header, '\n'); i != -1 {
+		header = header[:i]
+	}
+
+	dec := csv.NewReader(br)
+	dec.Comma = detectDelim(string(header))
+	rows, err := dec.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	return tableRecords(rows), nil
+}
+
+func readXLSXRecords(r io.Reader) ([]map[string]string, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, err
+	}
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, errors.New("there are no sheets")
+	}
+	// raw values keep full precision of amounts and dates which are formatted by cell styles otherwise
+	rows, err := f.GetRows(sheets[0], excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil, err
+	}
+
+	// skip empty rows before header, exported tables are not started from A1
+	for len(rows) > 0 && strings.TrimSpace(strings.Join(rows[0], "")) == "" {
+		rows = rows[1:]
+	}
+	records := tableRecords(rows)
+	for _, values := range records {
+		for name, v := range values {
+			field := strings.TrimSuffix(name, " currency")
+			if amount, ok := values[field]; ok && field != name {
+				values[field] = strings.TrimSpace(amount + " " + v)
+				delete(values, name)
+			}
+		}
+		if date, ok := values["trandate"]; ok {
+			values["trandate"] = excelTime(date)
+		}
+	}
+	return records, nil
+}
+
+// excelTime returns time of an Excel serial date value as "2006-01-02 15:04:05" or str itself if it is not a number.
+// Exported dates are serial numbers of the wall clock time, so they are read in Kyiv time as other layouts
+func excelTime(str string) string {
+	serial, err := strconv.ParseFloat(strings.TrimSpace(str), 64)
+	if err != nil {
+		return str
+	}
+	t, err := excelize.ExcelDateToTime(serial, false)
+	if err != nil {
+		return str
+	}
+	return t.Round(time.Second).Format(timeLayouts[1])
+}
+
+// tableRecords makes records from rows where the first row is a header, empty rows are skipped
+func tableRecords(rows [][]string) []map[string]string {
+	if len(rows) == 0 {
+		return nil
+	}
+	header := rows[0]
+	records := make([]map[string]string, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		if strings.TrimSpace(strings.Join(row, "")) == "" {
+			continue
+		}
+		values := make(map[string]string, len(header))
+		for k, name := range header {
+			if name = strings.ToLower(strings.TrimSpace(name)); name != "" && k < len(row) {
+				values[name] = row[k]
+			}
+		}
+		records = append(records, values)
+	}
+	return records
+}
+
+func detectDelim(header string) rune {
+	delim, count := ',', strings.Count(header, ",")
+	for _, d := range []rune{';', '\t', '|'} {
+		if c := strings.Count(header, string(d)); c > count {
+			delim, count = d, c
+		}
+	}
+	return delim
+}
+
+func attrsMap(attrs []xml.Attr) map[string]string {
+	res := make(map[string]string, len(attrs))
+	for _, a := range attrs {
+		res[strings.ToLower(a.Name.Local)] = a.Value
+	}
+	return res
+}
+
+func setStatementsAttrs(statements *p24.Statements, attrs []xml.Attr) error {
+	for _, a := range attrs {
+		var err error
+		switch a.Name.Local {
+		case "status":
+			statements.Status = a.Value
+		case "credit":
+			err = statements.Credit.UnmarshalText([]byte(normalizeNumber(a.Value)))
+		case "debet":
+			err = statements.Debet.UnmarshalText([]byte(normalizeNumber(a.Value)))
+		}
+		if err != nil {
+			return errors.Wrapf(err, "invalid %q attribute", a.Name.Local)
+		}
+	}
+	return nil
+}
+
+// MakeStatement makes p24.Statement from values by lower case field names, see ReadRecords.
+// p24 api "trandate" and "trantime" pair is supported too
+func MakeStatement(values map[string]string) (p24.Statement, error) {
+	var s p24.Statement
+	if t, ok := values["trantime"]; ok {
+		values["trandate"] = strings.TrimSpace(values["trandate"] + " " + t)
+	}
+
+	v := reflect.ValueOf(&s).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Name
+		str, ok := values[strings.ToLower(name)]
+		if !ok || str == "" {
+			continue
+		}
+		if err := setField(v.Field(i), str); err != nil {
+			return p24.Statement{}, errors.Wrapf(err, "invalid %q field", name)
+		}
+	}
+	return s, nil
+}
+
+func setField(field reflect.Value, str string) error {
+	switch field.Interface().(type) {
+	case string:
+		field.SetString(str)
+	case time.Time:
+		t, err := parseTime(str)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(t))
+	case p24.Funds:
+		var funds p24.Funds
+		if err := funds.UnmarshalText([]byte(normalizeNumber(str))); err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(funds))
+	default:
+		return errors.Errorf("%s type is unsupported", field.Type())
+	}
+	return nil
+}
+
+func parseTime(str string) (time.Time, error) {
+	for _, l := range timeLayouts {
+		if t, err := time.ParseInLocation(l, str, kievLocation); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.Errorf("%q has unknown time layout", str)
+}
+
+// normalizeNumber replaces decimal comma of an amount exported with "uk" locale to dot
+func normalizeNumber(str string) string {
+	return strings.Replace(strings.TrimSpace(str), ",", ".", 1)
+}
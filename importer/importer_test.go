@@ -0,0 +1,97 @@
+package importer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/export"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ReadExported(t *testing.T) {
+	statements := p24.Statements{
+		Status: "excellent",
+		Credit: 10000,
+		Debet:  29550,
+		Statements: []p24.Statement{
+			{
+				Card:        "1111111111111112",
+				Appcode:     "123",
+				TranDate:    time.Date(2022, 3, 15, 10, 30, 0, 0, kievLocation),
+				Terminal:    "Kyiv",
+				Description: "Silpo, \"Kyiv\"",
+				Amount:      p24.Funds{Currency: "UAH", Amount: -29550},
+				CardAmount:  p24.Funds{Currency: "UAH", Amount: -29550},
+				Rest:        p24.Funds{Currency: "UAH", Amount: 100000},
+			},
+			{
+				Card:       "1111111111111112",
+				Appcode:    "124",
+				TranDate:   time.Date(2022, 3, 16, 0, 0, 1, 0, kievLocation),
+				Amount:     p24.Funds{Currency: "USD", Amount: 300},
+				CardAmount: p24.Funds{Currency: "UAH", Amount: 10000},
+				Rest:       p24.Funds{Currency: "UAH", Amount: 110000},
+			},
+		},
+	}
+	format, err := export.MakeFormat(
+		"Card|Appcode|TranDate|Amount|CardAmount|Rest|Terminal|Description|Extra=year(TranDate)|;",
+		export.DefaultFormatParser(p24.Statement{}),
+	)
+	require.NoError(t, err)
+
+	buff := bytes.NewBuffer(nil)
+	require.NoError(t, export.NewXML(statements).Export(buff, format))
+	actual, err := ReadXML(buff)
+	require.NoError(t, err)
+	requireStatementsEqual(t, statements, actual)
+
+	buff.Reset()
+	format.Locale = export.Locales["uk"]
+	require.NoError(t, export.NewCSV(statements).Export(buff, format))
+	actual, err = ReadCSV(buff)
+	require.NoError(t, err)
+	requireStatementsEqual(t, p24.Statements{Statements: statements.Statements}, actual)
+
+	buff.Reset()
+	require.NoError(t, export.NewXLSX(statements).Export(buff, format))
+	actual, err = ReadXLSX(buff)
+	require.NoError(t, err)
+	requireStatementsEqual(t, p24.Statements{Statements: statements.Statements}, actual)
+	// default locale writes dates as Excel serial numbers formatted without seconds
+	buff.Reset()
+	format.Locale = export.Locale{}
+	require.NoError(t, export.NewXLSX(statements).Export(buff, format))
+	actual, err = ReadXLSX(buff)
+	require.NoError(t, err)
+	requireStatementsEqual(t, p24.Statements{Statements: statements.Statements}, actual)
+}
+
+func Test_ReadAPIXML(t *testing.T) {
+	data := `<statements status="excellent" credit="0.0" debet="0.3">
+		<statement card="5168742060221193" appcode="801111" trandate="2013-09-02" trantime="13:34:00"
+			amount="0.10 UAH" cardamount="-0.10 UAH" rest="0.95 UAH" terminal="PrivatBank, CS980400" description="test"/>
+	</statements>`
+	actual, err := ReadXML(strings.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, p24.Amount(30), actual.Debet)
+	require.Len(t, actual.Statements, 1)
+	require.Equal(t, time.Date(2013, 9, 2, 13, 34, 0, 0, kievLocation).Unix(), actual.Statements[0].TranDate.Unix())
+	require.Equal(t, p24.Funds{Currency: "UAH", Amount: -10}, actual.Statements[0].CardAmount)
+}
+
+func requireStatementsEqual(t *testing.T, expected, actual p24.Statements) {
+	require.Equal(t, expected.Status, actual.Status)
+	require.Equal(t, expected.Credit, actual.Credit)
+	require.Equal(t, expected.Debet, actual.Debet)
+	require.Len(t, actual.Statements, len(expected.Statements))
+	for i := range expected.Statements {
+		e, a := expected.Statements[i], actual.Statements[i]
+		require.True(t, e.TranDate.Equal(a.TranDate), "%s != %s", e.TranDate, a.TranDate)
+		e.TranDate, a.TranDate = time.Time{}, time.Time{}
+		require.Equal(t, e, a)
+	}
+}
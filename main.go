@@ -15,9 +15,23 @@ import (
 // nolint:govet // need to save commands order
 type Opts struct {
 	BalanceCmd    cmd.BalanceCmd    `command:"balance" description:"Get card balance of specified merchant"`
-	StatementsCmd cmd.StatementsCmd `command:"statements" description:"Load statements list for specified merchant and export it to a file/stdout"` // nolint
+	StatementsCmd cmd.StatementsCmd `command:"statements" description:"Load statements list for specified merchant and export it to a file/stdout"`                  // nolint
+	CategorizeCmd cmd.CategorizeCmd `command:"categorize" description:"Categorise statements list by rules file, --explain shows the rule fired for each statement"` // nolint
+	ReportCmd     cmd.ReportCmd     `command:"report" description:"Aggregate statements list by month, week, category, terminal, currency or weekday"`
+	RecurringCmd  cmd.RecurringCmd  `command:"recurring" description:"Detect recurring payments and subscriptions with next expected dates and missing charges"` // nolint
+	ForecastCmd   cmd.ForecastCmd   `command:"forecast" description:"Project daily balance by recurring statements and flag days below threshold"`
+	AnomaliesCmd  cmd.AnomaliesCmd  `command:"anomalies" description:"Detect unusual amounts, new terminals, odd hours and daily spikes of statements"`
+	ContinuityCmd cmd.ContinuityCmd `command:"continuity" description:"Check that each statement Rest is the previous Rest plus CardAmount and export daily balances"` // nolint
+	FeesCmd       cmd.FeesCmd       `command:"fees" description:"Report effective and reference exchange rates and conversion fees of foreign currency statements"`    // nolint
+	ReconcileCmd  cmd.ReconcileCmd  `command:"reconcile" description:"Match incoming statements to expected payments by reference, amount and date"`
+	TaxCmd        cmd.TaxCmd        `command:"tax" description:"Sole proprietor single tax income ledger and declaration"`
+	RatesCmd      cmd.RatesCmd      `command:"rates" description:"Fetch NBU and PrivatBank exchange rates to a local archive and export them"`
+	LabelCmd      cmd.LabelCmd      `command:"label" description:"Import manually assigned categories of statements from edited exports"`
+	TrainCmd      cmd.TrainCmd      `command:"train" description:"Train category classifier on labelled statements exports"`
 	VersionCmd    cmd.VersionCmd    `command:"version" description:"Show the 'p24' version information"`
 	Debug         bool              `long:"debug" description:"Is debug mode?"`
+	Locale        string            `long:"locale" choice:"en" choice:"uk" description:"Locale of exported dates and numbers"`
+	Config        flags.Filename    `long:"config" description:"Config file, \"$XDG_CONFIG_HOME/p24/config.yml\" by default"`
 }
 
 var (
@@ -34,7 +48,9 @@ func main() {
 
 		c := command.(cmd.CommonOptionsCommander)
 		c.SetCommon(cmd.CommonOpts{
-			Debug: opts.Debug,
+			Debug:      opts.Debug,
+			Locale:     opts.Locale,
+			ConfigFile: string(opts.Config),
 			BuildInfo: cmd.BuildInfo{
 				Version: version,
 				Commit:  commit,
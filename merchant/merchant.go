@@ -0,0 +1,159 @@
+// Package merchant normalises terminal and description spellings to canonical merchant names
+package merchant
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/export"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// translit is Ukrainian and Russian letters transliteration to latin, e.g. "сільпо" to "silpo"
+var translit = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "h", 'ґ': "g", 'д': "d", 'е': "e", 'є': "ie", 'ж': "zh", 'з': "z",
+	'и': "y", 'і': "i", 'ї': "i", 'й': "i", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p",
+	'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ь': "", 'ю': "iu", 'я': "ia", 'ы': "y", 'э': "e", 'ё': "e", 'ъ': "", '\'': "", '’': "", 'ʼ': "",
+}
+
+// stopWords are dropped from names: legal forms, domains, countries and cities
+var stopWords = map[string]bool{
+	"tov": true, "tzov": true, "fop": true, "pp": true, "pat": true, "prat": true, "llc": true, "ltd": true,
+	"inc": true, "gmbh": true, "ooo": true, "com": true, "net": true, "org": true, "www": true, "ua": true,
+	"ukraine": true, "ukraina": true, "kyiv": true, "kiev": true, "kyev": true, "kyiev": true, "lviv": true,
+	"lvov": true, "odesa": true, "odessa": true, "kharkiv": true, "kharkov": true, "dnipro": true, "dnepr": true,
+	"zaporizhzhia": true, "vinnytsia": true, "poltava": true, "chernihiv": true, "ivano": true, "frankivsk": true,
+}
+
+var nonLetterRegexp = regexp.MustCompile(`[^\p{L}]+`)
+
+// Alias maps Terminal, Description or normalised key matched by any regexp to the canonical name
+type Alias struct {
+	re []*regexp.Regexp
+
+	Name  string   `yaml:"name"`  // required
+	Match []string `yaml:"match"` // regexps, at least one is required
+}
+
+// Aliases is an aliases file
+type Aliases struct {
+	Aliases []*Alias `yaml:"aliases"`
+}
+
+// Normalizer maps statements to canonical merchant names by aliases and builtin heuristics.
+// nil Normalizer uses heuristics only
+type Normalizer struct {
+	aliases []*Alias
+}
+
+// LoadAliases reads aliases yaml file
+func LoadAliases(filename string) (*Aliases, error) {
+	data, err := os.ReadFile(filepath.Clean(filename))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read merchant aliases %q", filename)
+	}
+	var file Aliases
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse merchant aliases %q", filename)
+	}
+	return &file, nil
+}
+
+// NewNormalizer returns Normalizer of aliases, aliases can be nil
+func NewNormalizer(aliases *Aliases) (*Normalizer, error) {
+	n := &Normalizer{}
+	if aliases == nil {
+		return n, nil
+	}
+	for i, a := range aliases.Aliases {
+		if a.Name == "" {
+			return nil, errors.Errorf("alias #%d: name is required", i+1)
+		}
+		if len(a.Match) == 0 {
+			return nil, errors.Errorf("alias %q: match is required", a.Name)
+		}
+		alias := &Alias{Name: a.Name, Match: a.Match}
+		for _, m := range a.Match {
+			re, err := regexp.Compile(m)
+			if err != nil {
+				return nil, errors.Wrapf(err, "alias %q: invalid regexp", a.Name)
+			}
+			alias.re = append(alias.re, re)
+		}
+		n.aliases = append(n.aliases, alias)
+	}
+	return n, nil
+}
+
+// Name returns the name of the first alias matching Terminal, Description or the normalised key of s,
+// otherwise the key in title case. The key is built from Terminal or Description if Terminal is empty
+func (n *Normalizer) Name(s *p24.Statement) string {
+	raw := s.Terminal
+	if strings.TrimSpace(raw) == "" {
+		raw = s.Description
+	}
+	key := Key(raw)
+	if n != nil {
+		for _, a := range n.aliases {
+			for _, re := range a.re {
+				if re.MatchString(s.Terminal) || re.MatchString(s.Description) || re.MatchString(key) {
+					return a.Name
+				}
+			}
+		}
+	}
+	return title(key)
+}
+
+// Key returns lowercase transliterated str without digits, punctuation, legal forms, domains and cities,
+// e.g. "atb market" for "АТБ-Маркет №1234 Київ" and "ATB-Market 1234 KYIV"
+func Key(str string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(str) {
+		if t, ok := translit[r]; ok {
+			b.WriteString(t)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	var words []string
+	for _, w := range strings.Fields(nonLetterRegexp.ReplaceAllString(b.String(), " ")) {
+		if !stopWords[w] {
+			words = append(words, w)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// Fields returns Merchant virtual field of statements, see Normalizer.Name
+func Fields(n *Normalizer) export.VirtualFields {
+	return export.VirtualFields{
+		"Merchant": func(obj interface{}) (interface{}, error) {
+			switch s := obj.(type) {
+			case *p24.Statement:
+				return n.Name(s), nil
+			case p24.Statement:
+				return n.Name(&s), nil
+			default:
+				return nil, errors.Errorf("%T is not a statement", obj)
+			}
+		},
+	}
+}
+
+// title returns str with capitalised words
+func title(str string) string {
+	words := strings.Fields(str)
+	for i, w := range words {
+		r := []rune(w)
+		r[0] = unicode.ToUpper(r[0])
+		words[i] = string(r)
+	}
+	return strings.Join(words, " ")
+}
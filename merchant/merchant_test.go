@@ -0,0 +1,66 @@
+package merchant
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/dimboknv/p24"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Key(t *testing.T) {
+	cases := []struct {
+		str, expected string
+	}{
+		{"АТБ-Маркет №1234 Київ", "atb market"},
+		{"ATB-Market 1234 KYIV", "atb market"},
+		{"Сільпо", "silpo"},
+		{"SILPO 12 LVIV", "silpo"},
+		{"Rozetka.com.ua", "rozetka"},
+		{"ТОВ \"Нова Пошта\"", "nova poshta"},
+		{"1234", ""},
+	}
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			require.Equal(t, c.expected, Key(c.str))
+		})
+	}
+}
+
+func Test_Normalizer_Name(t *testing.T) {
+	n, err := NewNormalizer(&Aliases{Aliases: []*Alias{
+		{Name: "ATB", Match: []string{"^atb market$"}},
+		{Name: "Nova Poshta", Match: []string{"(?i)nova\\s*poshta|нова пошта"}},
+	}})
+	require.NoError(t, err)
+
+	cases := []struct {
+		normalizer *Normalizer
+		statement  p24.Statement
+		expected   string
+	}{
+		{n, p24.Statement{Terminal: "АТБ-Маркет №1234 Київ", Description: "Покупка"}, "ATB"},
+		{n, p24.Statement{Terminal: "ATB-Market 0567", Description: "Purchase"}, "ATB"},
+		{n, p24.Statement{Description: "Оплата Нова Пошта, ТТН 2045"}, "Nova Poshta"},
+		{n, p24.Statement{Terminal: "SILPO 12 LVIV"}, "Silpo"},
+		{nil, p24.Statement{Terminal: "ATB-Market 0567"}, "Atb Market"},
+		{nil, p24.Statement{Terminal: " ", Description: "Rozetka.com.ua"}, "Rozetka"},
+	}
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			require.Equal(t, c.expected, c.normalizer.Name(&c.statement))
+			v, err := Fields(c.normalizer)["Merchant"](c.statement)
+			require.NoError(t, err)
+			require.Equal(t, c.expected, v)
+		})
+	}
+
+	_, err = NewNormalizer(&Aliases{Aliases: []*Alias{{Name: "ATB"}}})
+	require.Error(t, err)
+	_, err = NewNormalizer(&Aliases{Aliases: []*Alias{{Match: []string{"atb"}}}})
+	require.Error(t, err)
+	_, err = NewNormalizer(&Aliases{Aliases: []*Alias{{Name: "ATB", Match: []string{"("}}}})
+	require.Error(t, err)
+}
@@ -0,0 +1,104 @@
+package pipeline
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/export"
+	"github.com/dimboknv/p24-cli/expr"
+	"github.com/pkg/errors"
+)
+
+// Fingerprint returns stable identifier of s built from
+// Card, Appcode, TranDate, Amount, CardAmount and Terminal.
+// TranDate is used in UTC so fingerprint doesn't depend on time zone
+func Fingerprint(s *p24.Statement) string {
+	h := sha256.New()
+	_, _ = fmt.Fprintf(h, "%s\x00%s\x00%d\x00%s\x00%s\x00%s",
+		s.Card, s.Appcode, s.TranDate.UTC().Unix(), s.Amount, s.CardAmount, s.Terminal)
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// Fields returns virtual fields of statements provided by pipeline:
+// - Fingerprint, see Fingerprint func
+func Fields() export.VirtualFields {
+	return export.VirtualFields{
+		"Fingerprint": statementField(func(s *p24.Statement) (interface{}, error) {
+			return Fingerprint(s), nil
+		}),
+	}
+}
+
+// statementField adapts statement func to export.VirtualField
+func statementField(f func(s *p24.Statement) (interface{}, error)) export.VirtualField {
+	return func(obj interface{}) (interface{}, error) {
+		switch s := obj.(type) {
+		case *p24.Statement:
+			return f(s)
+		case p24.Statement:
+			return f(&s)
+		default:
+			return nil, errors.Errorf("%T is not a statement", obj)
+		}
+	}
+}
+
+// ParseDedupKeys parses comma separated list of expressions which values
+// identify a statement, e.g. "Card,Appcode,TranDate". Empty str means Fingerprint
+func ParseDedupKeys(str string, fields export.VirtualFields) ([]*expr.Expr, error) {
+	if strings.TrimSpace(str) == "" {
+		return nil, nil
+	}
+
+	var keys []*expr.Expr
+	for _, s := range SplitTopLevel(str, ',') {
+		e, err := expr.Parse(s)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid dedup key %q", s)
+		}
+		if err := fields.Check(p24.Statement{}, e); err != nil {
+			return nil, errors.Wrapf(err, "invalid dedup key %q", s)
+		}
+		keys = append(keys, e)
+	}
+	return keys, nil
+}
+
+// Dedup removes duplicated statements, the first one of duplicates is kept.
+// Statements are identified by Fingerprint if there are no keys.
+// Returns statements without duplicates and count of dropped statements
+func Dedup(statements []p24.Statement, keys []*expr.Expr, fields export.VirtualFields) ([]p24.Statement, int, error) {
+	seen := make(map[string]struct{}, len(statements))
+	res := make([]p24.Statement, 0, len(statements))
+	for i := range statements {
+		id, err := dedupKey(&statements[i], keys, fields)
+		if err != nil {
+			return nil, 0, err
+		}
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		res = append(res, statements[i])
+	}
+	return res, len(statements) - len(res), nil
+}
+
+func dedupKey(s *p24.Statement, keys []*expr.Expr, fields export.VirtualFields) (string, error) {
+	if len(keys) == 0 {
+		return Fingerprint(s), nil
+	}
+
+	values := make([]string, len(keys))
+	for i, k := range keys {
+		v, err := fields.Env(s).Eval(k)
+		if err != nil {
+			return "", errors.Wrapf(err, "dedup key %q", k)
+		}
+		values[i] = fmt.Sprint(v)
+	}
+	return strings.Join(values, "\x00"), nil
+}
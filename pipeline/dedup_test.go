@@ -0,0 +1,81 @@
+package pipeline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Dedup(t *testing.T) {
+	kiev := p24.NewKievLocation()
+	s1 := p24.Statement{
+		Card:       "1111111111111112",
+		Appcode:    "1",
+		TranDate:   time.Date(2022, 1, 1, 10, 0, 0, 0, kiev),
+		CardAmount: p24.Funds{Currency: "UAH", Amount: -100},
+		Rest:       p24.Funds{Currency: "UAH", Amount: 1000},
+	}
+	// the same statement in UTC with different rest is the same transaction
+	s1UTC := s1
+	s1UTC.TranDate, s1UTC.Rest.Amount = s1.TranDate.UTC(), 900
+	s2 := s1
+	s2.Appcode = "2"
+
+	require.Equal(t, Fingerprint(&s1), Fingerprint(&s1UTC))
+	require.NotEqual(t, Fingerprint(&s1), Fingerprint(&s2))
+	require.Len(t, Fingerprint(&s1), 16)
+
+	res, dropped, err := Dedup([]p24.Statement{s1, s2, s1UTC, s2}, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, dropped)
+	require.Equal(t, []p24.Statement{s1, s2}, res)
+
+	keys, err := ParseDedupKeys("Card, CardAmount", Fields())
+	require.NoError(t, err)
+	res, dropped, err = Dedup([]p24.Statement{s1, s2, s1UTC}, keys, Fields())
+	require.NoError(t, err)
+	require.Equal(t, 2, dropped)
+	require.Equal(t, []p24.Statement{s1}, res)
+
+	_, err = ParseDedupKeys("Card,Unknown", Fields())
+	require.Error(t, err)
+
+	fingerprint, err := Fields()["Fingerprint"](&s1)
+	require.NoError(t, err)
+	require.Equal(t, Fingerprint(&s1), fingerprint)
+}
+
+func Test_Recount(t *testing.T) {
+	statements := p24.Statements{
+		Statements: []p24.Statement{
+			{CardAmount: p24.Funds{Currency: "UAH", Amount: -100}},
+			{CardAmount: p24.Funds{Currency: "UAH", Amount: 250}},
+			{CardAmount: p24.Funds{Currency: "UAH", Amount: -50}},
+		},
+		Credit: 1,
+		Debet:  1,
+	}
+	Recount(&statements)
+	require.Equal(t, p24.Amount(250), statements.Credit)
+	require.Equal(t, p24.Amount(150), statements.Debet)
+
+	statements.Statements = append(statements.Statements, p24.Statement{CardAmount: p24.Funds{Currency: "USD", Amount: -10}})
+	Recount(&statements)
+	require.Equal(t, p24.Amount(0), statements.Credit)
+	require.Equal(t, p24.Amount(0), statements.Debet)
+
+	totals, err := Totals(statements.Statements, nil)
+	require.NoError(t, err)
+	require.Equal(t, []Total{{Currency: "UAH", Credit: 250, Debet: 150}, {Currency: "USD", Debet: 10}}, totals)
+
+	totals, err = Totals(statements.Statements, func(s *p24.Statement) (p24.Funds, error) {
+		if s.CardAmount.Currency == "USD" {
+			return p24.Funds{Currency: "UAH", Amount: s.CardAmount.Amount * 40}, nil
+		}
+		return s.CardAmount, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []Total{{Currency: "UAH", Credit: 250, Debet: 550}}, totals)
+}
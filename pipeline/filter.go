@@ -0,0 +1,51 @@
+package pipeline
+
+import (
+	"strings"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/export"
+	"github.com/dimboknv/p24-cli/expr"
+	"github.com/pkg/errors"
+)
+
+// ParseWhere parses boolean filter expression, e.g. `CardAmount.Amount < 0 && Description ~ "(?i)uber"`.
+// Returns nil if str is empty
+func ParseWhere(str string, fields export.VirtualFields) (*expr.Expr, error) {
+	if strings.TrimSpace(str) == "" {
+		return nil, nil
+	}
+
+	e, err := expr.Parse(str)
+	if err != nil {
+		return nil, err
+	}
+	if err := fields.Check(p24.Statement{}, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Filter returns statements for which where expression is true. All statements are returned if where is nil
+func Filter(statements []p24.Statement, where *expr.Expr, fields export.VirtualFields) ([]p24.Statement, error) {
+	if where == nil {
+		return statements, nil
+	}
+
+	res := make([]p24.Statement, 0, len(statements))
+	for i := range statements {
+		env := fields.Env(&statements[i])
+		v, err := env.Eval(where)
+		if err != nil {
+			return nil, errors.Wrapf(err, "statement %s", Fingerprint(&statements[i]))
+		}
+		ok, isBool := v.(bool)
+		if !isBool {
+			return nil, errors.Errorf("%q is not a boolean expression", where)
+		}
+		if ok {
+			res = append(res, statements[i])
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,49 @@
+package pipeline
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Filter(t *testing.T) {
+	statements := []p24.Statement{
+		{Appcode: "1", TranDate: time.Date(2022, 1, 1, 10, 0, 0, 0, time.UTC), CardAmount: p24.Funds{Currency: "UAH", Amount: -1000}, Description: "UBER trip", Terminal: "T1"},
+		{Appcode: "2", TranDate: time.Date(2022, 1, 2, 10, 0, 0, 0, time.UTC), CardAmount: p24.Funds{Currency: "UAH", Amount: -2000}, Description: "Uber trip"},
+		{Appcode: "3", TranDate: time.Date(2022, 1, 3, 10, 0, 0, 0, time.UTC), CardAmount: p24.Funds{Currency: "UAH", Amount: 5000}, Description: "Salary"},
+	}
+
+	cases := []struct {
+		where    string
+		expected []string
+		withErr  bool
+	}{
+		{where: "", expected: []string{"1", "2", "3"}},
+		{where: `CardAmount.Amount < 0 && Description ~ "(?i)uber" && Terminal != ""`, expected: []string{"1"}},
+		{where: `CardAmount < -15 || TranDate >= "2022-01-03"`, expected: []string{"2", "3"}},
+		{where: `!(Description ~ "^U")`, expected: []string{"3"}},
+		{where: `Fingerprint == "unknown"`, expected: []string{}},
+		{where: `Unknown > 0`, withErr: true},
+		{where: `CardAmount.Amount`, withErr: true},
+	}
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			where, err := ParseWhere(c.where, Fields())
+			if err == nil {
+				var res []p24.Statement
+				if res, err = Filter(statements, where, Fields()); err == nil {
+					appcodes := make([]string, len(res))
+					for k := range res {
+						appcodes[k] = res[k].Appcode
+					}
+					require.Equal(t, c.expected, appcodes)
+				}
+			}
+			require.True(t, c.withErr == (err != nil), err)
+		})
+	}
+}
@@ -0,0 +1,159 @@
+// Package pipeline implements processing stages of statements list
+// applied between fetching and exporting
+package pipeline
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/export"
+	"github.com/dimboknv/p24-cli/expr"
+	"github.com/pkg/errors"
+)
+
+// SortKey is a single sort key, expression value of a statement
+type SortKey struct {
+	Expr *expr.Expr
+	Desc bool
+}
+
+// String returns SortKey string representation
+func (k SortKey) String() string {
+	if k.Desc {
+		return "-" + k.Expr.String()
+	}
+	return k.Expr.String()
+}
+
+// defaultSortKeys is a chronological order with tie-breakers by all other statement fields.
+// It makes statements order independent of loading order
+var defaultSortKeys = []SortKey{
+	{Expr: expr.MustParse("TranDate")},
+	{Expr: expr.MustParse("Card")},
+	{Expr: expr.MustParse("Appcode")},
+	{Expr: expr.MustParse("CardAmount.Currency")},
+	{Expr: expr.MustParse("CardAmount.Amount")},
+	{Expr: expr.MustParse("Amount.Currency")},
+	{Expr: expr.MustParse("Amount.Amount")},
+	{Expr: expr.MustParse("Rest.Amount")},
+	{Expr: expr.MustParse("Terminal")},
+	{Expr: expr.MustParse("Description")},
+}
+
+// ParseSortKeys parses comma separated list of sort keys, e.g. "TranDate,-CardAmount".
+// Key is a statement field, virtual field or an expression, '-' prefix means descending order
+func ParseSortKeys(str string, fields export.VirtualFields) ([]SortKey, error) {
+	if strings.TrimSpace(str) == "" {
+		return nil, nil
+	}
+
+	var keys []SortKey
+	for _, s := range SplitTopLevel(str, ',') {
+		s = strings.TrimSpace(s)
+		k := SortKey{Desc: strings.HasPrefix(s, "-")}
+		if k.Desc {
+			s = s[1:]
+		}
+
+		e, err := expr.Parse(s)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid sort key %q", s)
+		}
+		if err := fields.Check(p24.Statement{}, e); err != nil {
+			return nil, errors.Wrapf(err, "invalid sort key %q", s)
+		}
+		k.Expr = e
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// Sort sorts statements in place by keys. Statements are sorted chronologically if there are no keys.
+// Order of statements with equal keys is chronological too, so result is deterministic
+func Sort(statements []p24.Statement, keys []SortKey, fields export.VirtualFields) error {
+	keys = append(append([]SortKey{}, keys...), defaultSortKeys...)
+
+	// evaluate keys once per statement
+	values := make([][]interface{}, len(statements))
+	for i := range statements {
+		values[i] = make([]interface{}, len(keys))
+		for k, key := range keys {
+			v, err := fields.Env(&statements[i]).Eval(key.Expr)
+			if err != nil {
+				return errors.Wrapf(err, "sort key %q", key)
+			}
+			values[i][k] = v
+		}
+	}
+
+	idx := make([]int, len(statements))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool {
+		x, y := values[idx[i]], values[idx[j]]
+		for k, key := range keys {
+			c := compareSortValues(x[k], y[k])
+			if key.Desc {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+
+	sorted := make([]p24.Statement, len(statements))
+	for i, k := range idx {
+		sorted[i] = statements[k]
+	}
+	copy(statements, sorted)
+	return nil
+}
+
+// compareSortValues is like expr.Compare but it is total: funds with different
+// currencies are ordered by currency and incomparable values by their string representation
+func compareSortValues(x, y interface{}) int {
+	if fx, ok := x.(p24.Funds); ok {
+		if fy, ok := y.(p24.Funds); ok && fx.Currency != fy.Currency {
+			return strings.Compare(fx.Currency, fy.Currency)
+		}
+	}
+	c, err := expr.Compare(x, y)
+	if err != nil {
+		return strings.Compare(fmt.Sprint(x), fmt.Sprint(y))
+	}
+	return c
+}
+
+// SplitTopLevel splits str by sep skipping separators inside parentheses and quotes
+func SplitTopLevel(str string, sep rune) []string {
+	var (
+		res   []string
+		depth int
+		quote rune
+		start int
+	)
+	runes := []rune(str)
+	for i, c := range runes {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == sep && depth == 0:
+			res = append(res, string(runes[start:i]))
+			start = i + 1
+		}
+	}
+	return append(res, string(runes[start:]))
+}
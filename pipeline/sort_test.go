@@ -0,0 +1,63 @@
+package pipeline
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Sort(t *testing.T) {
+	statements := []p24.Statement{
+		{Appcode: "1", TranDate: time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC), CardAmount: p24.Funds{Currency: "UAH", Amount: -100}},
+		{Appcode: "2", TranDate: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), CardAmount: p24.Funds{Currency: "UAH", Amount: -300}},
+		{Appcode: "3", TranDate: time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC), CardAmount: p24.Funds{Currency: "UAH", Amount: 200}},
+		{Appcode: "4", TranDate: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), CardAmount: p24.Funds{Currency: "UAH", Amount: 500}},
+		{Appcode: "5", TranDate: time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC), CardAmount: p24.Funds{Currency: "USD", Amount: 10}},
+	}
+
+	cases := []struct {
+		keys     string
+		expected []string
+		withErr  bool
+	}{
+		{keys: "", expected: []string{"2", "4", "3", "5", "1"}},
+		{keys: "-TranDate", expected: []string{"1", "3", "5", "2", "4"}},
+		{keys: "TranDate,-CardAmount", expected: []string{"4", "2", "5", "3", "1"}},
+		{keys: "abs(CardAmount.Amount)", expected: []string{"5", "1", "3", "2", "4"}},
+		{keys: "-if(CardAmount < 0, 1, 0), Appcode", expected: []string{"1", "2", "3", "4", "5"}},
+		{keys: "Unknown", withErr: true},
+		{keys: "TranDate,(", withErr: true},
+	}
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			keys, err := ParseSortKeys(c.keys, nil)
+			require.True(t, c.withErr == (err != nil), err)
+			if err != nil {
+				return
+			}
+
+			// sort result shouldn't depend on input order
+			for _, input := range [][]p24.Statement{statements, reversed(statements)} {
+				actual := append([]p24.Statement{}, input...)
+				require.NoError(t, Sort(actual, keys, nil))
+				appcodes := make([]string, len(actual))
+				for k := range actual {
+					appcodes[k] = actual[k].Appcode
+				}
+				require.Equal(t, c.expected, appcodes)
+			}
+		})
+	}
+}
+
+func reversed(statements []p24.Statement) []p24.Statement {
+	res := make([]p24.Statement, len(statements))
+	for i := range statements {
+		res[len(statements)-1-i] = statements[i]
+	}
+	return res
+}
@@ -0,0 +1,59 @@
+package pipeline
+
+import (
+	"sort"
+
+	"github.com/dimboknv/p24"
+	"github.com/pkg/errors"
+)
+
+// Total is a sum of incomes and a sum of absolute values of expenses in a currency
+type Total struct {
+	Currency string
+	Credit   p24.Amount
+	Debet    p24.Amount
+}
+
+// Totals returns totals of statements amounts per currency sorted by currency.
+// amount returns amount of a statement, CardAmount is used if amount is nil
+func Totals(statements []p24.Statement, amount func(s *p24.Statement) (p24.Funds, error)) ([]Total, error) {
+	if amount == nil {
+		amount = func(s *p24.Statement) (p24.Funds, error) { return s.CardAmount, nil }
+	}
+
+	byCurrency := map[string]*Total{}
+	for i := range statements {
+		f, err := amount(&statements[i])
+		if err != nil {
+			return nil, errors.Wrapf(err, "statement %s", Fingerprint(&statements[i]))
+		}
+		t, ok := byCurrency[f.Currency]
+		if !ok {
+			t = &Total{Currency: f.Currency}
+			byCurrency[f.Currency] = t
+		}
+		if f.Amount > 0 {
+			t.Credit += f.Amount
+		} else {
+			t.Debet -= f.Amount
+		}
+	}
+
+	res := make([]Total, 0, len(byCurrency))
+	for _, t := range byCurrency {
+		res = append(res, *t)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Currency < res[j].Currency })
+	return res, nil
+}
+
+// Recount sets statements Credit and Debet by statements CardAmount.
+// Credit is a sum of incomes and Debet is a sum of absolute values of expenses.
+// Sums of different currencies are meaningless, so Credit and Debet are zero if there are several currencies
+func Recount(statements *p24.Statements) {
+	totals, _ := Totals(statements.Statements, nil) // CardAmount has no errors
+	statements.Credit, statements.Debet = 0, 0
+	if len(totals) == 1 {
+		statements.Credit, statements.Debet = totals[0].Credit, totals[0].Debet
+	}
+}
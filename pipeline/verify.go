@@ -0,0 +1,101 @@
+package pipeline
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dimboknv/p24"
+)
+
+// Verification finding kinds
+const (
+	FindingTotals    = "totals"
+	FindingDuplicate = "duplicate"
+	FindingCurrency  = "currency"
+	FindingRange     = "range"
+)
+
+// Finding is an integrity problem of a fetched statements list
+type Finding struct {
+	Kind    string
+	Message string
+}
+
+// String returns finding text
+func (f Finding) String() string {
+	return f.Kind + ": " + f.Message
+}
+
+// Verify checks integrity of a statements list fetched for startDate - endDate date range:
+// Credit and Debet equal sums of incomes and expenses CardAmount, there are no duplicated statements,
+// all statements are in the same currency and in the date range. Dates are compared in Kyiv time zone
+func Verify(statements p24.Statements, startDate, endDate time.Time) []Finding {
+	var findings []Finding
+	add := func(kind, format string, args ...interface{}) {
+		findings = append(findings, Finding{Kind: kind, Message: fmt.Sprintf(format, args...)})
+	}
+
+	totals, _ := Totals(statements.Statements, nil) // CardAmount has no errors
+	var credit, debet p24.Amount
+	for _, t := range totals {
+		credit, debet = credit+t.Credit, debet+t.Debet
+	}
+	if credit != statements.Credit || debet != statements.Debet {
+		add(FindingTotals, "credit %s and debet %s differ from statements sums %s and %s",
+			statements.Credit, statements.Debet, credit, debet)
+	}
+	if len(totals) > 1 {
+		currencies := make([]string, len(totals))
+		for i, t := range totals {
+			currencies[i] = t.Currency
+		}
+		add(FindingCurrency, "statements have several currencies %v", currencies)
+	}
+
+	kiev := p24.NewKievLocation()
+	from, to := dateOf(startDate, kiev), dateOf(endDate, kiev)
+	seen := map[string]bool{}
+	for i := range statements.Statements {
+		s := &statements.Statements[i]
+		if fp := Fingerprint(s); seen[fp] {
+			add(FindingDuplicate, "statement %s #%s is duplicated", s.TranDate.Format("2006-01-02 15:04:05"), s.Appcode)
+		} else {
+			seen[fp] = true
+		}
+		if s.Rest.Currency != "" && s.Rest.Currency != s.CardAmount.Currency {
+			add(FindingCurrency, "statement %s #%s rest currency %s differs from amount currency %s",
+				s.TranDate.Format("2006-01-02 15:04:05"), s.Appcode, s.Rest.Currency, s.CardAmount.Currency)
+		}
+		if d := dateOf(s.TranDate, kiev); d.Before(from) || d.After(to) {
+			add(FindingRange, "statement %s #%s is out of %s - %s date range", s.TranDate.Format("2006-01-02 15:04:05"),
+				s.Appcode, from.Format("02.01.2006"), to.Format("02.01.2006"))
+		}
+	}
+	return findings
+}
+
+// VerifyChunks checks that chunks of statements fetched for adjacent date ranges have no duplicated statements
+// between them, duplicates inside a chunk are found by Verify
+func VerifyChunks(chunks []p24.Statements) []Finding {
+	var findings []Finding
+	chunkOf := map[string]int{}
+	for i := range chunks {
+		for k := range chunks[i].Statements {
+			s := &chunks[i].Statements[k]
+			fp := Fingerprint(s)
+			if j, ok := chunkOf[fp]; ok && j != i {
+				findings = append(findings, Finding{Kind: FindingDuplicate, Message: fmt.Sprintf(
+					"statement %s #%s is duplicated in chunks %d and %d", s.TranDate.Format("2006-01-02 15:04:05"), s.Appcode, j+1, i+1)})
+				continue
+			}
+			chunkOf[fp] = i
+		}
+	}
+	return findings
+}
+
+// dateOf returns date of t in loc as UTC midnight, so dates are comparable
+func dateOf(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,92 @@
+package pipeline
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Verify(t *testing.T) {
+	kiev := p24.NewKievLocation()
+	sd, ed := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2022, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		statements p24.Statements
+		expected   []string
+	}{
+		{
+			statements: p24.Statements{Credit: 100, Debet: 50, Statements: []p24.Statement{
+				{Card: "1", Appcode: "1", TranDate: time.Date(2022, 1, 1, 23, 30, 0, 0, kiev), CardAmount: p24.Funds{Currency: "UAH", Amount: 100}, Rest: p24.Funds{Currency: "UAH", Amount: 1000}},
+				{Card: "1", Appcode: "2", TranDate: time.Date(2022, 1, 31, 23, 30, 0, 0, kiev), CardAmount: p24.Funds{Currency: "UAH", Amount: -50}, Rest: p24.Funds{Currency: "UAH", Amount: 1000}},
+			}},
+		},
+		{
+			statements: p24.Statements{Credit: 100, Debet: 30, Statements: []p24.Statement{
+				{Card: "1", Appcode: "1", TranDate: time.Date(2022, 1, 1, 23, 30, 0, 0, kiev), CardAmount: p24.Funds{Currency: "UAH", Amount: 100}, Rest: p24.Funds{Currency: "UAH", Amount: 1000}},
+				{Card: "1", Appcode: "2", TranDate: time.Date(2022, 1, 2, 23, 30, 0, 0, kiev), CardAmount: p24.Funds{Currency: "UAH", Amount: -50}, Rest: p24.Funds{Currency: "UAH", Amount: 1000}},
+			}},
+			expected: []string{FindingTotals},
+		},
+		{
+			statements: p24.Statements{Credit: 200, Statements: []p24.Statement{
+				{Card: "1", Appcode: "1", TranDate: time.Date(2022, 1, 1, 23, 30, 0, 0, kiev), CardAmount: p24.Funds{Currency: "UAH", Amount: 100}, Rest: p24.Funds{Currency: "UAH", Amount: 1000}},
+				{Card: "1", Appcode: "1", TranDate: time.Date(2022, 1, 1, 23, 30, 0, 0, kiev), CardAmount: p24.Funds{Currency: "UAH", Amount: 100}, Rest: p24.Funds{Currency: "UAH", Amount: 1000}},
+			}},
+			expected: []string{FindingDuplicate},
+		},
+		{
+			statements: p24.Statements{Credit: 200, Statements: []p24.Statement{
+				{Card: "1", Appcode: "1", TranDate: time.Date(2022, 1, 1, 23, 30, 0, 0, kiev), CardAmount: p24.Funds{Currency: "UAH", Amount: 100}, Rest: p24.Funds{Currency: "UAH", Amount: 1000}},
+				{Card: "1", Appcode: "2", TranDate: time.Date(2022, 1, 2, 23, 30, 0, 0, kiev), CardAmount: p24.Funds{Currency: "USD", Amount: 100}, Rest: p24.Funds{Currency: "USD", Amount: 1000}},
+			}},
+			expected: []string{FindingCurrency},
+		},
+		{
+			statements: p24.Statements{Credit: 100, Statements: []p24.Statement{
+				{Card: "1", Appcode: "1", TranDate: time.Date(2022, 2, 1, 23, 30, 0, 0, kiev), CardAmount: p24.Funds{Currency: "UAH", Amount: 100}, Rest: p24.Funds{Currency: "UAH", Amount: 1000}},
+			}},
+			expected: []string{FindingRange},
+		},
+	}
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var kinds []string
+			for _, f := range Verify(c.statements, sd, ed) {
+				kinds = append(kinds, f.Kind)
+			}
+			require.Equal(t, c.expected, kinds)
+		})
+	}
+}
+
+func Test_VerifyChunks(t *testing.T) {
+	kiev := p24.NewKievLocation()
+	chunks := []p24.Statements{
+		{Statements: []p24.Statement{
+			{Card: "1", Appcode: "1", TranDate: time.Date(2022, 1, 1, 23, 30, 0, 0, kiev), CardAmount: p24.Funds{Currency: "UAH", Amount: 100}},
+			{Card: "1", Appcode: "2", TranDate: time.Date(2022, 1, 2, 23, 30, 0, 0, kiev), CardAmount: p24.Funds{Currency: "UAH", Amount: 100}},
+			{Card: "1", Appcode: "2", TranDate: time.Date(2022, 1, 2, 23, 30, 0, 0, kiev), CardAmount: p24.Funds{Currency: "UAH", Amount: 100}},
+		}},
+		{Statements: []p24.Statement{
+			{Card: "1", Appcode: "3", TranDate: time.Date(2022, 1, 3, 23, 30, 0, 0, kiev), CardAmount: p24.Funds{Currency: "UAH", Amount: 100}},
+			{Card: "1", Appcode: "1", TranDate: time.Date(2022, 1, 1, 23, 30, 0, 0, kiev), CardAmount: p24.Funds{Currency: "UAH", Amount: 100}},
+		}},
+		{Statements: []p24.Statement{
+			{Card: "1", Appcode: "1", TranDate: time.Date(2022, 1, 1, 23, 30, 0, 0, kiev), CardAmount: p24.Funds{Currency: "UAH", Amount: 100}},
+		}},
+	}
+	var messages []string
+	for _, f := range VerifyChunks(chunks) {
+		require.Equal(t, FindingDuplicate, f.Kind)
+		messages = append(messages, f.Message)
+	}
+	require.Equal(t, []string{
+		"statement 2022-01-01 23:30:00 #1 is duplicated in chunks 1 and 2",
+		"statement 2022-01-01 23:30:00 #1 is duplicated in chunks 1 and 3",
+	}, messages)
+	require.Empty(t, VerifyChunks(chunks[:1]))
+}
@@ -0,0 +1,312 @@
+// Package reconcile matches incoming statements to expected payments, e.g. issued invoices
+package reconcile
+
+import (
+	"bytes"
+	"encoding/csv"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/export"
+	"github.com/pkg/errors"
+)
+
+// Item statuses
+const (
+	StatusMatched    = "matched"    // paid in full, overpayment is shown by Difference
+	StatusPartial    = "partial"    // paid less than expected minus tolerance
+	StatusUnexpected = "unexpected" // incoming statement matched no expected payment
+	StatusUnpaid     = "unpaid"     // expected payment matched no statement
+)
+
+// Match kinds
+const (
+	ByReference = "reference"
+	ByAmount    = "amount"
+)
+
+// DefaultCurrency of expected payments without currency column
+const DefaultCurrency = "UAH"
+
+var dateLayouts = []string{"2006-01-02", "02.01.2006"}
+
+// Expected is an expected payment. Reference is ID if empty
+type Expected struct {
+	ID          string
+	Date        time.Time
+	Amount      p24.Funds
+	Reference   string
+	Description string
+}
+
+// Options of matching
+type Options struct {
+	Tolerance p24.Amount     // allowed absolute difference of paid and expected amounts
+	Window    int            // allowed difference of expected and statement dates in days
+	Reference *regexp.Regexp // extracts a reference from Description by the first submatch or the whole match
+}
+
+// Item is a reconciled expected payment with its statements or an unexpected incoming statement
+type Item struct {
+	Status      string
+	MatchedBy   string
+	ID          string
+	Reference   string
+	Expected    p24.Funds
+	Paid        p24.Funds
+	Difference  p24.Funds // Paid - Expected
+	Count       int       // count of matched statements
+	Description string    // descriptions of matched statements or the expected payment description
+	Card        string
+
+	date     time.Time // expected date
+	tranDate time.Time // the last statement date
+}
+
+// LoadExpected reads expected payments from csv file with id, date, amount and optional currency,
+// reference and description columns. Dates have "2006-01-02" or "02.01.2006" layouts
+func LoadExpected(filename string) ([]Expected, error) {
+	data, err := os.ReadFile(filepath.Clean(filename))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read expected payments %q", filename)
+	}
+	res, err := parseCSV(data)
+	return res, errors.Wrapf(err, "failed to parse expected payments %q", filename)
+}
+
+// Reconcile matches incoming statements to expected payments. Statements are matched by a reference
+// extracted from Description first, several statements of the same reference are partial payments.
+// The rest statements are matched to unpaid expected payments of the same currency by amount within
+// tolerance and the nearest date. Both matches require dates within the window.
+// Returns items of expected payments in order of expected followed by unexpected statements sorted by date
+func Reconcile(expected []Expected, statements []p24.Statement, opts Options) []*Item {
+	kiev := p24.NewKievLocation()
+	incoming := make([]*p24.Statement, 0, len(statements))
+	for i := range statements {
+		if statements[i].CardAmount.Amount > 0 {
+			incoming = append(incoming, &statements[i])
+		}
+	}
+	sort.SliceStable(incoming, func(i, j int) bool { return incoming[i].TranDate.Before(incoming[j].TranDate) })
+
+	items := make([]*Item, len(expected))
+	byReference := map[string][]int{}
+	for i, e := range expected {
+		if e.Reference == "" {
+			e.Reference = e.ID
+		}
+		items[i] = &Item{
+			ID: e.ID, Reference: e.Reference, Expected: e.Amount, Paid: p24.Funds{Currency: e.Amount.Currency},
+			Description: e.Description, date: e.Date,
+		}
+		if e.Reference != "" {
+			key := strings.ToLower(e.Reference)
+			byReference[key] = append(byReference[key], i)
+		}
+	}
+	inWindow := func(i int, s *p24.Statement) bool {
+		return absInt(days(items[i].date, s.TranDate.In(kiev))) <= opts.Window
+	}
+	matched := map[*p24.Statement]bool{}
+	add := func(item *Item, s *p24.Statement, by string) {
+		matched[s] = true
+		if item.Count == 0 {
+			item.Description = ""
+		}
+		item.MatchedBy, item.Card, item.tranDate = by, s.Card, s.TranDate
+		item.Count++
+		item.Paid.Amount += s.CardAmount.Amount
+		item.Description = join(item.Description, s.Description)
+	}
+
+	if opts.Reference != nil {
+		for _, s := range incoming {
+			for _, i := range byReference[strings.ToLower(reference(opts.Reference, s.Description))] {
+				if items[i].Expected.Currency == s.CardAmount.Currency && inWindow(i, s) {
+					add(items[i], s, ByReference)
+					break
+				}
+			}
+		}
+	}
+
+	for _, s := range incoming {
+		if matched[s] {
+			continue
+		}
+		best := -1
+		for i, item := range items {
+			if item.Count > 0 || item.Expected.Currency != s.CardAmount.Currency || !inWindow(i, s) ||
+				abs(s.CardAmount.Amount-item.Expected.Amount) > opts.Tolerance {
+				continue
+			}
+			if best < 0 || absInt(days(item.date, s.TranDate.In(kiev))) < absInt(days(items[best].date, s.TranDate.In(kiev))) {
+				best = i
+			}
+		}
+		if best >= 0 {
+			add(items[best], s, ByAmount)
+		}
+	}
+
+	for _, item := range items {
+		item.Difference = p24.Funds{Currency: item.Expected.Currency, Amount: item.Paid.Amount - item.Expected.Amount}
+		switch {
+		case item.Count == 0:
+			item.Status = StatusUnpaid
+		case item.Difference.Amount < -opts.Tolerance:
+			item.Status = StatusPartial
+		default:
+			item.Status = StatusMatched
+		}
+	}
+	for _, s := range incoming {
+		if !matched[s] {
+			items = append(items, &Item{
+				Status: StatusUnexpected, Expected: p24.Funds{Currency: s.CardAmount.Currency}, Paid: s.CardAmount, Difference: s.CardAmount,
+				Count: 1, Description: s.Description, Card: s.Card, tranDate: s.TranDate,
+			})
+		}
+	}
+	return items
+}
+
+// Rows returns export rows of items
+func Rows(items []*Item) export.Rows {
+	list := make([]interface{}, len(items))
+	for i := range items {
+		list[i] = items[i]
+	}
+	return export.Rows{Root: "reconciliation", Elem: "item", Items: list}
+}
+
+// Fields returns Date and TranDate virtual fields of items, they are empty for unexpected statements
+// and unpaid expected payments respectively
+func Fields() export.VirtualFields {
+	field := func(get func(item *Item) time.Time) export.VirtualField {
+		return func(obj interface{}) (interface{}, error) {
+			var item *Item
+			switch i := obj.(type) {
+			case *Item:
+				item = i
+			case Item:
+				item = &i
+			default:
+				return nil, errors.Errorf("%T is not a reconciliation item", obj)
+			}
+			if t := get(item); !t.IsZero() {
+				return t, nil
+			}
+			return nil, nil
+		}
+	}
+	return export.VirtualFields{
+		"Date":     field(func(item *Item) time.Time { return item.date }),
+		"TranDate": field(func(item *Item) time.Time { return item.tranDate }),
+	}
+}
+
+func parseCSV(data []byte) ([]Expected, error) {
+	dec := csv.NewReader(bytes.NewReader(data))
+	dec.FieldsPerRecord = -1
+	records, err := dec.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	cols := map[string]int{}
+	for i, name := range records[0] {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"id", "date", "amount"} {
+		if _, ok := cols[name]; !ok {
+			return nil, errors.Errorf("%q column is required", name)
+		}
+	}
+	value := func(record []string, name string) string {
+		if i, ok := cols[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	kiev := p24.NewKievLocation()
+	res := make([]Expected, 0, len(records)-1)
+	for i, record := range records[1:] {
+		e := Expected{
+			ID:          value(record, "id"),
+			Reference:   value(record, "reference"),
+			Description: value(record, "description"),
+			Amount:      p24.Funds{Currency: strings.ToUpper(value(record, "currency"))},
+		}
+		if e.Amount.Currency == "" {
+			e.Amount.Currency = DefaultCurrency
+		}
+		if err := e.Amount.Amount.UnmarshalText([]byte(strings.Replace(value(record, "amount"), ",", ".", 1))); err != nil {
+			return nil, errors.Wrapf(err, "line %d: invalid amount", i+2)
+		}
+		if e.Date, err = parseDate(value(record, "date"), kiev); err != nil {
+			return nil, errors.Wrapf(err, "line %d", i+2)
+		}
+		res = append(res, e)
+	}
+	return res, nil
+}
+
+func parseDate(str string, loc *time.Location) (time.Time, error) {
+	for _, l := range dateLayouts {
+		if t, err := time.ParseInLocation(l, str, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.Errorf("%q has unknown date layout", str)
+}
+
+// reference returns the first submatch or the whole match of re in str
+func reference(re *regexp.Regexp, str string) string {
+	m := re.FindStringSubmatch(str)
+	switch {
+	case len(m) > 1:
+		return m[1]
+	case len(m) == 1:
+		return m[0]
+	}
+	return ""
+}
+
+// days returns number of calendar days from x date to y date
+func days(x, y time.Time) int {
+	ux := time.Date(x.Year(), x.Month(), x.Day(), 0, 0, 0, 0, time.UTC)
+	uy := time.Date(y.Year(), y.Month(), y.Day(), 0, 0, 0, 0, time.UTC)
+	return int(math.Round(uy.Sub(ux).Hours() / 24))
+}
+
+func join(x, y string) string {
+	if x == "" {
+		return y
+	}
+	return x + "; " + y
+}
+
+func abs(a p24.Amount) p24.Amount {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+func absInt(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,104 @@
+package reconcile
+
+import (
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseCSV(t *testing.T) {
+	kiev := p24.NewKievLocation()
+	cases := []struct {
+		data     string
+		expected []Expected
+		err      bool
+	}{
+		{
+			data: "id,date,amount,currency,reference\nINV-1,2022-01-05,1000,usd,1\nINV-2,10.01.2022,\"500,50\",,\n",
+			expected: []Expected{
+				{ID: "INV-1", Date: time.Date(2022, 1, 5, 0, 0, 0, 0, kiev), Amount: p24.Funds{Currency: "USD", Amount: 100000}, Reference: "1"},
+				{ID: "INV-2", Date: time.Date(2022, 1, 10, 0, 0, 0, 0, kiev), Amount: p24.Funds{Currency: "UAH", Amount: 50050}},
+			},
+		},
+		{data: "id,date\nINV-1,2022-01-05\n", err: true},
+		{data: "id,date,amount\nINV-1,2022/01/05,1\n", err: true},
+		{data: "id,date,amount\nINV-1,2022-01-05,x\n", err: true},
+	}
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			res, err := parseCSV([]byte(c.data))
+			if c.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.expected, res)
+		})
+	}
+}
+
+func Test_Reconcile(t *testing.T) {
+	kiev := p24.NewKievLocation()
+	expected := []Expected{
+		{ID: "INV-1", Date: time.Date(2022, 1, 5, 0, 0, 0, 0, kiev), Amount: p24.Funds{Currency: "UAH", Amount: 100000}},
+		{ID: "INV-2", Date: time.Date(2022, 1, 10, 0, 0, 0, 0, kiev), Amount: p24.Funds{Currency: "UAH", Amount: 50050}, Reference: "2"},
+		{ID: "INV-3", Date: time.Date(2022, 1, 20, 0, 0, 0, 0, kiev), Amount: p24.Funds{Currency: "UAH", Amount: 30000}, Reference: "3"},
+		{ID: "INV-4", Date: time.Date(2022, 1, 20, 0, 0, 0, 0, kiev), Amount: p24.Funds{Currency: "UAH", Amount: 70000}},
+	}
+	statements := []p24.Statement{
+		{Card: "1", TranDate: time.Date(2022, 1, 6, 10, 0, 0, 0, kiev), Description: "Payment", CardAmount: p24.Funds{Currency: "UAH", Amount: 99990}},
+		{Card: "1", TranDate: time.Date(2022, 1, 12, 10, 0, 0, 0, kiev), Description: "Invoice #2", CardAmount: p24.Funds{Currency: "UAH", Amount: 50050}},
+		{Card: "1", TranDate: time.Date(2022, 1, 21, 10, 0, 0, 0, kiev), Description: "Invoice 3 part 1", CardAmount: p24.Funds{Currency: "UAH", Amount: 10000}},
+		{Card: "1", TranDate: time.Date(2022, 1, 22, 10, 0, 0, 0, kiev), Description: "Invoice 3 part 2", CardAmount: p24.Funds{Currency: "UAH", Amount: 10000}},
+		{Card: "1", TranDate: time.Date(2022, 1, 22, 10, 0, 0, 0, kiev), Description: "Random", CardAmount: p24.Funds{Currency: "UAH", Amount: 7700}},
+		{Card: "1", TranDate: time.Date(2022, 1, 23, 10, 0, 0, 0, kiev), Description: "Shop", CardAmount: p24.Funds{Currency: "UAH", Amount: -5000}},
+		{Card: "1", TranDate: time.Date(2022, 1, 25, 10, 0, 0, 0, kiev), Description: "Late payment", CardAmount: p24.Funds{Currency: "UAH", Amount: 100000}},
+	}
+
+	cases := []struct {
+		opts     Options
+		statuses []string
+		matched  []string
+	}{
+		{
+			opts:     Options{Tolerance: 10, Window: 30, Reference: regexp.MustCompile(`(?i)invoice\s*#?(\d+)`)},
+			statuses: []string{StatusMatched, StatusMatched, StatusPartial, StatusUnpaid, StatusUnexpected, StatusUnexpected},
+			matched:  []string{ByAmount, ByReference, ByReference, "", "", ""},
+		},
+		{
+			// no reference, the first payment is out of tolerance, the late one is matched by amount
+			opts:     Options{Window: 30},
+			statuses: []string{StatusMatched, StatusMatched, StatusUnpaid, StatusUnpaid, StatusUnexpected, StatusUnexpected, StatusUnexpected, StatusUnexpected},
+			matched:  []string{ByAmount, ByAmount, "", "", "", "", "", ""},
+		},
+		{
+			// the late payment is out of the window
+			opts:     Options{Tolerance: 10, Window: 3},
+			statuses: []string{StatusMatched, StatusMatched, StatusUnpaid, StatusUnpaid, StatusUnexpected, StatusUnexpected, StatusUnexpected, StatusUnexpected},
+			matched:  []string{ByAmount, ByAmount, "", "", "", "", "", ""},
+		},
+	}
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			items := Reconcile(expected, statements, c.opts)
+			var statuses, matched []string
+			for _, item := range items {
+				statuses, matched = append(statuses, item.Status), append(matched, item.MatchedBy)
+			}
+			require.Equal(t, c.statuses, statuses)
+			require.Equal(t, c.matched, matched)
+		})
+	}
+
+	items := Reconcile(expected, statements, Options{Tolerance: 10, Window: 30, Reference: regexp.MustCompile(`(?i)invoice\s*#?(\d+)`)})
+	require.Equal(t, 2, items[2].Count)
+	require.Equal(t, p24.Funds{Currency: "UAH", Amount: -10000}, items[2].Difference)
+	require.Equal(t, "Invoice 3 part 1; Invoice 3 part 2", items[2].Description)
+	require.Equal(t, p24.Funds{Currency: "UAH", Amount: -10}, items[0].Difference)
+}
@@ -0,0 +1,285 @@
+// Package recurring detects recurring payments and subscriptions in statements
+package recurring
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/export"
+	"github.com/pkg/errors"
+)
+
+// Default detection options
+const (
+	DefaultAmountTolerance = 0.25
+	DefaultMinCount        = 3
+)
+
+// Period of a series
+type Period struct {
+	Name      string
+	Days      int // nominal interval
+	Tolerance int // allowed deviation of an interval in days
+	months    int // calendar months of the next date, 0 means Days
+}
+
+// Periods are detected periods of series
+var Periods = []Period{
+	{Name: "weekly", Days: 7, Tolerance: 1},
+	{Name: "monthly", Days: 30, Tolerance: 4, months: 1},
+	{Name: "yearly", Days: 365, Tolerance: 10, months: 12},
+}
+
+// Next returns the n-th expected date after t. Calendar months keep the day of month of t
+// clamped to the last day of a shorter month, e.g. Feb 28 and Mar 31 after Jan 31
+func (p Period) Next(t time.Time, n int) time.Time {
+	if p.months == 0 {
+		return t.AddDate(0, 0, p.Days*n)
+	}
+	y, m, d := t.Date()
+	month := time.Date(y, m+time.Month(p.months*n), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	if last := month.AddDate(0, 1, -1).Day(); d > last {
+		d = last
+	}
+	return month.AddDate(0, 0, d-1)
+}
+
+// Options of detection
+type Options struct {
+	AmountTolerance float64                       // relative difference of amounts of a series, DefaultAmountTolerance if zero
+	MinCount        int                           // minimal count of weekly and monthly series, DefaultMinCount if zero. Yearly ones need 2
+	At              time.Time                     // date of missing charges check, the latest statement date if zero
+	Merchant        func(s *p24.Statement) string // merchant name clustering statements, normalised Terminal and Description if nil
+}
+
+// Check returns an error if opts are invalid
+func (opts Options) Check() error {
+	if opts.AmountTolerance < 0 {
+		return errors.Errorf("amount tolerance %v is negative", opts.AmountTolerance)
+	}
+	if opts.MinCount < 0 {
+		return errors.Errorf("min count %d is negative", opts.MinCount)
+	}
+	return nil
+}
+
+// Series is a detected recurring payment or income
+type Series struct {
+	Name         string // the latest statement description
+	Terminal     string
+	Period       string
+	Count        int
+	First        time.Time
+	Last         time.Time
+	Next         time.Time // expected date of the next charge
+	Average      p24.Funds
+	Latest       p24.Funds
+	PriceChanges int
+	Changes      string // e.g. "2022-03-05: 149.00 -> 169.00"
+	Missing      bool   // the next expected charge is overdue at Options.At
+
+	period     Period
+	statements []p24.Statement
+}
+
+var (
+	digitsRegexp  = regexp.MustCompile(`\d+`)
+	nonWordRegexp = regexp.MustCompile(`[^\p{L}]+`)
+)
+
+// Normalize returns description or terminal without digits, punctuation and case, e.g. "netflix com" for "NETFLIX.COM 12345"
+func Normalize(str string) string {
+	str = digitsRegexp.ReplaceAllString(strings.ToLower(str), " ")
+	return strings.Join(strings.Fields(nonWordRegexp.ReplaceAllString(str, " ")), " ")
+}
+
+// Detect returns series of statements clustered by Options.Merchant or normalised Terminal and Description, currency,
+// direction and similar amount with weekly, monthly or yearly intervals. Series are sorted by name
+func Detect(statements []p24.Statement, opts Options) []*Series {
+	if opts.AmountTolerance <= 0 {
+		opts.AmountTolerance = DefaultAmountTolerance
+	}
+	if opts.MinCount <= 0 {
+		opts.MinCount = DefaultMinCount
+	}
+	if opts.At.IsZero() {
+		for i := range statements {
+			if statements[i].TranDate.After(opts.At) {
+				opts.At = statements[i].TranDate
+			}
+		}
+	}
+
+	clusters := map[string][]p24.Statement{}
+	for _, s := range statements {
+		if s.CardAmount.Amount == 0 {
+			continue
+		}
+		key := strings.Join([]string{Normalize(s.Terminal), Normalize(s.Description), s.CardAmount.Currency}, "|")
+		if opts.Merchant != nil {
+			key = strings.Join([]string{opts.Merchant(&s), s.CardAmount.Currency}, "|")
+		}
+		if s.CardAmount.Amount > 0 {
+			key += "|income"
+		}
+		clusters[key] = append(clusters[key], s)
+	}
+
+	var res []*Series
+	for _, list := range clusters {
+		for _, cluster := range splitAmounts(list, opts.AmountTolerance) {
+			if s := detect(cluster, opts); s != nil {
+				res = append(res, s)
+			}
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Name != res[j].Name {
+			return res[i].Name < res[j].Name
+		}
+		return res[i].First.Before(res[j].First)
+	})
+	return res
+}
+
+// Statements returns statements of the series sorted by TranDate
+func (s *Series) Statements() []p24.Statement {
+	return s.statements
+}
+
+// Dates returns expected dates of the series charges after the last one until t inclusive
+func (s *Series) Dates(until time.Time) []time.Time {
+	var res []time.Time
+	for n, d := 1, s.Next; !d.After(until); n, d = n+1, s.period.Next(s.Last, n+1) {
+		res = append(res, d)
+	}
+	return res
+}
+
+// Rows returns export rows of series
+func Rows(series []*Series) export.Rows {
+	items := make([]interface{}, len(series))
+	for i := range series {
+		items[i] = series[i]
+	}
+	return export.Rows{Root: "recurring", Elem: "series", Items: items}
+}
+
+// splitAmounts splits statements to clusters of similar absolute amounts
+func splitAmounts(statements []p24.Statement, tolerance float64) [][]p24.Statement {
+	sort.SliceStable(statements, func(i, j int) bool {
+		return abs(statements[i].CardAmount.Amount) < abs(statements[j].CardAmount.Amount)
+	})
+	var res [][]p24.Statement
+	start := 0
+	for i := 1; i <= len(statements); i++ {
+		if i == len(statements) ||
+			float64(abs(statements[i].CardAmount.Amount)) > float64(abs(statements[i-1].CardAmount.Amount))*(1+tolerance) {
+			res = append(res, statements[start:i])
+			start = i
+		}
+	}
+	return res
+}
+
+// detect returns series of statements if they have a regular period
+func detect(statements []p24.Statement, opts Options) *Series {
+	if len(statements) < 2 {
+		return nil
+	}
+	sort.SliceStable(statements, func(i, j int) bool { return statements[i].TranDate.Before(statements[j].TranDate) })
+
+	intervals := make([]int, len(statements)-1)
+	for i := 1; i < len(statements); i++ {
+		intervals[i-1] = days(statements[i-1].TranDate, statements[i].TranDate)
+	}
+	sorted := append([]int(nil), intervals...)
+	sort.Ints(sorted)
+	median := sorted[len(sorted)/2]
+
+	for _, p := range Periods {
+		if absInt(median-p.Days) > p.Tolerance {
+			continue
+		}
+		if p.months < 12 && len(statements) < opts.MinCount {
+			return nil
+		}
+		regular := 0
+		for _, d := range intervals {
+			if absInt(d-p.Days) <= p.Tolerance {
+				regular++
+			}
+		}
+		if float64(regular) < 0.75*float64(len(intervals)) {
+			return nil
+		}
+		return newSeries(statements, p, opts.At)
+	}
+	return nil
+}
+
+func newSeries(statements []p24.Statement, p Period, at time.Time) *Series {
+	first, last := statements[0], statements[len(statements)-1]
+	s := &Series{
+		Name:       last.Description,
+		Terminal:   last.Terminal,
+		Period:     p.Name,
+		Count:      len(statements),
+		First:      first.TranDate,
+		Last:       last.TranDate,
+		Next:       p.Next(last.TranDate, 1),
+		Latest:     last.CardAmount,
+		period:     p,
+		statements: statements,
+	}
+
+	var (
+		sum     p24.Amount
+		changes []string
+	)
+	for i, st := range statements {
+		sum += st.CardAmount.Amount
+		if i > 0 && st.CardAmount.Amount != statements[i-1].CardAmount.Amount {
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s",
+				st.TranDate.Format("2006-01-02"), money(statements[i-1].CardAmount.Amount), money(st.CardAmount.Amount)))
+		}
+	}
+	s.Average = p24.Funds{Currency: last.CardAmount.Currency, Amount: p24.Amount(math.Round(float64(sum) / float64(len(statements))))}
+	s.PriceChanges, s.Changes = len(changes), strings.Join(changes, "; ")
+	s.Missing = at.After(s.Next.AddDate(0, 0, p.Tolerance))
+	return s
+}
+
+// days returns number of calendar days between dates of x and y in Kyiv time zone
+func days(x, y time.Time) int {
+	kiev := p24.NewKievLocation()
+	dx, dy := x.In(kiev), y.In(kiev)
+	ux := time.Date(dx.Year(), dx.Month(), dx.Day(), 0, 0, 0, 0, time.UTC)
+	uy := time.Date(dy.Year(), dy.Month(), dy.Day(), 0, 0, 0, 0, time.UTC)
+	return int(math.Round(uy.Sub(ux).Hours() / 24))
+}
+
+func abs(a p24.Amount) p24.Amount {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// money returns absolute amount with 2 decimal digits
+func money(a p24.Amount) string {
+	return strconv.FormatFloat(abs(a).Float64(), 'f', 2, 64)
+}
+
+func absInt(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
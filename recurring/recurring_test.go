@@ -0,0 +1,132 @@
+package recurring
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Normalize(t *testing.T) {
+	cases := []struct {
+		str, expected string
+	}{
+		{"NETFLIX.COM 12345", "netflix com"},
+		{"  Оплата послуг #42, Київстар ", "оплата послуг київстар"},
+		{"123", ""},
+	}
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			require.Equal(t, c.expected, Normalize(c.str))
+		})
+	}
+}
+
+func Test_Detect(t *testing.T) {
+	kiev := p24.NewKievLocation()
+	statements := []p24.Statement{
+		{TranDate: time.Date(2022, 1, 5, 10, 0, 0, 0, kiev), Description: "Netflix 1", Terminal: "T", CardAmount: p24.Funds{Currency: "UAH", Amount: -14900}},
+		{TranDate: time.Date(2022, 2, 5, 10, 0, 0, 0, kiev), Description: "Netflix 2", Terminal: "T", CardAmount: p24.Funds{Currency: "UAH", Amount: -14900}},
+		{TranDate: time.Date(2022, 3, 7, 10, 0, 0, 0, kiev), Description: "Netflix 3", Terminal: "T", CardAmount: p24.Funds{Currency: "UAH", Amount: -16900}},
+		{TranDate: time.Date(2022, 4, 5, 10, 0, 0, 0, kiev), Description: "Netflix 4", Terminal: "T", CardAmount: p24.Funds{Currency: "UAH", Amount: -16900}},
+		{TranDate: time.Date(2022, 1, 10, 10, 0, 0, 0, kiev), Description: "Gym", Terminal: "T", CardAmount: p24.Funds{Currency: "UAH", Amount: -5000}},
+		{TranDate: time.Date(2022, 1, 17, 10, 0, 0, 0, kiev), Description: "Gym", Terminal: "T", CardAmount: p24.Funds{Currency: "UAH", Amount: -5000}},
+		{TranDate: time.Date(2022, 1, 24, 10, 0, 0, 0, kiev), Description: "Gym", Terminal: "T", CardAmount: p24.Funds{Currency: "UAH", Amount: -5000}},
+		{TranDate: time.Date(2021, 2, 1, 10, 0, 0, 0, kiev), Description: "Domain", Terminal: "T", CardAmount: p24.Funds{Currency: "UAH", Amount: -30000}},
+		{TranDate: time.Date(2022, 2, 3, 10, 0, 0, 0, kiev), Description: "Domain", Terminal: "T", CardAmount: p24.Funds{Currency: "UAH", Amount: -31000}},
+		{TranDate: time.Date(2022, 1, 25, 10, 0, 0, 0, kiev), Description: "Salary", Terminal: "T", CardAmount: p24.Funds{Currency: "UAH", Amount: 1000000}},
+		{TranDate: time.Date(2022, 2, 25, 10, 0, 0, 0, kiev), Description: "Salary", Terminal: "T", CardAmount: p24.Funds{Currency: "UAH", Amount: 1000000}},
+		{TranDate: time.Date(2022, 3, 25, 10, 0, 0, 0, kiev), Description: "Salary", Terminal: "T", CardAmount: p24.Funds{Currency: "UAH", Amount: 1050000}},
+		{TranDate: time.Date(2022, 4, 25, 10, 0, 0, 0, kiev), Description: "Salary", Terminal: "T", CardAmount: p24.Funds{Currency: "UAH", Amount: 1000000}},
+		// irregular
+		{TranDate: time.Date(2022, 1, 1, 10, 0, 0, 0, kiev), Description: "Coffee", Terminal: "T", CardAmount: p24.Funds{Currency: "UAH", Amount: -5000}},
+		{TranDate: time.Date(2022, 1, 3, 10, 0, 0, 0, kiev), Description: "Coffee", Terminal: "T", CardAmount: p24.Funds{Currency: "UAH", Amount: -5000}},
+		{TranDate: time.Date(2022, 2, 20, 10, 0, 0, 0, kiev), Description: "Coffee", Terminal: "T", CardAmount: p24.Funds{Currency: "UAH", Amount: -5000}},
+		// the same description with other amount is other cluster
+		{TranDate: time.Date(2022, 3, 1, 10, 0, 0, 0, kiev), Description: "Gym", Terminal: "T", CardAmount: p24.Funds{Currency: "UAH", Amount: -90000}},
+	}
+
+	series := Detect(statements, Options{})
+	require.Len(t, series, 4)
+
+	domain, gym, netflix, salary := series[0], series[1], series[2], series[3]
+	require.Equal(t, "yearly", domain.Period)
+	require.Equal(t, time.Date(2023, 2, 3, 10, 0, 0, 0, kiev), domain.Next)
+	require.False(t, domain.Missing)
+
+	require.Equal(t, "weekly", gym.Period)
+	require.Equal(t, 3, gym.Count)
+	require.True(t, gym.Missing)
+	require.Len(t, gym.Dates(time.Date(2022, 2, 14, 23, 0, 0, 0, kiev)), 3)
+
+	require.Equal(t, "monthly", netflix.Period)
+	require.Equal(t, "Netflix 4", netflix.Name)
+	require.Equal(t, 1, netflix.PriceChanges)
+	require.Equal(t, "2022-03-07: 149.00 -> 169.00", netflix.Changes)
+	require.Equal(t, p24.Funds{Currency: "UAH", Amount: -15900}, netflix.Average)
+	require.Equal(t, time.Date(2022, 5, 5, 10, 0, 0, 0, kiev), netflix.Next)
+	require.False(t, netflix.Missing)
+	require.Len(t, netflix.Statements(), 4)
+
+	require.Equal(t, "monthly", salary.Period)
+	require.Equal(t, 2, salary.PriceChanges)
+
+	// missing charges are checked at the given date
+	series = Detect(statements, Options{At: time.Date(2022, 6, 1, 0, 0, 0, 0, kiev), MinCount: 4})
+	require.Len(t, series, 3)
+	for _, s := range series {
+		require.Equal(t, s.Period != "yearly", s.Missing, s.Name)
+	}
+
+	// statements of the same merchant with different descriptions are clustered together
+	spellings := append([]p24.Statement(nil), statements[:4]...)
+	for i, d := range []string{"NETFLIX.COM", "Netflix Inc", "NETFLIX.COM", "Netflix Inc"} {
+		spellings[i].Description = d
+	}
+	require.Empty(t, Detect(spellings, Options{}))
+	series = Detect(spellings, Options{Merchant: func(s *p24.Statement) string { return "Netflix" }})
+	require.Len(t, series, 1)
+	require.Equal(t, 4, series[0].Count)
+
+	require.Error(t, Options{AmountTolerance: -1}.Check())
+	require.NoError(t, Options{}.Check())
+}
+
+func Test_PeriodNext(t *testing.T) {
+	kiev := p24.NewKievLocation()
+	weekly, monthly, yearly := Periods[0], Periods[1], Periods[2]
+	cases := []struct {
+		period   Period
+		t        time.Time
+		n        int
+		expected time.Time
+	}{
+		{period: weekly, t: time.Date(2022, 1, 31, 10, 0, 0, 0, kiev), n: 1, expected: time.Date(2022, 2, 7, 10, 0, 0, 0, kiev)},
+		{period: weekly, t: time.Date(2022, 1, 31, 10, 0, 0, 0, kiev), n: 2, expected: time.Date(2022, 2, 14, 10, 0, 0, 0, kiev)},
+		{period: monthly, t: time.Date(2022, 1, 5, 10, 0, 0, 0, kiev), n: 1, expected: time.Date(2022, 2, 5, 10, 0, 0, 0, kiev)},
+		{period: monthly, t: time.Date(2022, 1, 31, 10, 0, 0, 0, kiev), n: 1, expected: time.Date(2022, 2, 28, 10, 0, 0, 0, kiev)},
+		{period: monthly, t: time.Date(2022, 1, 31, 10, 0, 0, 0, kiev), n: 2, expected: time.Date(2022, 3, 31, 10, 0, 0, 0, kiev)},
+		{period: monthly, t: time.Date(2022, 1, 31, 10, 0, 0, 0, kiev), n: 3, expected: time.Date(2022, 4, 30, 10, 0, 0, 0, kiev)},
+		{period: monthly, t: time.Date(2022, 11, 30, 10, 0, 0, 0, kiev), n: 3, expected: time.Date(2023, 2, 28, 10, 0, 0, 0, kiev)},
+		{period: yearly, t: time.Date(2020, 2, 29, 10, 0, 0, 0, kiev), n: 1, expected: time.Date(2021, 2, 28, 10, 0, 0, 0, kiev)},
+		{period: yearly, t: time.Date(2020, 2, 29, 10, 0, 0, 0, kiev), n: 4, expected: time.Date(2024, 2, 29, 10, 0, 0, 0, kiev)},
+	}
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			require.Equal(t, c.expected, c.period.Next(c.t, c.n))
+		})
+	}
+
+	// dates of a series are anchored to the day of month of the last charge
+	s := &Series{Last: time.Date(2022, 1, 31, 10, 0, 0, 0, kiev), period: monthly}
+	s.Next = monthly.Next(s.Last, 1)
+	require.Equal(t, []time.Time{
+		time.Date(2022, 2, 28, 10, 0, 0, 0, kiev),
+		time.Date(2022, 3, 31, 10, 0, 0, 0, kiev),
+		time.Date(2022, 4, 30, 10, 0, 0, 0, kiev),
+	}, s.Dates(time.Date(2022, 5, 1, 0, 0, 0, 0, kiev)))
+}
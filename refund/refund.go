@@ -0,0 +1,198 @@
+// Package refund pairs refunds and reversals with their original purchases
+package refund
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/export"
+	"github.com/dimboknv/p24-cli/pipeline"
+	"github.com/dimboknv/p24-cli/recurring"
+	"github.com/pkg/errors"
+)
+
+// DefaultWindow is the default maximal count of days between a purchase and its refund
+const DefaultWindow = 90
+
+// Options of pairing
+type Options struct {
+	Window int // maximal count of days between a purchase and its refund, DefaultWindow if zero
+}
+
+// Pair is a purchase with its full or partial refunds. ID is the purchase fingerprint
+type Pair struct {
+	ID       string
+	Purchase p24.Statement
+	Refunds  []p24.Statement
+	Refunded p24.Amount // sum of refunds amounts
+}
+
+// Full returns true if the purchase is refunded in full
+func (p *Pair) Full() bool {
+	return p.Refunded >= -p.Purchase.CardAmount.Amount
+}
+
+// Pairs are purchases paired with refunds, statements are identified by fingerprints
+type Pairs struct {
+	opts       Options
+	list       []*Pair
+	byPurchase map[string]*Pair
+	byRefund   map[string]*Pair
+}
+
+// NewPairs returns empty Pairs with opts, see Pairs.Detect
+func NewPairs(opts Options) *Pairs {
+	if opts.Window <= 0 {
+		opts.Window = DefaultWindow
+	}
+	return &Pairs{opts: opts, byPurchase: map[string]*Pair{}, byRefund: map[string]*Pair{}}
+}
+
+// Detect replaces pairs by pairs of statements. A refund is an income of the same card and currency after
+// a purchase within the window days with the same normalised Terminal or, if terminals are empty,
+// a Description containing all words of the purchase description. A refund is paired with the latest
+// purchase it refunds in full, otherwise with the latest purchase with enough not refunded amount
+func (p *Pairs) Detect(statements []p24.Statement) {
+	p.list, p.byPurchase, p.byRefund = nil, map[string]*Pair{}, map[string]*Pair{}
+	sorted := make([]*p24.Statement, len(statements))
+	for i := range statements {
+		sorted[i] = &statements[i]
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].TranDate.Before(sorted[j].TranDate) })
+
+	refunded := map[*p24.Statement]p24.Amount{}
+	pairs := map[*p24.Statement]*Pair{}
+	for i, r := range sorted {
+		if r.CardAmount.Amount <= 0 {
+			continue
+		}
+		var partial, full *p24.Statement
+		for k := i - 1; k >= 0 && full == nil; k-- {
+			s := sorted[k]
+			if r.TranDate.Sub(s.TranDate).Hours() > float64(24*p.opts.Window) {
+				break
+			}
+			if s.CardAmount.Amount >= 0 || s.Card != r.Card || s.CardAmount.Currency != r.CardAmount.Currency || !similar(s, r) {
+				continue
+			}
+			switch rest := -s.CardAmount.Amount - refunded[s]; {
+			case rest == r.CardAmount.Amount:
+				full = s
+			case rest > r.CardAmount.Amount && partial == nil:
+				partial = s
+			}
+		}
+		purchase := full
+		if purchase == nil {
+			purchase = partial
+		}
+		if purchase == nil {
+			continue
+		}
+
+		refunded[purchase] += r.CardAmount.Amount
+		pair := pairs[purchase]
+		if pair == nil {
+			pair = &Pair{ID: pipeline.Fingerprint(purchase), Purchase: *purchase}
+			pairs[purchase] = pair
+			p.list = append(p.list, pair)
+			p.byPurchase[pair.ID] = pair
+		}
+		pair.Refunds = append(pair.Refunds, *r)
+		pair.Refunded += r.CardAmount.Amount
+		p.byRefund[pipeline.Fingerprint(r)] = pair
+	}
+}
+
+// List returns pairs in order of refunds
+func (p *Pairs) List() []*Pair {
+	return p.list
+}
+
+// Lookup returns the pair of a purchase or a refund statement
+func (p *Pairs) Lookup(s *p24.Statement) (pair *Pair, isRefund bool) {
+	fp := pipeline.Fingerprint(s)
+	if pair, ok := p.byRefund[fp]; ok {
+		return pair, true
+	}
+	return p.byPurchase[fp], false
+}
+
+// Net returns statements without paired refunds and fully refunded purchases. Amounts of partially refunded
+// purchases aren't changed since fingerprints of statements key manual labels, transfers and pairs lookups,
+// they are reduced by Netted when aggregated
+func Net(statements []p24.Statement, p *Pairs) []p24.Statement {
+	res := make([]p24.Statement, 0, len(statements))
+	for _, s := range statements {
+		if pair, isRefund := p.Lookup(&s); pair != nil && (isRefund || pair.Full()) {
+			continue
+		}
+		res = append(res, s)
+	}
+	return res
+}
+
+// Netted returns copy of s with amounts reduced by refunds if it is a partially refunded purchase
+func (p *Pairs) Netted(s *p24.Statement) p24.Statement {
+	res := *s
+	if pair, isRefund := p.Lookup(s); pair != nil && !isRefund {
+		if res.Amount.Currency == res.CardAmount.Currency && res.Amount.Amount == res.CardAmount.Amount {
+			res.Amount.Amount += pair.Refunded
+		}
+		res.CardAmount.Amount += pair.Refunded
+	}
+	return res
+}
+
+// Fields returns virtual fields of statements:
+// - PairID is the fingerprint of the purchase of a paired purchase or refund, empty otherwise
+// - IsRefund is true for refunds paired with purchases
+func Fields(p *Pairs) export.VirtualFields {
+	field := func(f func(pair *Pair, isRefund bool) interface{}) export.VirtualField {
+		return func(obj interface{}) (interface{}, error) {
+			if p == nil {
+				return nil, errors.New("refunds are not paired")
+			}
+			var s p24.Statement
+			switch v := obj.(type) {
+			case *p24.Statement:
+				s = *v
+			case p24.Statement:
+				s = v
+			default:
+				return nil, errors.Errorf("%T is not a statement", obj)
+			}
+			return f(p.Lookup(&s)), nil
+		}
+	}
+
+	return export.VirtualFields{
+		"PairID": field(func(pair *Pair, _ bool) interface{} {
+			if pair == nil {
+				return ""
+			}
+			return pair.ID
+		}),
+		"IsRefund": field(func(_ *Pair, isRefund bool) interface{} { return isRefund }),
+	}
+}
+
+// similar returns true if refund r has the same normalised terminal as purchase s or,
+// if any terminal is empty, r description contains all words of s description
+func similar(s, r *p24.Statement) bool {
+	if st, rt := recurring.Normalize(s.Terminal), recurring.Normalize(r.Terminal); st != "" && rt != "" {
+		return st == rt
+	}
+	words := map[string]bool{}
+	for _, w := range strings.Fields(recurring.Normalize(r.Description)) {
+		words[w] = true
+	}
+	purchase := strings.Fields(recurring.Normalize(s.Description))
+	for _, w := range purchase {
+		if !words[w] {
+			return false
+		}
+	}
+	return len(purchase) > 0
+}
@@ -0,0 +1,125 @@
+package refund
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/pipeline"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Pairs_Detect(t *testing.T) {
+	kiev := p24.NewKievLocation()
+	statements := []p24.Statement{
+		{
+			Card: "1", Appcode: "1", TranDate: time.Date(2022, 1, 1, 10, 0, 0, 0, kiev), Terminal: "ROZETKA",
+			Description: "Rozetka order", CardAmount: p24.Funds{Currency: "UAH", Amount: -100000},
+		},
+		{
+			Card: "1", Appcode: "2", TranDate: time.Date(2022, 1, 2, 10, 0, 0, 0, kiev), Terminal: "ROZETKA",
+			Description: "Rozetka order", CardAmount: p24.Funds{Currency: "UAH", Amount: -50000},
+		},
+		{
+			Card: "1", Appcode: "3", TranDate: time.Date(2022, 1, 3, 10, 0, 0, 0, kiev),
+			Description: "Shop Beta", CardAmount: p24.Funds{Currency: "UAH", Amount: -30000},
+		},
+		// full refund of the older purchase
+		{
+			Card: "1", Appcode: "4", TranDate: time.Date(2022, 1, 4, 10, 0, 0, 0, kiev), Terminal: "ROZETKA",
+			Description: "Refund", CardAmount: p24.Funds{Currency: "UAH", Amount: 100000},
+		},
+		// partial
+		{
+			Card: "1", Appcode: "5", TranDate: time.Date(2022, 1, 5, 10, 0, 0, 0, kiev),
+			Description: "Refund: Shop Beta", CardAmount: p24.Funds{Currency: "UAH", Amount: 10000},
+		},
+		// partial
+		{
+			Card: "1", Appcode: "6", TranDate: time.Date(2022, 1, 6, 10, 0, 0, 0, kiev),
+			Description: "Refund: Shop Beta", CardAmount: p24.Funds{Currency: "UAH", Amount: 10000},
+		},
+		// other card
+		{
+			Card: "2", Appcode: "7", TranDate: time.Date(2022, 1, 7, 10, 0, 0, 0, kiev),
+			Description: "Refund: Shop Beta", CardAmount: p24.Funds{Currency: "UAH", Amount: 10000},
+		},
+		// not similar
+		{
+			Card: "1", Appcode: "8", TranDate: time.Date(2022, 1, 8, 10, 0, 0, 0, kiev),
+			Description: "Refund: Shop", CardAmount: p24.Funds{Currency: "UAH", Amount: 5000},
+		},
+		// more than purchase
+		{
+			Card: "1", Appcode: "9", TranDate: time.Date(2022, 1, 9, 10, 0, 0, 0, kiev), Terminal: "ROZETKA",
+			Description: "Refund", CardAmount: p24.Funds{Currency: "UAH", Amount: 60000},
+		},
+		{
+			Card: "1", Appcode: "10", TranDate: time.Date(2022, 1, 10, 10, 0, 0, 0, kiev),
+			Description: "Salary", CardAmount: p24.Funds{Currency: "UAH", Amount: 500000},
+		},
+	}
+
+	cases := []struct {
+		window   int
+		expected [][]int // indexes of purchase and refunds of pairs
+	}{
+		{expected: [][]int{{0, 3}, {2, 4, 5}}},
+		{window: 2, expected: [][]int{{2, 4}}}, // day 6 is more than 2 days after the purchase
+	}
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			p := NewPairs(Options{Window: c.window})
+			p.Detect(statements)
+			pairs := make([][]int, 0, len(p.List()))
+			for _, pair := range p.List() {
+				indexes := []int{index(statements, pair.Purchase)}
+				for _, r := range pair.Refunds {
+					indexes = append(indexes, index(statements, r))
+				}
+				pairs = append(pairs, indexes)
+			}
+			require.Equal(t, c.expected, pairs)
+		})
+	}
+
+	p := NewPairs(Options{})
+	p.Detect(statements)
+	pair, isRefund := p.Lookup(&statements[3])
+	require.True(t, isRefund)
+	require.Equal(t, pipeline.Fingerprint(&statements[0]), pair.ID)
+	require.True(t, pair.Full())
+	pair, isRefund = p.Lookup(&statements[2])
+	require.False(t, isRefund)
+	require.False(t, pair.Full())
+	require.Equal(t, p24.Amount(20000), pair.Refunded)
+	pair, _ = p.Lookup(&statements[9])
+	require.Nil(t, pair)
+
+	fields := Fields(p)
+	id, err := fields["PairID"](statements[4])
+	require.NoError(t, err)
+	require.Equal(t, pipeline.Fingerprint(&statements[2]), id)
+	isRefundValue, err := fields["IsRefund"](&statements[4])
+	require.NoError(t, err)
+	require.Equal(t, true, isRefundValue)
+
+	net := Net(statements, p)
+	amounts := make([]p24.Amount, len(net))
+	for i := range net {
+		require.Equal(t, net[i], statements[index(statements, net[i])], "amounts aren't changed")
+		amounts[i] = p.Netted(&net[i]).CardAmount.Amount
+	}
+	require.Equal(t, []p24.Amount{-50000, -10000, 10000, 5000, 60000, 500000}, amounts)
+}
+
+func index(statements []p24.Statement, s p24.Statement) int {
+	for i := range statements {
+		if statements[i].Appcode == s.Appcode {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,356 @@
+// Package report aggregates statements to summaries grouped by keys
+package report
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/export"
+	"github.com/dimboknv/p24-cli/expr"
+	"github.com/dimboknv/p24-cli/pipeline"
+	"github.com/pkg/errors"
+)
+
+// Row levels
+const (
+	LevelGroup    = "group"
+	LevelSubtotal = "subtotal"
+	LevelTotal    = "total"
+)
+
+// aliases of group keys expressions
+var aliases = map[string]string{
+	"year":     "year(TranDate)",
+	"month":    "month(TranDate)",
+	"week":     "week(TranDate)",
+	"day":      "date(TranDate)",
+	"weekday":  "weekday(TranDate)",
+	"category": "Category",
+	"terminal": "Terminal",
+	"currency": "CardAmount.Currency",
+	"merchant": "Merchant",
+}
+
+// layouts of time values of date aliases
+var layouts = map[string]string{
+	"day": "2006-01-02",
+}
+
+var namedKeyRegexp = regexp.MustCompile(`^([A-Za-z_]\w*)\s*=([^=].*)$`)
+
+// Key is a group key of report
+type Key struct {
+	Expr   *expr.Expr
+	Name   string
+	Layout string // layout of time values, "2006-01-02 15:04:05" if empty
+}
+
+// Row is an aggregated group of statements amounts of a currency.
+// Subtotal and total rows have empty values of nested keys
+type Row struct {
+	keys     []string
+	depth    int // count of keys of subtotal and total rows, the next key is their label
+	Level    string
+	Currency string // currency of amounts, amounts of different currencies are aggregated in separate rows
+	Count    int
+	Inflow   p24.Amount // sum of incomes
+	Outflow  p24.Amount // sum of absolute values of expenses
+	Net      p24.Amount // Inflow - Outflow
+	Average  p24.Amount // Net / Count
+	Max      p24.Amount // amount with the largest absolute value
+}
+
+// ParseKeys parses comma separated list of group keys. A key is an alias:
+// year, month, week, day, weekday, category, terminal, currency, merchant or an expression with optional name,
+// e.g. "month,Dir=direction(CardAmount)"
+func ParseKeys(str string, fields export.VirtualFields) ([]Key, error) {
+	var keys []Key
+	for _, s := range pipeline.SplitTopLevel(str, ',') {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+
+		name, src, layout := s, s, ""
+		if alias, ok := aliases[strings.ToLower(s)]; ok {
+			name, src, layout = capitalize(strings.ToLower(s)), alias, layouts[strings.ToLower(s)]
+		} else if m := namedKeyRegexp.FindStringSubmatch(s); m != nil {
+			name, src = m[1], m[2]
+		}
+
+		e, err := expr.Parse(src)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid group key %q", s)
+		}
+		if err := fields.Check(p24.Statement{}, e); err != nil {
+			return nil, errors.Wrapf(err, "invalid group key %q", s)
+		}
+		keys = append(keys, Key{Name: name, Expr: e, Layout: layout})
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("there are no group keys")
+	}
+	return keys, nil
+}
+
+// Aggregate groups statements by keys and amount currency. Groups are sorted by keys values and currency, rows of
+// groups sharing first keys are followed by subtotal rows per currency and the last rows are grand totals per currency.
+// amount returns aggregated amount of a statement, e.g. converted to the base currency. CardAmount is used if amount is nil
+func Aggregate(
+	statements []p24.Statement, keys []Key, fields export.VirtualFields, amount func(s *p24.Statement) (p24.Funds, error),
+) ([]*Row, error) {
+	if amount == nil {
+		amount = func(s *p24.Statement) (p24.Funds, error) { return s.CardAmount, nil }
+	}
+
+	type group struct {
+		row    *Row
+		values []interface{}
+	}
+	currencies := func(rows map[string]*Row) []string {
+		res := make([]string, 0, len(rows))
+		for c := range rows {
+			res = append(res, c)
+		}
+		sort.Strings(res)
+		return res
+	}
+	groups := map[string]*group{}
+	for i := range statements {
+		env := fields.Env(&statements[i])
+		values, strs := make([]interface{}, len(keys)), make([]string, len(keys))
+		for k, key := range keys {
+			v, err := env.Eval(key.Expr)
+			if err != nil {
+				return nil, errors.Wrapf(err, "group key %q", key.Name)
+			}
+			values[k], strs[k] = v, keyString(v, key.Layout)
+		}
+
+		f, err := amount(&statements[i])
+		if err != nil {
+			return nil, err
+		}
+		id := strings.Join(append(strs, f.Currency), "\x00")
+		if groups[id] == nil {
+			groups[id] = &group{row: &Row{keys: strs, Level: LevelGroup, Currency: f.Currency}, values: values}
+		}
+		groups[id].row.add(f)
+	}
+
+	sorted := make([]*group, 0, len(groups))
+	for _, g := range groups {
+		sorted = append(sorted, g)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		for k := range keys {
+			if c := compareKeys(sorted[i].values[k], sorted[j].values[k]); c != 0 {
+				return c < 0
+			}
+		}
+		return sorted[i].row.Currency < sorted[j].row.Currency
+	})
+
+	// subtotals[l] accumulates groups sharing the first l+1 keys by currency
+	var (
+		res       []*Row
+		subtotals = make([]map[string]*Row, len(keys)-1)
+		totals    = map[string]*Row{}
+	)
+	flush := func(level int) {
+		for l := len(subtotals) - 1; l >= level; l-- {
+			for _, c := range currencies(subtotals[l]) {
+				res = append(res, subtotals[l][c].finish())
+			}
+			subtotals[l] = nil
+		}
+	}
+	for i, g := range sorted {
+		if i > 0 {
+			prev := sorted[i-1].row.keys
+			for l := range subtotals {
+				if prev[l] != g.row.keys[l] {
+					flush(l)
+					break
+				}
+			}
+		}
+		res = append(res, g.row.finish())
+		currency := g.row.Currency
+		for l := range subtotals {
+			if subtotals[l] == nil {
+				subtotals[l] = map[string]*Row{}
+			}
+			if subtotals[l][currency] == nil {
+				keys := make([]string, len(g.row.keys))
+				copy(keys, g.row.keys[:l+1])
+				subtotals[l][currency] = &Row{keys: keys, depth: l + 1, Level: LevelSubtotal, Currency: currency}
+			}
+			subtotals[l][currency].merge(g.row)
+		}
+		if totals[currency] == nil {
+			totals[currency] = &Row{keys: make([]string, len(keys)), Level: LevelTotal, Currency: currency}
+		}
+		totals[currency].merge(g.row)
+	}
+	flush(0)
+	if len(totals) == 0 {
+		totals[""] = &Row{keys: make([]string, len(keys)), Level: LevelTotal}
+	}
+	for _, c := range currencies(totals) {
+		res = append(res, totals[c].finish())
+	}
+	return res, nil
+}
+
+// Fields returns virtual fields of report rows by keys names
+func Fields(keys []Key) export.VirtualFields {
+	fields := export.VirtualFields{}
+	for i, key := range keys {
+		i := i
+		fields[key.Name] = func(obj interface{}) (interface{}, error) {
+			var row Row
+			switch r := obj.(type) {
+			case *Row:
+				row = *r
+			case Row:
+				row = r
+			default:
+				return nil, errors.Errorf("%T is not a report row", obj)
+			}
+			return row.Key(i), nil
+		}
+	}
+	return fields
+}
+
+// DefaultFormat returns export format of report rows with keys columns
+func DefaultFormat(keys []Key) string {
+	names := make([]string, len(keys))
+	for i, key := range keys {
+		names[i] = key.Name
+	}
+	format := strings.Join(names, "|") + "|Count"
+	for _, name := range []string{"Inflow", "Outflow", "Net", "Average", "Max"} {
+		format += "|" + name + "{precision=2}"
+	}
+	if !containsKey(keys, aliases["currency"]) {
+		format += "|Currency"
+	}
+	return format + "|,"
+}
+
+// Rows returns export rows of report
+func Rows(rows []*Row) export.Rows {
+	items := make([]interface{}, len(rows))
+	for i := range rows {
+		items[i] = rows[i]
+	}
+	return export.Rows{Root: "report", Elem: "row", Items: items}
+}
+
+// Key returns i key value of row. The key after keys of subtotal and total rows is "Subtotal" or "Total" label
+func (r *Row) Key(i int) string {
+	if r.Level != LevelGroup && i == r.depth {
+		return capitalize(r.Level)
+	}
+	return r.keys[i]
+}
+
+func (r *Row) add(f p24.Funds) {
+	r.merge(&Row{Count: 1, Inflow: positive(f.Amount), Outflow: positive(-f.Amount), Max: f.Amount})
+}
+
+// merge adds amounts of o to r, rows must have the same currency
+func (r *Row) merge(o *Row) {
+	if r.Count == 0 || abs(o.Max) > abs(r.Max) {
+		r.Max = o.Max
+	}
+	r.Count += o.Count
+	r.Inflow += o.Inflow
+	r.Outflow += o.Outflow
+}
+
+func (r *Row) finish() *Row {
+	r.Net = r.Inflow - r.Outflow
+	if r.Count > 0 {
+		r.Average = r.Net / p24.Amount(r.Count)
+	}
+	return r
+}
+
+func containsKey(keys []Key, src string) bool {
+	for _, key := range keys {
+		if key.Expr.String() == src {
+			return true
+		}
+	}
+	return false
+}
+
+func capitalize(str string) string {
+	if str == "" {
+		return str
+	}
+	return strings.ToUpper(str[:1]) + str[1:]
+}
+
+func positive(a p24.Amount) p24.Amount {
+	if a > 0 {
+		return a
+	}
+	return 0
+}
+
+func abs(a p24.Amount) p24.Amount {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// keyString returns text of a key value, e.g. "2022" for year(TranDate). Time values are formatted by layout
+func keyString(v interface{}, layout string) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case time.Time:
+		if layout == "" {
+			layout = "2006-01-02 15:04:05"
+		}
+		return t.Format(layout)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// compareKeys compares key values, weekdays are ordered from Monday
+func compareKeys(x, y interface{}) int {
+	if wx, ok := weekday(x); ok {
+		if wy, ok := weekday(y); ok {
+			return wx - wy
+		}
+	}
+	if c, err := expr.Compare(x, y); err == nil {
+		return c
+	}
+	return strings.Compare(keyString(x, ""), keyString(y, ""))
+}
+
+func weekday(v interface{}) (int, bool) {
+	str, ok := v.(string)
+	if !ok {
+		return 0, false
+	}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if d.String() == str {
+			return (int(d) + 6) % 7, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,184 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/export"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Aggregate(t *testing.T) {
+	statements := []p24.Statement{
+		{TranDate: time.Date(2022, 1, 3, 10, 0, 0, 0, time.UTC), CardAmount: p24.Funds{Currency: "UAH", Amount: -1000}, Terminal: "T1"},
+		{TranDate: time.Date(2022, 1, 4, 10, 0, 0, 0, time.UTC), CardAmount: p24.Funds{Currency: "UAH", Amount: -3000}, Terminal: "T1"},
+		{TranDate: time.Date(2022, 1, 9, 10, 0, 0, 0, time.UTC), CardAmount: p24.Funds{Currency: "UAH", Amount: 10000}, Terminal: "T2"},
+		{TranDate: time.Date(2022, 2, 7, 10, 0, 0, 0, time.UTC), CardAmount: p24.Funds{Currency: "USD", Amount: -500}, Terminal: "T1"},
+	}
+
+	cases := []struct {
+		by       string
+		expected []string
+		withErr  bool
+	}{
+		{
+			by: "month",
+			expected: []string{
+				"2022-01 group 3 10000 4000 6000 2000 10000 UAH",
+				"2022-02 group 1 0 500 -500 -500 -500 USD",
+				"Total total 3 10000 4000 6000 2000 10000 UAH",
+				"Total total 1 0 500 -500 -500 -500 USD",
+			},
+		},
+		{
+			by: "weekday",
+			expected: []string{
+				"Monday group 1 0 1000 -1000 -1000 -1000 UAH",
+				"Monday group 1 0 500 -500 -500 -500 USD",
+				"Tuesday group 1 0 3000 -3000 -3000 -3000 UAH",
+				"Sunday group 1 10000 0 10000 10000 10000 UAH",
+				"Total total 3 10000 4000 6000 2000 10000 UAH",
+				"Total total 1 0 500 -500 -500 -500 USD",
+			},
+		},
+		{
+			by: "month, Terminal",
+			expected: []string{
+				"2022-01|T1 group 2 0 4000 -4000 -2000 -3000 UAH",
+				"2022-01|T2 group 1 10000 0 10000 10000 10000 UAH",
+				"2022-01|Subtotal subtotal 3 10000 4000 6000 2000 10000 UAH",
+				"2022-02|T1 group 1 0 500 -500 -500 -500 USD",
+				"2022-02|Subtotal subtotal 1 0 500 -500 -500 -500 USD",
+				"Total| total 3 10000 4000 6000 2000 10000 UAH",
+				"Total| total 1 0 500 -500 -500 -500 USD",
+			},
+		},
+		{by: "Big=CardAmount > 50", expected: []string{
+			"false group 2 0 4000 -4000 -2000 -3000 UAH",
+			"false group 1 0 500 -500 -500 -500 USD",
+			"true group 1 10000 0 10000 10000 10000 UAH",
+			"Total total 3 10000 4000 6000 2000 10000 UAH",
+			"Total total 1 0 500 -500 -500 -500 USD",
+		}},
+		{by: `month,Dir=if(CardAmount.Amount < 0, "out", "in")`, expected: []string{
+			"2022-01|in group 1 10000 0 10000 10000 10000 UAH",
+			"2022-01|out group 2 0 4000 -4000 -2000 -3000 UAH",
+			"2022-01|Subtotal subtotal 3 10000 4000 6000 2000 10000 UAH",
+			"2022-02|out group 1 0 500 -500 -500 -500 USD",
+			"2022-02|Subtotal subtotal 1 0 500 -500 -500 -500 USD",
+			"Total| total 3 10000 4000 6000 2000 10000 UAH",
+			"Total| total 1 0 500 -500 -500 -500 USD",
+		}},
+		{by: "day", expected: []string{
+			"2022-01-03 group 1 0 1000 -1000 -1000 -1000 UAH",
+			"2022-01-04 group 1 0 3000 -3000 -3000 -3000 UAH",
+			"2022-01-09 group 1 10000 0 10000 10000 10000 UAH",
+			"2022-02-07 group 1 0 500 -500 -500 -500 USD",
+			"Total total 3 10000 4000 6000 2000 10000 UAH",
+			"Total total 1 0 500 -500 -500 -500 USD",
+		}},
+		{by: `T=if(Terminal == "T2", "", Terminal),month`, expected: []string{
+			"|2022-01 group 1 10000 0 10000 10000 10000 UAH",
+			"|Subtotal subtotal 1 10000 0 10000 10000 10000 UAH",
+			"T1|2022-01 group 2 0 4000 -4000 -2000 -3000 UAH",
+			"T1|2022-02 group 1 0 500 -500 -500 -500 USD",
+			"T1|Subtotal subtotal 2 0 4000 -4000 -2000 -3000 UAH",
+			"T1|Subtotal subtotal 1 0 500 -500 -500 -500 USD",
+			"Total| total 3 10000 4000 6000 2000 10000 UAH",
+			"Total| total 1 0 500 -500 -500 -500 USD",
+		}},
+		{by: "", withErr: true},
+		{by: "Unknown", withErr: true},
+		{by: "month(", withErr: true},
+	}
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			keys, err := ParseKeys(c.by, nil)
+			if err == nil {
+				var rows []*Row
+				if rows, err = Aggregate(statements, keys, nil, nil); err == nil {
+					actual := make([]string, len(rows))
+					for k, r := range rows {
+						names := make([]string, len(keys))
+						for n := range keys {
+							names[n] = r.Key(n)
+						}
+						actual[k] = fmt.Sprintf("%s %s %d %d %d %d %d %d %s", strings.Join(names, "|"), r.Level, r.Count,
+							r.Inflow, r.Outflow, r.Net, r.Average, r.Max, r.Currency)
+					}
+					require.Equal(t, c.expected, actual)
+				}
+			}
+			require.True(t, c.withErr == (err != nil), err)
+		})
+	}
+}
+
+func Test_Aggregate_Currencies(t *testing.T) {
+	statements := []p24.Statement{
+		{TranDate: time.Date(2022, 1, 3, 10, 0, 0, 0, time.UTC), CardAmount: p24.Funds{Currency: "UAH", Amount: 150000}},
+		{TranDate: time.Date(2022, 1, 4, 10, 0, 0, 0, time.UTC), CardAmount: p24.Funds{Currency: "USD", Amount: 10000}},
+	}
+	keys, err := ParseKeys("month", nil)
+	require.NoError(t, err)
+
+	// amounts of different currencies aren't added together
+	rows, err := Aggregate(statements, keys, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+	for i, expected := range []struct {
+		level, currency string
+		inflow          p24.Amount
+	}{{LevelGroup, "UAH", 150000}, {LevelGroup, "USD", 10000}, {LevelTotal, "UAH", 150000}, {LevelTotal, "USD", 10000}} {
+		require.Equal(t, expected.level, rows[i].Level)
+		require.Equal(t, expected.currency, rows[i].Currency)
+		require.Equal(t, expected.inflow, rows[i].Inflow)
+	}
+
+	// converted amounts have the same currency
+	rows, err = Aggregate(statements, keys, nil, func(s *p24.Statement) (p24.Funds, error) {
+		if s.CardAmount.Currency == "USD" {
+			return p24.Funds{Currency: "UAH", Amount: s.CardAmount.Amount * 37}, nil
+		}
+		return s.CardAmount, nil
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	require.Equal(t, p24.Amount(520000), rows[1].Inflow)
+	require.Equal(t, "UAH", rows[1].Currency)
+
+	rows, err = Aggregate(nil, keys, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	require.Equal(t, LevelTotal, rows[0].Level)
+}
+
+func Test_ReportExport(t *testing.T) {
+	statements := []p24.Statement{
+		{TranDate: time.Date(2022, 1, 3, 10, 0, 0, 0, time.UTC), CardAmount: p24.Funds{Currency: "UAH", Amount: -1050}},
+	}
+	keys, err := ParseKeys("month", nil)
+	require.NoError(t, err)
+	rows, err := Aggregate(statements, keys, nil, nil)
+	require.NoError(t, err)
+
+	format, err := export.MakeFormat(DefaultFormat(keys), export.DefaultFormatParser(Row{}, Fields(keys)))
+	require.NoError(t, err)
+	buff := bytes.NewBuffer(nil)
+	require.NoError(t, export.NewCSVRows(Rows(rows)).Export(buff, format))
+	require.Equal(t, "Month,Count,Inflow,Outflow,Net,Average,Max,Currency\n"+
+		"2022-01,1,0.00,10.50,-10.50,-10.50,-10.50,UAH\n"+
+		"Total,1,0.00,10.50,-10.50,-10.50,-10.50,UAH\n", buff.String())
+
+	buff.Reset()
+	require.NoError(t, export.NewTableRows(Rows(rows)).Export(buff, format))
+	require.Equal(t, "Month    Count  Inflow  Outflow  Net     Average  Max     Currency\n"+
+		"2022-01  1      0.00    10.50    -10.50  -10.50   -10.50  UAH\n"+
+		"Total    1      0.00    10.50    -10.50  -10.50   -10.50  UAH\n", buff.String())
+}
@@ -0,0 +1,264 @@
+package tax
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/pkg/errors"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// DefaultRate is the default single tax rate of the third group in percents
+const DefaultRate = 5
+
+// DefaultFormCode is the default declaration form code of a sole proprietor on the single tax.
+// Amounts elements codes have no defaults, they change with form versions and are taken from the form XSD schema
+const DefaultFormCode = "F0103309"
+
+// period types of the declaration head by quarter of the cumulative reporting period:
+// quarter, half-year, nine months and year
+var periodTypes = [...]int{0, 2, 3, 4, 5}
+
+var (
+	formCodeRegexp = regexp.MustCompile(`^[A-Z]\d{7}$`)
+	elemRegexp     = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+	// tinRegexp matches РНОКПП or passport series and number of persons refused from РНОКПП
+	tinRegexp        = regexp.MustCompile(`^(\d{10}|\d{9}|\p{Lu}{2}\d{6})$`)
+	officeCodeRegexp = regexp.MustCompile(`^\d{4}$`)
+)
+
+// Taxpayer is a sole proprietor filing the declaration
+type Taxpayer struct {
+	Name       string
+	TIN        string // РНОКПП
+	Address    string
+	Office     string  // tax office name
+	OfficeCode string  // tax office code of region and district, e.g. "2650"
+	Rate       float64 // single tax rate in percents, DefaultRate if zero
+	Limit      p24.Amount
+}
+
+// Form is a declaration form code and elements codes of its amounts
+type Form struct {
+	Code          string // document code, subcode and version, e.g. F0103309
+	Income        string // income of the reporting period since the beginning of the year
+	QuarterIncome string // income of the quarter
+	Tax           string // tax of the reporting period
+	PreviousTax   string // tax of the previous reporting period
+	Due           string // tax of the quarter
+}
+
+// Declaration is a quarterly declaration of a sole proprietor on the single tax.
+// Reporting periods are cumulative: the first quarter, half-year, nine months and year
+type Declaration struct {
+	Form          Form
+	Taxpayer      Taxpayer
+	Period        Period
+	Filled        time.Time
+	Income        p24.Amount // since the beginning of the year
+	QuarterIncome p24.Amount
+	Tax           p24.Amount
+	PreviousTax   p24.Amount
+	Due           p24.Amount // Tax - PreviousTax
+}
+
+// NewDeclaration returns declaration of the last quarter subtotal of ledger entries, see Ledger
+func NewDeclaration(entries []*Entry, payer Taxpayer, form Form, filled time.Time) (*Declaration, error) {
+	var subtotal *Entry
+	for _, e := range entries {
+		if e.Level == LevelSubtotal {
+			subtotal = e
+		}
+	}
+	if subtotal == nil {
+		return nil, errors.New("ledger has no quarter subtotals")
+	}
+
+	var p Period
+	if _, err := fmt.Sscanf(subtotal.Quarter, "%d Q%d", &p.Year, &p.Quarter); err != nil {
+		return nil, errors.Wrapf(err, "invalid quarter %q", subtotal.Quarter)
+	}
+	if payer.Rate == 0 {
+		payer.Rate = DefaultRate
+	}
+
+	d := &Declaration{
+		Form:          form,
+		Taxpayer:      payer,
+		Period:        p,
+		Filled:        filled,
+		Income:        subtotal.Cumulative,
+		QuarterIncome: subtotal.Income,
+		Tax:           percent(subtotal.Cumulative, payer.Rate),
+		PreviousTax:   percent(subtotal.Cumulative-subtotal.Income, payer.Rate),
+	}
+	d.Due = d.Tax - d.PreviousTax
+	return d, nil
+}
+
+// Validate checks required taxpayer details, codes and amounts of d before rendering.
+// The rendered xml is validated by the form XSD schema with Schema
+func (d *Declaration) Validate() error {
+	var errs []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(formCodeRegexp.MatchString(d.Form.Code), "form code %q is not a letter and 7 digits", d.Form.Code)
+	for _, elem := range []string{d.Form.Income, d.Form.QuarterIncome, d.Form.Tax, d.Form.PreviousTax, d.Form.Due} {
+		check(elem == "" || elemRegexp.MatchString(elem), "element code %q is invalid", elem)
+	}
+	check(d.Form.Income != "" && d.Form.QuarterIncome != "" && d.Form.Tax != "" && d.Form.PreviousTax != "" && d.Form.Due != "",
+		"amounts elements codes of the form are required")
+	check(strings.TrimSpace(d.Taxpayer.Name) != "", "taxpayer name is required")
+	check(tinRegexp.MatchString(d.Taxpayer.TIN), "taxpayer TIN %q is not 10 digits, 9 digits or passport series and number", d.Taxpayer.TIN)
+	check(officeCodeRegexp.MatchString(d.Taxpayer.OfficeCode), "tax office code %q is not 4 digits", d.Taxpayer.OfficeCode)
+	check(d.Taxpayer.Rate > 0 && d.Taxpayer.Rate <= 100, "tax rate %v%% is out of range", d.Taxpayer.Rate)
+	check(d.Period.Year > 0 && d.Period.Quarter >= 1 && d.Period.Quarter <= 4, "period %s is not a quarter", d.Period)
+	check(!d.Filled.IsZero(), "fill date is required")
+	check(d.Income >= 0 && d.QuarterIncome >= 0 && d.QuarterIncome <= d.Income, "income amounts are inconsistent")
+	check(d.Due == d.Tax-d.PreviousTax, "tax due is not the difference of the period and the previous period taxes")
+
+	if len(errs) > 0 {
+		return errors.Errorf("invalid declaration: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// LimitExceeded returns true if the income exceeds the taxpayer yearly income limit if it is set
+func (d *Declaration) LimitExceeded() bool {
+	return d.Taxpayer.Limit > 0 && d.Income > d.Taxpayer.Limit
+}
+
+// XML renders d as windows-1251 encoded xml of the electronic reporting format:
+// DECLARHEAD with document, period and tax office codes and DECLARBODY with taxpayer details and amounts
+func (d *Declaration) XML() ([]byte, error) {
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+
+	type nilElem struct {
+		XMLName xml.Name
+		Nil     string `xml:"xsi:nil,attr"`
+	}
+	type elem struct {
+		XMLName xml.Name
+		Value   string `xml:",chardata"`
+	}
+	e := func(name string, value interface{}) elem {
+		return elem{XMLName: xml.Name{Local: name}, Value: fmt.Sprint(value)}
+	}
+	amount := func(name string, a p24.Amount) elem {
+		return e(name, fmt.Sprintf("%.2f", a.Float64()))
+	}
+	fill := d.Filled.In(p24.NewKievLocation()).Format("02012006")
+	region, _ := strconv.Atoi(d.Taxpayer.OfficeCode[:2])
+	district, _ := strconv.Atoi(d.Taxpayer.OfficeCode[2:])
+	version, _ := strconv.Atoi(d.Form.Code[6:])
+
+	head := []interface{}{
+		e("TIN", d.Taxpayer.TIN),
+		e("C_DOC", d.Form.Code[:3]),
+		e("C_DOC_SUB", d.Form.Code[3:6]),
+		e("C_DOC_VER", version),
+		e("C_DOC_TYPE", 0),
+		e("C_DOC_CNT", 1),
+		e("C_REG", region),
+		e("C_RAJ", district),
+		e("PERIOD_MONTH", d.Period.Quarter*3),
+		e("PERIOD_TYPE", periodTypes[d.Period.Quarter]),
+		e("PERIOD_YEAR", d.Period.Year),
+		e("C_STI_ORIG", d.Taxpayer.OfficeCode),
+		e("C_DOC_STAN", 1),
+		nilElem{XMLName: xml.Name{Local: "LINKED_DOCS"}, Nil: "true"},
+		e("D_FILL", fill),
+		nilElem{XMLName: xml.Name{Local: "SOFTWARE"}, Nil: "true"},
+	}
+	body := []interface{}{
+		e("HZ", 1),
+		e("HZY", d.Period.Year),
+		e("HZKV", d.Period.Quarter),
+		e("HNAME", d.Taxpayer.Name),
+		e("HTIN", d.Taxpayer.TIN),
+		e("HLOC", d.Taxpayer.Address),
+		e("HSTI", d.Taxpayer.Office),
+		amount(d.Form.Income, d.Income),
+		amount(d.Form.QuarterIncome, d.QuarterIncome),
+		amount(d.Form.Tax, d.Tax),
+		amount(d.Form.PreviousTax, d.PreviousTax),
+		amount(d.Form.Due, d.Due),
+		e("HFILL", fill),
+		e("HBOS", d.Taxpayer.Name),
+		e("HKBOS", d.Taxpayer.TIN),
+	}
+	doc := struct {
+		XMLName xml.Name      `xml:"DECLAR"`
+		XSI     string        `xml:"xmlns:xsi,attr"`
+		Schema  string        `xml:"xsi:noNamespaceSchemaLocation,attr"`
+		Head    []interface{} `xml:"DECLARHEAD>elem"`
+		Body    []interface{} `xml:"DECLARBODY>elem"`
+	}{
+		XSI:    "http://www.w3.org/2001/XMLSchema-instance",
+		Schema: d.Form.Code + ".xsd",
+		Head:   head,
+		Body:   body,
+	}
+
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal declaration")
+	}
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="windows-1251"?>` + "\n")
+	w := charmap.Windows1251.NewEncoder().Writer(&buf)
+	if _, err := w.Write([]byte(windows1251(string(data)))); err != nil {
+		return nil, errors.Wrap(err, "failed to encode declaration to windows-1251")
+	}
+	return buf.Bytes(), nil
+}
+
+// Summary returns human-readable lines of the declaration amounts
+func (d *Declaration) Summary() []string {
+	periods := [...]string{"", "the first quarter of %d", "the half-year of %d", "nine months of %d", "the year %d"}
+	period := fmt.Sprintf(periods[d.Period.Quarter], d.Period.Year)
+	lines := []string{
+		fmt.Sprintf("%s declaration for %s, %s %s", d.Form.Code, period, d.Taxpayer.Name, d.Taxpayer.TIN),
+		fmt.Sprintf("income of Q%d: %.2f %s", d.Period.Quarter, d.QuarterIncome.Float64(), Currency),
+		fmt.Sprintf("income since the beginning of the year: %.2f %s", d.Income.Float64(), Currency),
+		fmt.Sprintf("single tax %v%%: %.2f %s", d.Taxpayer.Rate, d.Tax.Float64(), Currency),
+		fmt.Sprintf("tax of the previous period: %.2f %s", d.PreviousTax.Float64(), Currency),
+		fmt.Sprintf("tax due for Q%d: %.2f %s", d.Period.Quarter, d.Due.Float64(), Currency),
+	}
+	if d.LimitExceeded() {
+		lines = append(lines, fmt.Sprintf("WARNING: income exceeds the yearly limit %.2f %s", d.Taxpayer.Limit.Float64(), Currency))
+	}
+	return lines
+}
+
+// windows1251 replaces runes which windows-1251 hasn't: the modifier letter apostrophe of Ukrainian names
+// by the ascii one and others by '?'
+func windows1251(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == 'ʼ' {
+			return '\''
+		}
+		if _, ok := charmap.Windows1251.EncodeRune(r); !ok {
+			return '?'
+		}
+		return r
+	}, s)
+}
+
+// percent returns rate percents of a rounded to kopecks
+func percent(a p24.Amount, rate float64) p24.Amount {
+	return p24.Amount(math.Round(float64(a) * rate / 100))
+}
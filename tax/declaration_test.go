@@ -0,0 +1,180 @@
+package tax
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// testForm has amounts elements codes of the test schema, see testFormSchema
+var testForm = Form{Code: DefaultFormCode, Income: "R01G3", QuarterIncome: "R02G3", Tax: "R03G3", PreviousTax: "R04G3", Due: "R05G3"}
+
+func Test_NewDeclaration(t *testing.T) {
+	kiev := p24.NewKievLocation()
+	statements := []p24.Statement{
+		{TranDate: time.Date(2022, 2, 1, 10, 0, 0, 0, kiev), CardAmount: p24.Funds{Currency: "UAH", Amount: 3000000}},
+		{TranDate: time.Date(2022, 5, 1, 10, 0, 0, 0, kiev), CardAmount: p24.Funds{Currency: "UAH", Amount: 2000000}},
+		{TranDate: time.Date(2022, 8, 1, 10, 0, 0, 0, kiev), CardAmount: p24.Funds{Currency: "UAH", Amount: 1000005}},
+	}
+	payer := Taxpayer{Name: "Іваненко Іван Іванович", TIN: "1234567890", OfficeCode: "2650"}
+	filled := time.Date(2022, 10, 15, 0, 0, 0, 0, kiev)
+
+	cases := []struct {
+		period                             Period
+		income, quarterIncome, tax, prevTx p24.Amount
+	}{
+		{period: Period{Year: 2022, Quarter: 1}, income: 3000000, quarterIncome: 3000000, tax: 150000},
+		{period: Period{Year: 2022, Quarter: 2}, income: 5000000, quarterIncome: 2000000, tax: 250000, prevTx: 150000},
+		{period: Period{Year: 2022, Quarter: 3}, income: 6000005, quarterIncome: 1000005, tax: 300000, prevTx: 250000},
+		// the year declaration is the fourth quarter one
+		{period: Period{Year: 2022}, income: 6000005, tax: 300000, prevTx: 300000},
+	}
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			entries, err := Ledger(statements, c.period, nil)
+			require.NoError(t, err)
+			d, err := NewDeclaration(entries, payer, testForm, filled)
+			require.NoError(t, err)
+			require.NoError(t, d.Validate())
+
+			quarter := c.period.Quarter
+			if quarter == 0 {
+				quarter = 4
+			}
+			require.Equal(t, Period{Year: 2022, Quarter: quarter}, d.Period)
+			require.Equal(t, float64(DefaultRate), d.Taxpayer.Rate)
+			require.Equal(t, c.income, d.Income)
+			require.Equal(t, c.quarterIncome, d.QuarterIncome)
+			require.Equal(t, c.tax, d.Tax)
+			require.Equal(t, c.prevTx, d.PreviousTax)
+			require.Equal(t, c.tax-c.prevTx, d.Due)
+		})
+	}
+
+	_, err := NewDeclaration(nil, payer, testForm, filled)
+	require.Error(t, err)
+
+	d := &Declaration{Taxpayer: Taxpayer{Limit: 5000000}, Income: 5000001}
+	require.True(t, d.LimitExceeded())
+	d.Taxpayer.Limit = 0
+	require.False(t, d.LimitExceeded())
+}
+
+func Test_Declaration_Validate(t *testing.T) {
+	valid := func() Declaration {
+		return Declaration{
+			Form:     testForm,
+			Taxpayer: Taxpayer{Name: "Іваненко Іван Іванович", TIN: "1234567890", OfficeCode: "2650", Rate: 5},
+			Period:   Period{Year: 2022, Quarter: 2},
+			Filled:   time.Date(2022, 7, 15, 0, 0, 0, 0, p24.NewKievLocation()),
+			Income:   500, QuarterIncome: 200, Tax: 25, PreviousTax: 15, Due: 10,
+		}
+	}
+	cases := []struct {
+		modify func(d *Declaration)
+		err    bool
+	}{
+		{modify: func(d *Declaration) {}},
+		{modify: func(d *Declaration) { d.Taxpayer.TIN = "АБ123456" }},
+		{modify: func(d *Declaration) { d.Taxpayer.TIN = "12345" }, err: true},
+		{modify: func(d *Declaration) { d.Taxpayer.Name = " " }, err: true},
+		{modify: func(d *Declaration) { d.Taxpayer.OfficeCode = "26" }, err: true},
+		{modify: func(d *Declaration) { d.Taxpayer.Rate = 0 }, err: true},
+		{modify: func(d *Declaration) { d.Form.Code = "F01033" }, err: true},
+		{modify: func(d *Declaration) { d.Form.Income = "r01 g3" }, err: true},
+		{modify: func(d *Declaration) { d.Form.Due = "" }, err: true},
+		{modify: func(d *Declaration) { d.Period.Quarter = 0 }, err: true},
+		{modify: func(d *Declaration) { d.Filled = time.Time{} }, err: true},
+		{modify: func(d *Declaration) { d.QuarterIncome = 600 }, err: true},
+		{modify: func(d *Declaration) { d.Due = 25 }, err: true},
+	}
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			d := valid()
+			c.modify(&d)
+			if c.err {
+				require.Error(t, d.Validate())
+				return
+			}
+			require.NoError(t, d.Validate())
+		})
+	}
+}
+
+func Test_Declaration_XML(t *testing.T) {
+	d := &Declaration{
+		Form:     testForm,
+		Taxpayer: Taxpayer{Name: "Іваненко Іван Іванович", TIN: "1234567890", Address: "м. Київ", OfficeCode: "2650", Rate: 5},
+		Period:   Period{Year: 2022, Quarter: 3},
+		Filled:   time.Date(2022, 10, 15, 0, 0, 0, 0, p24.NewKievLocation()),
+		Income:   6000005, QuarterIncome: 1000005, Tax: 300000, PreviousTax: 250000, Due: 50000,
+	}
+	data, err := d.XML()
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(data, []byte(`<?xml version="1.0" encoding="windows-1251"?>`)))
+
+	var doc struct {
+		Head struct {
+			Elems []struct {
+				XMLName xml.Name
+				Value   string `xml:",chardata"`
+			} `xml:",any"`
+		} `xml:"DECLARHEAD"`
+		Body struct {
+			Name   string `xml:"HNAME"`
+			Income string `xml:"R01G3"`
+			Due    string `xml:"R05G3"`
+		} `xml:"DECLARBODY"`
+	}
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		require.Equal(t, "windows-1251", charset)
+		return charmap.Windows1251.NewDecoder().Reader(input), nil
+	}
+	require.NoError(t, dec.Decode(&doc))
+
+	head := map[string]string{}
+	for _, e := range doc.Head.Elems {
+		head[e.XMLName.Local] = e.Value
+	}
+	require.Equal(t, map[string]string{
+		"TIN": "1234567890", "C_DOC": "F01", "C_DOC_SUB": "033", "C_DOC_VER": "9", "C_DOC_TYPE": "0", "C_DOC_CNT": "1",
+		"C_REG": "26", "C_RAJ": "50", "PERIOD_MONTH": "9", "PERIOD_TYPE": "4", "PERIOD_YEAR": "2022", "C_STI_ORIG": "2650",
+		"C_DOC_STAN": "1", "LINKED_DOCS": "", "D_FILL": "15102022", "SOFTWARE": "",
+	}, head)
+	require.Equal(t, "Іваненко Іван Іванович", doc.Body.Name)
+	require.Equal(t, "60000.05", doc.Body.Income)
+	require.Equal(t, "500.00", doc.Body.Due)
+
+	summary := d.Summary()
+	require.Len(t, summary, 6)
+	require.Equal(t, "F0103309 declaration for nine months of 2022, Іваненко Іван Іванович 1234567890", summary[0])
+	require.Equal(t, "tax due for Q3: 500.00 UAH", summary[5])
+	d.Taxpayer.Limit = 5000000
+	require.Len(t, d.Summary(), 7, "exceeded limit is warned")
+
+	// runes out of windows-1251 are replaced
+	d.Taxpayer.Name, d.Taxpayer.Address = "Мельничук Марʼяна Ігорівна", "м. Київ, вул. Обʼїзна 1 ₴"
+	data, err = d.XML()
+	require.NoError(t, err)
+	dec = xml.NewDecoder(bytes.NewReader(data))
+	dec.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return charmap.Windows1251.NewDecoder().Reader(input), nil
+	}
+	require.NoError(t, dec.Decode(&doc))
+	require.Equal(t, "Мельничук Мар'яна Ігорівна", doc.Body.Name)
+	require.Contains(t, string(data), "1 ?</HLOC>")
+
+	d.Taxpayer.TIN = ""
+	_, err = d.XML()
+	require.Error(t, err)
+}
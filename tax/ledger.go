@@ -0,0 +1,213 @@
+// Package tax builds the income ledger of a sole proprietor (FOP) on the single tax
+package tax
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/export"
+	"github.com/dimboknv/p24-cli/fx"
+	"github.com/pkg/errors"
+)
+
+// Currency of income
+const Currency = "UAH"
+
+// DefaultExclude is the default expression of statements excluded from income
+const DefaultExclude = "IsTransfer || IsRefund"
+
+// Entry levels
+const (
+	LevelReceipt  = "receipt"
+	LevelSubtotal = "subtotal"
+	LevelTotal    = "total"
+)
+
+// Period is a year or a quarter of a year if Quarter is not zero
+type Period struct {
+	Year    int
+	Quarter int // 1-4 or zero for the whole year
+}
+
+// Entry is a receipt of the ledger, a quarter subtotal or a year total.
+// Income is the received amount converted to UAH by the rate at the receipt date,
+// Cumulative is income since the beginning of the year
+type Entry struct {
+	Level       string
+	Quarter     string
+	RateDate    string
+	Income      p24.Amount
+	Cumulative  p24.Amount
+	Count       int // count of receipts
+	Card        string
+	Description string
+
+	n        int // ordinal number of a receipt in the year
+	date     time.Time
+	received p24.Funds
+	rate     float64
+}
+
+// Check returns an error if p is invalid
+func (p Period) Check() error {
+	if p.Year < 1 {
+		return errors.Errorf("invalid year %d", p.Year)
+	}
+	if p.Quarter < 0 || p.Quarter > 4 {
+		return errors.Errorf("invalid quarter %d, expected 1-4", p.Quarter)
+	}
+	return nil
+}
+
+// Bounds returns the first and the last days of p in Kyiv time
+func (p Period) Bounds() (start, end time.Time) {
+	first, months := 1, 12
+	if p.Quarter > 0 {
+		first, months = (p.Quarter-1)*3+1, 3
+	}
+	start = time.Date(p.Year, time.Month(first), 1, 0, 0, 0, 0, p24.NewKievLocation())
+	return start, start.AddDate(0, months, -1)
+}
+
+func (p Period) String() string {
+	if p.Quarter == 0 {
+		return fmt.Sprint(p.Year)
+	}
+	return fmt.Sprintf("%d Q%d", p.Year, p.Quarter)
+}
+
+// Quarter returns the quarter of t in Kyiv time, 1-4
+func Quarter(t time.Time) int {
+	return (int(t.In(p24.NewKievLocation()).Month())-1)/3 + 1
+}
+
+// Ledger returns entries of incoming statements of period p sorted by date, each quarter is followed by a subtotal
+// and the year is followed by a total. Statements of previous quarters of the year are counted in N and Cumulative.
+// A receipt is Amount of a statement or CardAmount if Amount is not set, so foreign currency receipts credited
+// to UAH cards are converted by the rates at the receipt date too. rates are required for foreign currency receipts only
+func Ledger(statements []p24.Statement, p Period, rates *fx.Table) ([]*Entry, error) {
+	start, _ := Period{Year: p.Year}.Bounds()
+	_, end := p.Bounds()
+	end = end.AddDate(0, 0, 1)
+
+	var receipts []*p24.Statement
+	for i := range statements {
+		s := &statements[i]
+		if s.CardAmount.Amount > 0 && !s.TranDate.Before(start) && s.TranDate.Before(end) {
+			receipts = append(receipts, s)
+		}
+	}
+	sort.SliceStable(receipts, func(i, j int) bool { return receipts[i].TranDate.Before(receipts[j].TranDate) })
+
+	first, last := 1, 4
+	if p.Quarter > 0 {
+		first, last = p.Quarter, p.Quarter
+	}
+	var (
+		res        []*Entry
+		count, i   int
+		cumulative p24.Amount
+		total      = &Entry{Level: LevelTotal, Quarter: p.String()}
+	)
+	for q := 1; q <= last; q++ {
+		subtotal := &Entry{Level: LevelSubtotal, Quarter: Period{Year: p.Year, Quarter: q}.String()}
+		for ; i < len(receipts) && Quarter(receipts[i].TranDate) == q; i++ {
+			e, err := receipt(receipts[i], rates)
+			if err != nil {
+				return nil, err
+			}
+			count++
+			cumulative += e.Income
+			e.n, e.Cumulative = count, cumulative
+			subtotal.Count++
+			subtotal.Income += e.Income
+			if q >= first {
+				res = append(res, e)
+			}
+		}
+		subtotal.Cumulative = cumulative
+		if q >= first {
+			res = append(res, subtotal)
+			total.Count += subtotal.Count
+			total.Income += subtotal.Income
+		}
+	}
+	if p.Quarter == 0 {
+		total.Cumulative = cumulative
+		res = append(res, total)
+	}
+	return res, nil
+}
+
+// Rows returns export rows of ledger entries
+func Rows(entries []*Entry) export.Rows {
+	items := make([]interface{}, len(entries))
+	for i := range entries {
+		items[i] = entries[i]
+	}
+	return export.Rows{Root: "ledger", Elem: "entry", Items: items}
+}
+
+// Fields returns virtual fields of receipts, they are empty for subtotals and totals:
+// - N is the ordinal number of a receipt in the year
+// - Date is the receipt date
+// - Received is the received amount in its currency
+// - Rate is the rate of the received currency to UAH
+func Fields() export.VirtualFields {
+	field := func(get func(e *Entry) interface{}) export.VirtualField {
+		return func(obj interface{}) (interface{}, error) {
+			var e *Entry
+			switch v := obj.(type) {
+			case *Entry:
+				e = v
+			case Entry:
+				e = &v
+			default:
+				return nil, errors.Errorf("%T is not a ledger entry", obj)
+			}
+			if e.Level != LevelReceipt {
+				return nil, nil
+			}
+			return get(e), nil
+		}
+	}
+	return export.VirtualFields{
+		"N":        field(func(e *Entry) interface{} { return e.n }),
+		"Date":     field(func(e *Entry) interface{} { return e.date }),
+		"Received": field(func(e *Entry) interface{} { return e.received }),
+		"Rate":     field(func(e *Entry) interface{} { return e.rate }),
+	}
+}
+
+// receipt returns the ledger entry of incoming statement s
+func receipt(s *p24.Statement, rates *fx.Table) (*Entry, error) {
+	received := s.Amount
+	if received.Currency == "" || received.Amount <= 0 {
+		received = s.CardAmount
+	}
+	e := &Entry{
+		Level:       LevelReceipt,
+		Quarter:     Period{Year: s.TranDate.In(p24.NewKievLocation()).Year(), Quarter: Quarter(s.TranDate)}.String(),
+		Income:      received.Amount,
+		Count:       1,
+		Card:        s.Card,
+		Description: s.Description,
+		date:        s.TranDate,
+		received:    received,
+		rate:        1,
+	}
+	if received.Currency == Currency {
+		return e, nil
+	}
+	if rates == nil {
+		return nil, errors.Errorf("exchange rates are required for %s receipt at %s", received.Currency, s.TranDate.Format(fx.DateLayout))
+	}
+	income, r, err := rates.Convert(received, Currency, s.TranDate)
+	if err != nil {
+		return nil, err
+	}
+	e.rate, e.RateDate, e.Income = r.Rate, r.Date.Format(fx.DateLayout), income.Amount
+	return e, nil
+}
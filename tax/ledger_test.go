@@ -0,0 +1,148 @@
+package tax
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/fx"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Period(t *testing.T) {
+	kiev := p24.NewKievLocation()
+	cases := []struct {
+		period     Period
+		start, end time.Time
+		str        string
+		err        bool
+	}{
+		{
+			period: Period{Year: 2022}, str: "2022",
+			start: time.Date(2022, 1, 1, 0, 0, 0, 0, kiev), end: time.Date(2022, 12, 31, 0, 0, 0, 0, kiev),
+		},
+		{
+			period: Period{Year: 2022, Quarter: 1}, str: "2022 Q1",
+			start: time.Date(2022, 1, 1, 0, 0, 0, 0, kiev), end: time.Date(2022, 3, 31, 0, 0, 0, 0, kiev),
+		},
+		{
+			period: Period{Year: 2022, Quarter: 4}, str: "2022 Q4",
+			start: time.Date(2022, 10, 1, 0, 0, 0, 0, kiev), end: time.Date(2022, 12, 31, 0, 0, 0, 0, kiev),
+		},
+		{period: Period{Year: 2022, Quarter: 5}, err: true},
+		{period: Period{Quarter: 1}, err: true},
+	}
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			if c.err {
+				require.Error(t, c.period.Check())
+				return
+			}
+			require.NoError(t, c.period.Check())
+			start, end := c.period.Bounds()
+			require.Equal(t, c.start, start)
+			require.Equal(t, c.end, end)
+			require.Equal(t, c.str, c.period.String())
+		})
+	}
+}
+
+func Test_Ledger(t *testing.T) {
+	kiev := p24.NewKievLocation()
+	statements := []p24.Statement{
+		{TranDate: time.Date(2022, 5, 5, 10, 0, 0, 0, kiev), Amount: p24.Funds{Currency: "UAH", Amount: 2000000}, CardAmount: p24.Funds{Currency: "UAH", Amount: 2000000}},
+		{TranDate: time.Date(2022, 1, 15, 12, 0, 0, 0, kiev), Amount: p24.Funds{Currency: "USD", Amount: 100000}, CardAmount: p24.Funds{Currency: "USD", Amount: 100000}},
+		// expenses and statements of other years are skipped
+		{TranDate: time.Date(2022, 2, 15, 12, 0, 0, 0, kiev), Amount: p24.Funds{Currency: "USD", Amount: -30000}, CardAmount: p24.Funds{Currency: "USD", Amount: -30000}},
+		{TranDate: time.Date(2021, 12, 31, 23, 0, 0, 0, kiev), Amount: p24.Funds{Currency: "UAH", Amount: 100000}, CardAmount: p24.Funds{Currency: "UAH", Amount: 100000}},
+		// foreign currency receipt credited to UAH card is converted by the rate at the receipt date
+		{TranDate: time.Date(2022, 12, 30, 10, 0, 0, 0, kiev), Amount: p24.Funds{Currency: "EUR", Amount: 50000}, CardAmount: p24.Funds{Currency: "UAH", Amount: 1500000}},
+	}
+	rates := fx.NewTable(
+		fx.Rate{Date: time.Date(2022, 1, 14, 0, 0, 0, 0, kiev), Currency: "USD", Rate: 29.25},
+		fx.Rate{Date: time.Date(2022, 12, 30, 0, 0, 0, 0, kiev), Currency: "EUR", Rate: 38.95},
+	)
+
+	type row struct {
+		level, quarter string
+		n, count       int
+		income, cumul  p24.Amount
+	}
+	cases := []struct {
+		period   Period
+		expected []row
+	}{
+		{
+			period: Period{Year: 2022},
+			expected: []row{
+				{LevelReceipt, "2022 Q1", 1, 1, 2925000, 2925000},
+				{LevelSubtotal, "2022 Q1", 0, 1, 2925000, 2925000},
+				{LevelReceipt, "2022 Q2", 2, 1, 2000000, 4925000},
+				{LevelSubtotal, "2022 Q2", 0, 1, 2000000, 4925000},
+				{LevelSubtotal, "2022 Q3", 0, 0, 0, 4925000},
+				{LevelReceipt, "2022 Q4", 3, 1, 1947500, 6872500},
+				{LevelSubtotal, "2022 Q4", 0, 1, 1947500, 6872500},
+				{LevelTotal, "2022", 0, 3, 6872500, 6872500},
+			},
+		},
+		{
+			// receipts of previous quarters are counted in N and Cumulative
+			period: Period{Year: 2022, Quarter: 2},
+			expected: []row{
+				{LevelReceipt, "2022 Q2", 2, 1, 2000000, 4925000},
+				{LevelSubtotal, "2022 Q2", 0, 1, 2000000, 4925000},
+			},
+		},
+		{
+			period: Period{Year: 2022, Quarter: 3},
+			expected: []row{
+				{LevelSubtotal, "2022 Q3", 0, 0, 0, 4925000},
+			},
+		},
+	}
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			entries, err := Ledger(statements, c.period, rates)
+			require.NoError(t, err)
+			res := make([]row, len(entries))
+			for k, e := range entries {
+				res[k] = row{e.Level, e.Quarter, e.n, e.Count, e.Income, e.Cumulative}
+			}
+			require.Equal(t, c.expected, res)
+		})
+	}
+
+	_, err := Ledger(statements, Period{Year: 2022}, nil)
+	require.Error(t, err, "rates are required for foreign currency receipts")
+	_, err = Ledger(statements, Period{Year: 2022, Quarter: 2}, fx.NewTable())
+	require.Error(t, err, "previous quarters receipts are converted too")
+}
+
+func Test_Fields(t *testing.T) {
+	kiev := p24.NewKievLocation()
+	statements := []p24.Statement{{
+		TranDate:   time.Date(2022, 1, 15, 12, 0, 0, 0, kiev),
+		Amount:     p24.Funds{Currency: "UAH", Amount: 100},
+		CardAmount: p24.Funds{Currency: "UAH", Amount: 100},
+	}}
+	entries, err := Ledger(statements, Period{Year: 2022, Quarter: 1}, nil)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	fields := Fields()
+	for name, expected := range map[string]interface{}{"N": 1, "Date": statements[0].TranDate, "Received": statements[0].Amount, "Rate": 1.0} {
+		v, err := fields[name](entries[0])
+		require.NoError(t, err)
+		require.Equal(t, expected, v, name)
+
+		v, err = fields[name](*entries[1])
+		require.NoError(t, err)
+		require.Nil(t, v, name)
+	}
+
+	_, err = fields["Date"](statements[0])
+	require.Error(t, err)
+}
@@ -0,0 +1,585 @@
+package tax
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Schema is elements and simple types of a published form XSD schema. It validates rendered declarations
+// offline: child elements must match sequence, choice and all groups of their parents with their occurrences
+// and values must match their simple types bases and facets. XSD features which aren't supported, e.g. patterns
+// which regexp package can't compile, lists, unions, wildcards and groups references, fail validation of elements
+// using them instead of being skipped. Attributes aren't validated.
+// The schema isn't bundled since form versions change, it is published by the tax service
+type Schema struct {
+	elements map[string]*schemaElement  // global and nested elements by name
+	complex  map[string]*schemaParticle // content of named complex types
+	simple   map[string]*schemaType     // named simple types
+	loaded   map[string]bool            // loaded files of includes
+}
+
+type schemaElement struct {
+	name        string
+	ref         string
+	typ         string          // type name without namespace prefix
+	inline      *schemaType     // anonymous simple type
+	content     *schemaParticle // anonymous complex type content, nil if it is empty or the element is simple
+	complex     bool            // has anonymous complex type
+	nillable    bool
+	unsupported string // unsupported features of anonymous complex type
+}
+
+// schemaParticle is an element or a group of particles with occurrences
+type schemaParticle struct {
+	element  *schemaElement    // nil for groups
+	kind     string            // sequence, choice or all of groups
+	items    []*schemaParticle // particles of groups
+	min, max int               // occurrences, max is -1 if unbounded
+}
+
+type schemaType struct {
+	base           string
+	patterns       []*regexp.Regexp
+	enumeration    []string
+	minLength      int
+	maxLength      int // -1 if unset
+	totalDigits    int // 0 if unset
+	fractionDigits int // -1 if unset
+	bounds         []schemaBound
+	unsupported    []string // facets and derivations which aren't supported
+}
+
+// schemaBound is minInclusive, maxInclusive, minExclusive or maxExclusive facet
+type schemaBound struct {
+	facet string
+	value float64
+}
+
+// xsdNode is an element of XSD schema or xml document
+type xsdNode struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Value   string     `xml:",chardata"`
+	Nodes   []xsdNode  `xml:",any"`
+}
+
+var (
+	decimalRegexp = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)$`)
+	integerRegexp = regexp.MustCompile(`^[+-]?\d+$`)
+	booleanRegexp = regexp.MustCompile(`^(true|false|0|1)$`)
+	dateRegexp    = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+)
+
+// LoadSchema reads XSD schema of the form from filename with its includes relative to it
+func LoadSchema(filename string) (*Schema, error) {
+	s := &Schema{
+		elements: map[string]*schemaElement{},
+		complex:  map[string]*schemaParticle{},
+		simple:   map[string]*schemaType{},
+		loaded:   map[string]bool{},
+	}
+	if err := s.load(filepath.Clean(filename)); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Schema) load(filename string) error {
+	if s.loaded[filename] {
+		return nil
+	}
+	s.loaded[filename] = true
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read schema %q", filename)
+	}
+	var root xsdNode
+	if err = decodeXML(data, &root); err != nil {
+		return errors.Wrapf(err, "failed to parse schema %q", filename)
+	}
+	if root.XMLName.Local != "schema" {
+		return errors.Errorf("%q isn't XSD schema", filename)
+	}
+
+	for _, n := range root.Nodes {
+		switch n.XMLName.Local {
+		case "include", "import":
+			if location := n.attr("schemaLocation"); location != "" {
+				if err = s.load(filepath.Join(filepath.Dir(filename), location)); err != nil {
+					return err
+				}
+			}
+		case "element":
+			_, err = s.element(n)
+		case "complexType":
+			var unsupported string
+			if s.complex[n.attr("name")], unsupported, err = s.complexType(n); err == nil && unsupported != "" {
+				// named complex types of unsupported features fail validation of their elements
+				s.complex[n.attr("name")] = &schemaParticle{element: &schemaElement{unsupported: unsupported}}
+			}
+		case "simpleType":
+			s.simple[n.attr("name")], err = simpleType(n)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "invalid schema %q", filename)
+		}
+	}
+	return nil
+}
+
+// element parses n element declaration and registers it by name
+func (s *Schema) element(n xsdNode) (*schemaElement, error) {
+	e := &schemaElement{
+		name:     n.attr("name"),
+		ref:      localName(n.attr("ref")),
+		typ:      localName(n.attr("type")),
+		nillable: n.attr("nillable") == "true",
+	}
+	for _, c := range n.Nodes {
+		var err error
+		switch c.XMLName.Local {
+		case "simpleType":
+			e.inline, err = simpleType(c)
+		case "complexType":
+			e.complex = true
+			e.content, e.unsupported, err = s.complexType(c)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid element %q", e.name)
+		}
+	}
+	if _, ok := s.elements[e.name]; !ok && e.name != "" {
+		s.elements[e.name] = e
+	}
+	return e, nil
+}
+
+// complexType returns content particle of n complex type and its unsupported features
+func (s *Schema) complexType(n xsdNode) (content *schemaParticle, unsupported string, err error) {
+	for _, c := range n.Nodes {
+		switch c.XMLName.Local {
+		case "sequence", "choice", "all":
+			if content, err = s.particle(c); err != nil {
+				return nil, "", err
+			}
+			if content.element != nil {
+				unsupported = content.element.unsupported
+				content = nil
+			}
+		case "simpleContent", "complexContent", "group", "any":
+			unsupported = c.XMLName.Local
+		}
+	}
+	return content, unsupported, nil
+}
+
+// particle parses n element or group with its occurrences. Unsupported groups are returned as
+// an element particle with unsupported features
+func (s *Schema) particle(n xsdNode) (*schemaParticle, error) {
+	p := &schemaParticle{kind: n.XMLName.Local, min: 1, max: 1}
+	if v := n.attr("minOccurs"); v != "" {
+		var err error
+		if p.min, err = strconv.Atoi(v); err != nil {
+			return nil, errors.Wrapf(err, "invalid minOccurs")
+		}
+	}
+	switch v := n.attr("maxOccurs"); v {
+	case "":
+	case "unbounded":
+		p.max = -1
+	default:
+		var err error
+		if p.max, err = strconv.Atoi(v); err != nil {
+			return nil, errors.Wrapf(err, "invalid maxOccurs")
+		}
+	}
+
+	if p.kind == "element" {
+		e, err := s.element(n)
+		p.element = e
+		return p, err
+	}
+	for _, c := range n.Nodes {
+		switch c.XMLName.Local {
+		case "element", "sequence", "choice", "all":
+			item, err := s.particle(c)
+			if err != nil {
+				return nil, err
+			}
+			p.items = append(p.items, item)
+		case "group", "any":
+			return &schemaParticle{element: &schemaElement{unsupported: c.XMLName.Local}}, nil
+		}
+	}
+	return p, nil
+}
+
+// simpleType parses restriction of n simple type, lists, unions and unknown facets are unsupported
+func simpleType(n xsdNode) (*schemaType, error) {
+	t := &schemaType{base: "string", maxLength: -1, fractionDigits: -1}
+	for _, r := range n.Nodes {
+		if r.XMLName.Local != "restriction" {
+			if r.XMLName.Local == "list" || r.XMLName.Local == "union" {
+				t.unsupported = append(t.unsupported, r.XMLName.Local)
+			}
+			continue
+		}
+		t.base = localName(r.attr("base"))
+		for _, f := range r.Nodes {
+			value := f.attr("value")
+			var err error
+			switch f.XMLName.Local {
+			case "pattern":
+				re, reErr := regexp.Compile(`^(?:` + value + `)$`)
+				if reErr != nil {
+					t.unsupported = append(t.unsupported, fmt.Sprintf("pattern %q", value))
+					continue
+				}
+				t.patterns = append(t.patterns, re)
+			case "enumeration":
+				t.enumeration = append(t.enumeration, value)
+			case "length":
+				if t.minLength, err = strconv.Atoi(value); err == nil {
+					t.maxLength = t.minLength
+				}
+			case "minLength":
+				t.minLength, err = strconv.Atoi(value)
+			case "maxLength":
+				t.maxLength, err = strconv.Atoi(value)
+			case "totalDigits":
+				t.totalDigits, err = strconv.Atoi(value)
+			case "fractionDigits":
+				t.fractionDigits, err = strconv.Atoi(value)
+			case "minInclusive", "maxInclusive", "minExclusive", "maxExclusive":
+				var v float64
+				if v, err = strconv.ParseFloat(value, 64); err == nil {
+					t.bounds = append(t.bounds, schemaBound{facet: f.XMLName.Local, value: v})
+				}
+			case "whiteSpace", "annotation":
+				// values are trimmed before checks
+			default:
+				t.unsupported = append(t.unsupported, f.XMLName.Local)
+			}
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid %s facet of type %q", f.XMLName.Local, n.attr("name"))
+			}
+		}
+	}
+	return t, nil
+}
+
+// Validate checks windows-1251 or utf-8 encoded declaration xml by the schema
+func (s *Schema) Validate(data []byte) error {
+	var root xsdNode
+	if err := decodeXML(data, &root); err != nil {
+		return errors.Wrap(err, "failed to parse declaration xml")
+	}
+
+	var errs []string
+	if e, ok := s.elements[root.XMLName.Local]; ok {
+		errs = s.validate(root, e, root.XMLName.Local, errs)
+	} else {
+		errs = append(errs, fmt.Sprintf("root element %s isn't declared", root.XMLName.Local))
+	}
+	if len(errs) > 0 {
+		return errors.Errorf("declaration xml doesn't match the schema: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (s *Schema) validate(n xsdNode, e *schemaElement, path string, errs []string) []string {
+	if e.ref != "" {
+		ref, ok := s.elements[e.ref]
+		if !ok {
+			return append(errs, fmt.Sprintf("%s refers to undeclared element %s", path, e.ref))
+		}
+		e = ref
+	}
+	if n.attr("nil") == "true" {
+		if !e.nillable {
+			errs = append(errs, fmt.Sprintf("%s isn't nillable", path))
+		}
+		return errs
+	}
+
+	content, complexType, unsupported := e.content, e.complex, e.unsupported
+	if c, ok := s.complex[e.typ]; ok {
+		content, complexType = c, true
+		if c.element != nil {
+			content, unsupported = nil, c.element.unsupported
+		}
+	}
+	switch {
+	case unsupported != "":
+		return append(errs, fmt.Sprintf("%s content of %s isn't supported", path, unsupported))
+	case !complexType:
+		if len(n.Nodes) > 0 {
+			return append(errs, fmt.Sprintf("%s has child elements", path))
+		}
+		if err := s.check(e, strings.TrimSpace(n.Value)); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", path, err))
+		}
+		return errs
+	case content == nil:
+		if len(n.Nodes) > 0 {
+			errs = append(errs, fmt.Sprintf("%s has child elements", path))
+		}
+		return errs
+	}
+
+	declared := map[string]*schemaElement{}
+	content.elements(declared)
+	valid := true
+	for _, c := range n.Nodes {
+		if declared[c.XMLName.Local] == nil {
+			errs, valid = append(errs, fmt.Sprintf("%s/%s isn't declared", path, c.XMLName.Local)), false
+		}
+	}
+	present := map[string]bool{}
+	for _, c := range n.Nodes {
+		present[c.XMLName.Local] = true
+	}
+	for _, name := range content.required() {
+		if !present[name] {
+			errs, valid = append(errs, fmt.Sprintf("%s/%s is required", path, name)), false
+		}
+	}
+	furthest := 0
+	if end, ok := content.match(n.Nodes, 0, &furthest); valid && (!ok || end < len(n.Nodes)) {
+		if furthest < len(n.Nodes) {
+			errs = append(errs, fmt.Sprintf("%s/%s is out of order or repeated", path, n.Nodes[furthest].XMLName.Local))
+		} else {
+			errs = append(errs, fmt.Sprintf("children of %s don't match the schema", path))
+		}
+	}
+	for _, c := range n.Nodes {
+		if decl := declared[c.XMLName.Local]; decl != nil {
+			errs = s.validate(c, decl, path+"/"+c.XMLName.Local, errs)
+		}
+	}
+	return errs
+}
+
+// elements adds declared elements of p by names
+func (p *schemaParticle) elements(declared map[string]*schemaElement) {
+	if p.element != nil {
+		declared[p.element.name+p.element.ref] = p.element
+	}
+	for _, item := range p.items {
+		item.elements(declared)
+	}
+}
+
+// match returns index of nodes after occurrences of p from i, false if p has less than minimal occurrences.
+// furthest is updated by index after the furthest matched element for errors. Matching is greedy,
+// it is enough for deterministic content models required by XSD
+func (p *schemaParticle) match(nodes []xsdNode, i int, furthest *int) (int, bool) {
+	count := 0
+	for p.max < 0 || count < p.max {
+		end, ok := p.once(nodes, i, furthest)
+		if !ok || end == i {
+			// an empty occurrence satisfies the minimal occurrences
+			if ok {
+				count = p.min
+			}
+			break
+		}
+		i, count = end, count+1
+	}
+	return i, count >= p.min
+}
+
+// once returns index of nodes after an occurrence of p from i
+func (p *schemaParticle) once(nodes []xsdNode, i int, furthest *int) (int, bool) {
+	switch {
+	case p.element != nil:
+		if i < len(nodes) && nodes[i].XMLName.Local == p.element.name+p.element.ref {
+			if i+1 > *furthest {
+				*furthest = i + 1
+			}
+			return i + 1, true
+		}
+		return i, false
+	case p.kind == "sequence":
+		for _, item := range p.items {
+			var ok bool
+			if i, ok = item.match(nodes, i, furthest); !ok {
+				return i, false
+			}
+		}
+		return i, true
+	case p.kind == "choice":
+		empty := false
+		for _, item := range p.items {
+			end, ok := item.match(nodes, i, furthest)
+			if ok && end > i {
+				return end, true
+			}
+			empty = empty || ok
+		}
+		return i, empty
+	default: // all
+		used := map[*schemaParticle]bool{}
+		for progress := true; progress; {
+			progress = false
+			for _, item := range p.items {
+				if end, ok := item.once(nodes, i, furthest); ok && end > i && !used[item] {
+					used[item], i, progress = true, end, true
+				}
+			}
+		}
+		for _, item := range p.items {
+			if !used[item] && item.min > 0 {
+				return i, false
+			}
+		}
+		return i, true
+	}
+}
+
+// required returns names of elements which are required in any occurrence of p
+func (p *schemaParticle) required() []string {
+	if p.min == 0 || (p.kind == "choice" && p.element == nil) {
+		return nil
+	}
+	if p.element != nil {
+		return []string{p.element.name + p.element.ref}
+	}
+	var names []string
+	for _, item := range p.items {
+		names = append(names, item.required()...)
+	}
+	return names
+}
+
+// check returns error if value doesn't match e simple type
+func (s *Schema) check(e *schemaElement, value string) error {
+	t, name := e.inline, e.typ
+	for depth := 0; ; depth++ {
+		if t == nil {
+			if t = s.simple[name]; t == nil {
+				return checkBuiltin(name, value)
+			}
+		}
+		if err := t.check(value); err != nil {
+			return err
+		}
+		if depth > 16 {
+			return errors.Errorf("type %q restrictions are too deep", e.typ)
+		}
+		t, name = nil, t.base
+	}
+}
+
+func (t *schemaType) check(value string) error {
+	if len(t.unsupported) > 0 {
+		return errors.Errorf("type of %s isn't supported", strings.Join(t.unsupported, ", "))
+	}
+	if len(t.patterns) > 0 {
+		matched := false
+		for _, re := range t.patterns {
+			matched = matched || re.MatchString(value)
+		}
+		if !matched {
+			return errors.Errorf("%q doesn't match pattern %s", value, t.patterns[0])
+		}
+	}
+	if len(t.enumeration) > 0 {
+		found := false
+		for _, v := range t.enumeration {
+			found = found || v == value
+		}
+		if !found {
+			return errors.Errorf("%q isn't one of %s", value, strings.Join(t.enumeration, ", "))
+		}
+	}
+	if n := len([]rune(value)); n < t.minLength || (t.maxLength >= 0 && n > t.maxLength) {
+		return errors.Errorf("length of %q is out of range", value)
+	}
+
+	digits := strings.TrimLeft(value, "+-")
+	fraction := ""
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		digits, fraction = digits[:i], digits[i+1:]
+	}
+	if t.fractionDigits >= 0 && len(fraction) > t.fractionDigits {
+		return errors.Errorf("%q has more than %d fraction digits", value, t.fractionDigits)
+	}
+	if t.totalDigits > 0 && len(strings.TrimLeft(digits, "0"))+len(fraction) > t.totalDigits {
+		return errors.Errorf("%q has more than %d digits", value, t.totalDigits)
+	}
+	for _, b := range t.bounds {
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return errors.Errorf("%q isn't a number of %s %v", value, b.facet, b.value)
+		}
+		ok := map[string]bool{
+			"minInclusive": v >= b.value, "maxInclusive": v <= b.value, "minExclusive": v > b.value, "maxExclusive": v < b.value,
+		}[b.facet]
+		if !ok {
+			return errors.Errorf("%q is out of %s %v", value, b.facet, b.value)
+		}
+	}
+	return nil
+}
+
+// checkBuiltin returns error if value doesn't match decimal, integer, boolean or date XSD type, others aren't checked
+func checkBuiltin(name, value string) error {
+	var re *regexp.Regexp
+	switch name {
+	case "decimal", "double", "float":
+		re = decimalRegexp
+	case "integer", "int", "long", "short", "byte", "nonNegativeInteger", "positiveInteger",
+		"unsignedInt", "unsignedLong", "unsignedShort", "unsignedByte":
+		re = integerRegexp
+	case "boolean":
+		re = booleanRegexp
+	case "date":
+		re = dateRegexp
+	default:
+		return nil
+	}
+	if !re.MatchString(value) {
+		return errors.Errorf("%q isn't %s", value, name)
+	}
+	return nil
+}
+
+func (n xsdNode) attr(name string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// localName returns name without namespace prefix
+func localName(name string) string {
+	return name[strings.IndexByte(name, ':')+1:]
+}
+
+// decodeXML decodes data of utf-8 or windows-1251 encoding to v
+func decodeXML(data []byte, v interface{}) error {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		switch strings.ToLower(charset) {
+		case "windows-1251", "cp1251":
+			return charmap.Windows1251.NewDecoder().Reader(input), nil
+		default:
+			return nil, errors.Errorf("unsupported charset %q", charset)
+		}
+	}
+	return dec.Decode(v)
+}
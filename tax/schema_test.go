@@ -0,0 +1,190 @@
+package tax
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/stretchr/testify/require"
+)
+
+// testCommonTypes and testFormSchema are a test schema in the layout of the tax service forms schemas:
+// the form schema includes common types. Its amounts elements codes are ones of testForm
+const testCommonTypes = `<?xml version="1.0" encoding="utf-8"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:simpleType name="DGdecimal2">
+    <xs:restriction base="xs:decimal"><xs:fractionDigits value="2"/><xs:totalDigits value="15"/></xs:restriction>
+  </xs:simpleType>
+  <xs:simpleType name="DGHTINF">
+    <xs:restriction base="xs:string"><xs:pattern value="\d{10}"/></xs:restriction>
+  </xs:simpleType>
+  <xs:simpleType name="DGDate">
+    <xs:restriction base="xs:string"><xs:pattern value="\d{8}"/></xs:restriction>
+  </xs:simpleType>
+  <xs:simpleType name="DGKv">
+    <xs:restriction base="xs:integer"><xs:minInclusive value="1"/><xs:maxInclusive value="4"/></xs:restriction>
+  </xs:simpleType>
+  <xs:simpleType name="DGHNAME">
+    <xs:restriction base="xs:string"><xs:maxLength value="30"/></xs:restriction>
+  </xs:simpleType>
+  <xs:complexType name="DHead">
+    <xs:sequence>
+      <xs:element name="TIN" type="DGHTINF"/>
+      <xs:element name="C_DOC" type="xs:string"/>
+      <xs:element name="C_DOC_SUB" type="xs:string"/>
+      <xs:element name="C_DOC_VER" type="xs:integer"/>
+      <xs:element name="C_DOC_TYPE" type="xs:integer"/>
+      <xs:element name="C_DOC_CNT" type="xs:integer"/>
+      <xs:element name="C_REG" type="xs:integer"/>
+      <xs:element name="C_RAJ" type="xs:integer"/>
+      <xs:element name="PERIOD_MONTH" type="xs:integer"/>
+      <xs:element name="PERIOD_TYPE" type="xs:integer"/>
+      <xs:element name="PERIOD_YEAR" type="xs:integer"/>
+      <xs:element name="C_STI_ORIG" type="xs:integer"/>
+      <xs:element name="C_DOC_STAN" type="xs:integer"/>
+      <xs:element name="LINKED_DOCS" nillable="true" minOccurs="0"/>
+      <xs:element name="D_FILL" type="DGDate"/>
+      <xs:element name="SOFTWARE" type="xs:string" nillable="true" minOccurs="0"/>
+    </xs:sequence>
+  </xs:complexType>
+</xs:schema>`
+
+const testFormSchema = `<?xml version="1.0" encoding="utf-8"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:include schemaLocation="common_types.xsd"/>
+  <xs:element name="DECLAR">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="DECLARHEAD" type="DHead"/>
+        <xs:element name="DECLARBODY">
+          <xs:complexType>
+            <xs:sequence>
+              <xs:element name="HZ" type="xs:integer" minOccurs="0"/>
+              <xs:element name="HZU" type="xs:integer" minOccurs="0"/>
+              <xs:element name="HZY" type="xs:integer"/>
+              <xs:element name="HZKV" type="DGKv"/>
+              <xs:element name="HNAME" type="DGHNAME"/>
+              <xs:element name="HTIN" type="DGHTINF"/>
+              <xs:element name="HLOC" type="xs:string" minOccurs="0"/>
+              <xs:element name="HSTI" type="xs:string" minOccurs="0"/>
+              <xs:element name="R01G3" type="DGdecimal2"/>
+              <xs:element name="R02G3" type="DGdecimal2"/>
+              <xs:element name="R03G3" type="DGdecimal2"/>
+              <xs:element name="R04G3" type="DGdecimal2" minOccurs="0"/>
+              <xs:element name="R05G3" type="DGdecimal2"/>
+              <xs:element name="HFILL" type="DGDate"/>
+              <xs:element name="HBOS" type="xs:string"/>
+              <xs:element name="HKBOS" type="xs:string"/>
+            </xs:sequence>
+          </xs:complexType>
+        </xs:element>
+      </xs:sequence>
+    </xs:complexType>
+  </xs:element>
+</xs:schema>`
+
+// testTableSchema has a table of rows, a choice and a pattern of XSD regular expressions only
+const testTableSchema = `<?xml version="1.0" encoding="utf-8"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:simpleType name="Name">
+    <xs:restriction base="xs:string"><xs:pattern value="\i\c*"/></xs:restriction>
+  </xs:simpleType>
+  <xs:element name="T">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="A" type="xs:integer"/>
+        <xs:element name="ROW" type="xs:decimal" minOccurs="0" maxOccurs="2"/>
+        <xs:choice>
+          <xs:element name="B" type="xs:string"/>
+          <xs:element name="C" type="xs:string"/>
+        </xs:choice>
+        <xs:element name="N" type="Name" minOccurs="0"/>
+      </xs:sequence>
+    </xs:complexType>
+  </xs:element>
+</xs:schema>`
+
+func Test_Schema(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "common_types.xsd"), []byte(testCommonTypes), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "F0103309.xsd"), []byte(testFormSchema), 0o600))
+	schema, err := LoadSchema(filepath.Join(dir, "F0103309.xsd"))
+	require.NoError(t, err)
+
+	long := "Костянтиненко-Вишневецька Олександра"
+	cases := []struct {
+		modify func(d *Declaration)
+		err    string
+	}{
+		{modify: func(d *Declaration) {}},
+		{modify: func(d *Declaration) { d.Form.Income = "R011G3" }, err: "DECLAR/DECLARBODY/R011G3 isn't declared"},
+		{modify: func(d *Declaration) { d.Form.Due = "R06G3" }, err: "DECLAR/DECLARBODY/R05G3 is required"},
+		{modify: func(d *Declaration) { d.Form.Tax, d.Form.PreviousTax = "R04G3", "R03G3" }, err: "DECLAR/DECLARBODY/R04G3 is out of order"},
+		{modify: func(d *Declaration) { d.Taxpayer.TIN = "123456789" }, err: `DECLAR/DECLARHEAD/TIN: "123456789" doesn't match pattern`},
+		{modify: func(d *Declaration) { d.Taxpayer.Name = long }, err: "DECLAR/DECLARBODY/HNAME: length"},
+	}
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			d := Declaration{
+				Form:     testForm,
+				Taxpayer: Taxpayer{Name: "Марʼяна Іваненко", TIN: "1234567890", OfficeCode: "2650", Rate: 5},
+				Period:   Period{Year: 2022, Quarter: 2},
+				Filled:   time.Date(2022, 7, 15, 0, 0, 0, 0, p24.NewKievLocation()),
+				Income:   500, QuarterIncome: 200, Tax: 25, PreviousTax: 15, Due: 10,
+			}
+			c.modify(&d)
+			data, err := d.XML()
+			require.NoError(t, err)
+			err = schema.Validate(data)
+			if c.err != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), c.err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "invalid.xsd"), []byte(`<DECLAR/>`), 0o600))
+	_, err = LoadSchema(filepath.Join(dir, "invalid.xsd"))
+	require.Error(t, err)
+	_, err = LoadSchema(filepath.Join(dir, "not-exist.xsd"))
+	require.Error(t, err)
+}
+
+func Test_Schema_Content(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "table.xsd")
+	require.NoError(t, os.WriteFile(filename, []byte(testTableSchema), 0o600))
+	schema, err := LoadSchema(filename)
+	require.NoError(t, err)
+
+	cases := []struct {
+		doc string
+		err string
+	}{
+		{doc: `<T><A>1</A><B>b</B></T>`},
+		{doc: `<T><A>1</A><ROW>1.5</ROW><ROW>2</ROW><C>c</C></T>`},
+		{doc: `<T><A>1</A><ROW>1</ROW><ROW>2</ROW><ROW>3</ROW><C>c</C></T>`, err: "T/ROW is out of order or repeated"},
+		{doc: `<T><B>b</B><A>1</A></T>`, err: "T/B is out of order or repeated"},
+		{doc: `<T><A>1</A><B>b</B><C>c</C></T>`, err: "T/C is out of order or repeated"},
+		{doc: `<T><A>1</A></T>`, err: "children of T don't match the schema"},
+		{doc: `<T><A>1</A><ROW>x</ROW><B>b</B></T>`, err: `T/ROW: "x" isn't decimal`},
+		{doc: `<T><A>1</A><B>b</B><N>name</N></T>`, err: `T/N: type of pattern "\\i\\c*" isn't supported`},
+	}
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			err := schema.Validate([]byte(c.doc))
+			if c.err != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), c.err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,201 @@
+// Package transfer detects transfers between own cards
+package transfer
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/dimboknv/p24-cli/export"
+	"github.com/dimboknv/p24-cli/pipeline"
+	"github.com/pkg/errors"
+)
+
+// DefaultWindow is the default maximal time between the outgoing and incoming statements of a transfer
+const DefaultWindow = time.Hour
+
+// cardRegexp matches full or masked card numbers, e.g. "5168742012345678", "5168****5678", "*5678"
+var cardRegexp = regexp.MustCompile(`\d{16}|\d{0,6}[*xX]{2,}\d{4}|\*\d{4}`)
+
+// Options of detection
+type Options struct {
+	Window time.Duration // DefaultWindow if zero
+	Cards  []string      // own card numbers besides cards of statements
+}
+
+// Link is a statement side of a transfer. ID is the fingerprint of the outgoing statement
+// or of the statement itself if the other side is not loaded. Card is the other card
+type Link struct {
+	ID   string
+	Card string
+}
+
+// Transfers are links of own transfers statements by fingerprints
+type Transfers struct {
+	opts  Options
+	links map[string]Link
+}
+
+// NewTransfers returns empty Transfers with opts, see Transfers.Detect
+func NewTransfers(opts Options) *Transfers {
+	if opts.Window <= 0 {
+		opts.Window = DefaultWindow
+	}
+	return &Transfers{opts: opts, links: map[string]Link{}}
+}
+
+// Detect replaces transfers by transfers of statements. An outgoing statement is paired with the nearest
+// incoming one of other card with the opposite CardAmount or Amount within the window, statements with
+// a Description mentioning the other card are paired first. Not paired statements with a Description
+// mentioning other own card, e.g. "5168****5678", are transfers to or from cards which are not loaded
+func (t *Transfers) Detect(statements []p24.Statement) {
+	t.links = map[string]Link{}
+	sorted := make([]*p24.Statement, len(statements))
+	cards := map[string]bool{}
+	for _, c := range t.opts.Cards {
+		cards[c] = true
+	}
+	for i := range statements {
+		sorted[i] = &statements[i]
+		cards[statements[i].Card] = true
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].TranDate.Before(sorted[j].TranDate) })
+
+	// statements mentioning the other card are paired first, so they aren't taken by other nearer statements
+	matched := map[*p24.Statement]bool{}
+	for _, mention := range []bool{true, false} {
+		for i, s := range sorted {
+			if s.CardAmount.Amount >= 0 || matched[s] {
+				continue
+			}
+			var (
+				best     *p24.Statement
+				bestDiff time.Duration
+			)
+			for k := i - 1; k >= 0 && s.TranDate.Sub(sorted[k].TranDate) <= t.opts.Window; k-- {
+				best, bestDiff = better(s, sorted[k], best, bestDiff, mention, matched)
+			}
+			for k := i + 1; k < len(sorted) && sorted[k].TranDate.Sub(s.TranDate) <= t.opts.Window; k++ {
+				best, bestDiff = better(s, sorted[k], best, bestDiff, mention, matched)
+			}
+			if best == nil {
+				continue
+			}
+			matched[s], matched[best] = true, true
+			id := pipeline.Fingerprint(s)
+			t.links[id] = Link{ID: id, Card: best.Card}
+			t.links[pipeline.Fingerprint(best)] = Link{ID: id, Card: s.Card}
+		}
+	}
+
+	for _, s := range sorted {
+		if matched[s] {
+			continue
+		}
+		for _, card := range mentions(s.Description, cards) {
+			if card != s.Card {
+				id := pipeline.Fingerprint(s)
+				t.links[id] = Link{ID: id, Card: card}
+				break
+			}
+		}
+	}
+}
+
+// Lookup returns the transfer link of a statement
+func (t *Transfers) Lookup(s *p24.Statement) (Link, bool) {
+	l, ok := t.links[pipeline.Fingerprint(s)]
+	return l, ok
+}
+
+// Fields returns virtual fields of statements:
+// - IsTransfer is true for transfers between own cards
+// - TransferID is the same for both sides of a transfer, empty if a statement is not a transfer
+// - TransferCard is the other card of a transfer
+func Fields(t *Transfers) export.VirtualFields {
+	field := func(f func(l Link, ok bool) interface{}) export.VirtualField {
+		return func(obj interface{}) (interface{}, error) {
+			if t == nil {
+				return nil, errors.New("transfers are not detected")
+			}
+			var s p24.Statement
+			switch v := obj.(type) {
+			case *p24.Statement:
+				s = *v
+			case p24.Statement:
+				s = v
+			default:
+				return nil, errors.Errorf("%T is not a statement", obj)
+			}
+			return f(t.Lookup(&s)), nil
+		}
+	}
+
+	return export.VirtualFields{
+		"IsTransfer":   field(func(_ Link, ok bool) interface{} { return ok }),
+		"TransferID":   field(func(l Link, _ bool) interface{} { return l.ID }),
+		"TransferCard": field(func(l Link, _ bool) interface{} { return l.Card }),
+	}
+}
+
+// better returns incoming r with its time difference to outgoing s if r is nearer to s than best,
+// otherwise returns best. If mention is true, a Description of s or r must mention the other card
+func better(s, r, best *p24.Statement, bestDiff time.Duration, mention bool, matched map[*p24.Statement]bool) (*p24.Statement, time.Duration) {
+	if r.CardAmount.Amount <= 0 || r.Card == s.Card || matched[r] || !opposite(s, r) ||
+		(mention && !mentioned(s.Description, r.Card) && !mentioned(r.Description, s.Card)) {
+		return best, bestDiff
+	}
+	diff := r.TranDate.Sub(s.TranDate)
+	if diff < 0 {
+		diff = -diff
+	}
+	if best == nil || diff < bestDiff {
+		return r, diff
+	}
+	return best, bestDiff
+}
+
+// opposite returns true if CardAmount or Amount of outgoing s and incoming r are opposite
+func opposite(s, r *p24.Statement) bool {
+	for _, pair := range [][2]p24.Funds{{s.CardAmount, r.CardAmount}, {s.Amount, r.Amount}} {
+		if pair[0].Currency != "" && pair[0].Currency == pair[1].Currency && pair[0].Amount == -pair[1].Amount {
+			return true
+		}
+	}
+	return false
+}
+
+// mentions returns cards mentioned in description by full or masked numbers
+func mentions(description string, cards map[string]bool) []string {
+	var res []string
+	for card := range cards {
+		if mentioned(description, card) {
+			res = append(res, card)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// mentioned returns true if description mentions card by full or masked number with the same
+// last 4 digits and the same first digits if any
+func mentioned(description, card string) bool {
+	if len(card) < 4 {
+		return false
+	}
+	for _, m := range cardRegexp.FindAllString(description, -1) {
+		if m == card {
+			return true
+		}
+		masked := strings.IndexAny(m, "*xX")
+		if masked < 0 || !strings.HasSuffix(card, m[len(m)-4:]) {
+			continue
+		}
+		if strings.HasPrefix(card, m[:masked]) {
+			return true
+		}
+	}
+	return false
+}
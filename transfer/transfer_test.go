@@ -0,0 +1,110 @@
+package transfer
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dimboknv/p24"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_mentioned(t *testing.T) {
+	cases := []struct {
+		description, card string
+		expected          bool
+	}{
+		{"Transfer to 5168742022222222", "5168742022222222", true},
+		{"Transfer to 5168****2222", "5168742022222222", true},
+		{"Transfer to 4149****2222", "5168742022222222", false},
+		{"Transfer to *2222", "5168742022222222", true},
+		{"Transfer to card 2222", "5168742022222222", false},
+		{"Transfer to *3333", "5168742022222222", false},
+	}
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			require.Equal(t, c.expected, mentioned(c.description, c.card))
+		})
+	}
+}
+
+func Test_Transfers_Detect(t *testing.T) {
+	kiev := p24.NewKievLocation()
+	const first, second, third = "5168742011111111", "5168742022222222", "5168742033333333"
+	statements := []p24.Statement{
+		{
+			Card: first, Appcode: "0", TranDate: time.Date(2022, 1, 6, 10, 0, 0, 0, kiev), Description: "Shop",
+			Amount: p24.Funds{Currency: "UAH", Amount: -100000}, CardAmount: p24.Funds{Currency: "UAH", Amount: -100000},
+		},
+		{
+			Card: first, Appcode: "1", TranDate: time.Date(2022, 1, 6, 10, 1, 0, 0, kiev), Description: "Transfer to 5168****2222",
+			Amount: p24.Funds{Currency: "UAH", Amount: -100000}, CardAmount: p24.Funds{Currency: "UAH", Amount: -100000},
+		},
+		{
+			Card: second, Appcode: "2", TranDate: time.Date(2022, 1, 6, 10, 2, 0, 0, kiev), Description: "Transfer from card",
+			Amount: p24.Funds{Currency: "UAH", Amount: 100000}, CardAmount: p24.Funds{Currency: "UAH", Amount: 100000},
+		},
+		{
+			Card: first, Appcode: "3", TranDate: time.Date(2022, 1, 6, 10, 3, 0, 0, kiev), Description: "Buy USD",
+			Amount: p24.Funds{Currency: "USD", Amount: -10000}, CardAmount: p24.Funds{Currency: "UAH", Amount: -370000},
+		},
+		{
+			Card: third, Appcode: "4", TranDate: time.Date(2022, 1, 6, 10, 4, 0, 0, kiev), Description: "Buy USD",
+			Amount: p24.Funds{Currency: "USD", Amount: 10000}, CardAmount: p24.Funds{Currency: "USD", Amount: 10000},
+		},
+		{
+			Card: second, Appcode: "5", TranDate: time.Date(2022, 1, 6, 10, 5, 0, 0, kiev), Description: "Transfer to *4444",
+			Amount: p24.Funds{Currency: "UAH", Amount: -20000}, CardAmount: p24.Funds{Currency: "UAH", Amount: -20000},
+		},
+		{
+			Card: second, Appcode: "6", TranDate: time.Date(2022, 1, 6, 10, 6, 0, 0, kiev), Description: "Salary",
+			Amount: p24.Funds{Currency: "UAH", Amount: 500000}, CardAmount: p24.Funds{Currency: "UAH", Amount: 500000},
+		},
+		// out of 30 minutes window
+		{
+			Card: first, Appcode: "50", TranDate: time.Date(2022, 1, 6, 10, 50, 0, 0, kiev), Description: "Shop",
+			Amount: p24.Funds{Currency: "UAH", Amount: -500000}, CardAmount: p24.Funds{Currency: "UAH", Amount: -500000},
+		},
+	}
+
+	cases := []struct {
+		opts     Options
+		expected []string // other cards of statements
+	}{
+		{
+			opts:     Options{Window: 30 * time.Minute, Cards: []string{"4149000044444444"}},
+			expected: []string{"", second, first, third, first, "4149000044444444", "", ""},
+		},
+		{
+			opts:     Options{Window: 30 * time.Minute},
+			expected: []string{"", second, first, third, first, "", "", ""},
+		},
+	}
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			transfers := NewTransfers(c.opts)
+			transfers.Detect(statements)
+			cards := make([]string, len(statements))
+			for k := range statements {
+				l, ok := transfers.Lookup(&statements[k])
+				require.Equal(t, c.expected[k] != "", ok)
+				cards[k] = l.Card
+			}
+			require.Equal(t, c.expected, cards)
+		})
+	}
+
+	transfers := NewTransfers(Options{})
+	transfers.Detect(statements)
+	fields := Fields(transfers)
+	out, err := fields["TransferID"](statements[1])
+	require.NoError(t, err)
+	in, err := fields["TransferID"](&statements[2])
+	require.NoError(t, err)
+	require.Equal(t, out, in)
+	isTransfer, err := fields["IsTransfer"](statements[0])
+	require.NoError(t, err)
+	require.Equal(t, false, isTransfer)
+}